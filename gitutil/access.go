@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// RepositoryAccess is the diagnosis of a failure to access a repository,
+// telling apart the missing repositories from the forbidden ones.
+type RepositoryAccess int
+
+const (
+	// AccessAmbiguous is a failure that can be caused by either a missing
+	// or a forbidden repository, e.g. because the provider reports both
+	// the same way to not disclose the existence of private repositories.
+	AccessAmbiguous RepositoryAccess = iota
+	// AccessNotFound is a failure caused by a repository that doesn't exist.
+	AccessNotFound
+	// AccessForbidden is a failure caused by a repository that exists, but
+	// that the credentials aren't allowed to access.
+	AccessForbidden
+)
+
+// String returns the name of the repository access diagnosis.
+func (a RepositoryAccess) String() string {
+	switch a {
+	case AccessNotFound:
+		return "NotFound"
+	case AccessForbidden:
+		return "Forbidden"
+	default:
+		return "Ambiguous"
+	}
+}
+
+// ambiguousNotFoundProviders holds the providers reporting the repositories
+// the credentials can't access as missing.
+var ambiguousNotFoundProviders = map[Provider]bool{
+	// GitHub answers 404 for the private repositories, and asks for
+	// credentials for the missing ones.
+	ProviderGitHub: true,
+	// Gitea answers 404 for the private repositories.
+	ProviderGitea: true,
+	// The provider may do either.
+	ProviderUnknown: true,
+}
+
+// ambiguousNotFoundPatterns holds the lower-cased messages stating that the
+// repository is either missing or forbidden, whatever the provider.
+var ambiguousNotFoundPatterns = []string{
+	"or you don't have permission",
+	"or you do not have permission",
+	"or you do not have access",
+}
+
+// DiagnoseRepositoryAccess tells whether the given repository access failure
+// is caused by a missing repository or by a forbidden one, based on the
+// class of the error and on the way the provider of the given remote URL
+// reports the failures. The provider is detected from the error message when
+// the remote URL is empty. It returns AccessAmbiguous when the failure can't
+// be told apart, including when the error is neither classified as
+// ClassRepositoryNotFound nor as ClassPermissionDenied.
+func DiagnoseRepositoryAccess(err error, remoteURL string) RepositoryAccess {
+	if err == nil {
+		return AccessAmbiguous
+	}
+
+	switch Classify(err) {
+	case ClassPermissionDenied:
+		// The permissions are only checked for the visible repositories.
+		return AccessForbidden
+	case ClassRepositoryNotFound:
+	default:
+		return AccessAmbiguous
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range ambiguousNotFoundPatterns {
+		if strings.Contains(msg, pattern) {
+			return AccessAmbiguous
+		}
+	}
+
+	provider := DetectProvider(remoteURL)
+	if provider == ProviderUnknown {
+		var adoErr *AzureDevOpsError
+		if errors.As(err, &adoErr) {
+			provider = ProviderAzureDevOps
+		} else {
+			provider = messageProvider(err.Error())
+		}
+	}
+	if ambiguousNotFoundProviders[provider] {
+		return AccessAmbiguous
+	}
+	return AccessNotFound
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiagnoseRepositoryAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		remoteURL string
+		want      RepositoryAccess
+	}{
+		{
+			name: "nil error",
+			want: AccessAmbiguous,
+		},
+		{
+			name:      "GitHub repository not found",
+			err:       errors.New("remote: Repository not found.\nfatal: repository 'https://github.com/org/repo.git/' not found"),
+			remoteURL: "https://github.com/org/repo.git",
+			want:      AccessAmbiguous,
+		},
+		{
+			name: "GitHub detected from the message",
+			err:  errors.New("remote: Repository not found.\nfatal: repository 'https://github.com/org/repo.git/' not found"),
+			want: AccessAmbiguous,
+		},
+		{
+			name:      "GitHub permission denied",
+			err:       errors.New("remote: Permission to org/repo.git denied to user."),
+			remoteURL: "https://github.com/org/repo.git",
+			want:      AccessForbidden,
+		},
+		{
+			name:      "GitLab project not found",
+			err:       errors.New("repository not found"),
+			remoteURL: "https://gitlab.com/org/repo.git",
+			want:      AccessNotFound,
+		},
+		{
+			name:      "GitLab ambiguous message",
+			err:       errors.New("remote: The project you were looking for could not be found or you don't have permission to view it."),
+			remoteURL: "git@gitlab.com:org/repo.git",
+			want:      AccessAmbiguous,
+		},
+		{
+			name:      "GitLab deploy key without write access",
+			err:       errors.New("remote: This deploy key does not have write access to this project."),
+			remoteURL: "git@gitlab.com:org/repo.git",
+			want:      AccessForbidden,
+		},
+		{
+			name:      "Bitbucket repository not found",
+			err:       errors.New("repository not found"),
+			remoteURL: "https://bitbucket.org/org/repo.git",
+			want:      AccessNotFound,
+		},
+		{
+			name:      "Azure DevOps missing or forbidden repository",
+			err:       LibGit2Error(errors.New("remote: TF401019: The Git repository with name or identifier repo does not exist or you do not have permissions for the operation you are attempting.")),
+			remoteURL: "https://dev.azure.com/org/project/_git/repo",
+			want:      AccessAmbiguous,
+		},
+		{
+			name:      "Azure DevOps missing permission",
+			err:       LibGit2Error(errors.New("remote: TF401027: You need the Git 'GenericContribute' permission to perform this action.")),
+			remoteURL: "https://dev.azure.com/org/project/_git/repo",
+			want:      AccessForbidden,
+		},
+		{
+			name:      "unknown provider",
+			err:       errors.New("repository not found"),
+			remoteURL: "https://git.example.com/org/repo.git",
+			want:      AccessAmbiguous,
+		},
+		{
+			name:      "unrelated error",
+			err:       errors.New("dial tcp: lookup gitlab.com: no such host"),
+			remoteURL: "https://gitlab.com/org/repo.git",
+			want:      AccessAmbiguous,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiagnoseRepositoryAccess(tt.err, tt.remoteURL); got != tt.want {
+				t.Errorf("DiagnoseRepositoryAccess() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AggregateError is the tidied summary of an aggregate error, e.g. the
+// errors collected by a retry loop.
+type AggregateError struct {
+	// Errors holds the tidied constituent errors, deduplicated by message
+	// and in order of first occurrence.
+	Errors []error
+
+	counts []int
+	last   error
+}
+
+// Error returns the messages of the constituent errors, followed by the
+// number of occurrences of the repeated ones.
+func (e *AggregateError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+		if e.counts[i] > 1 {
+			msgs[i] += fmt.Sprintf(" (x%d)", e.counts[i])
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the constituent errors.
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// Class returns the class of the last constituent error, which in a retry
+// loop is the outcome of the last attempt.
+func (e *AggregateError) Class() ErrorClass {
+	return Classify(e.last)
+}
+
+// TidyAggregate applies the given tidy function, e.g. GoGitError or
+// LibGit2Error, to each constituent of an aggregate error and returns an
+// AggregateError summarising them. Both the errors created with errors.Join
+// and the hashicorp/go-multierror errors are supported, nested aggregates are
+// flattened. If the error isn't an aggregate, or all its constituents have the
+// same tidied message, the tidied error is returned. It returns `nil` if the
+// error is `nil`.
+func TidyAggregate(err error, tidy func(error) error) error {
+	if err == nil {
+		return nil
+	}
+
+	agg := &AggregateError{}
+	index := map[string]int{}
+	for _, e := range flattenErrors(err) {
+		e = tidy(e)
+		agg.last = e
+		if i, ok := index[e.Error()]; ok {
+			agg.counts[i]++
+			continue
+		}
+		index[e.Error()] = len(agg.Errors)
+		agg.Errors = append(agg.Errors, e)
+		agg.counts = append(agg.counts, 1)
+	}
+
+	if len(agg.Errors) == 1 {
+		return agg.Errors[0]
+	}
+	return agg
+}
+
+// flattenErrors returns the leaf errors of an aggregate error, in order.
+func flattenErrors(err error) []error {
+	var errs []error
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		errs = e.Unwrap()
+	case interface{ WrappedErrors() []error }:
+		errs = e.WrappedErrors()
+	default:
+		return []error{err}
+	}
+
+	var leaves []error
+	for _, e := range errs {
+		if e != nil {
+			leaves = append(leaves, flattenErrors(e)...)
+		}
+	}
+	return leaves
+}
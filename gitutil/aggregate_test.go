@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// joinError mimics the errors created with errors.Join.
+type joinError []error
+
+func (e joinError) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e joinError) Unwrap() []error {
+	return e
+}
+
+// multiError mimics the hashicorp/go-multierror errors.
+type multiError struct {
+	errs []error
+}
+
+func (e *multiError) Error() string {
+	return joinError(e.errs).Error()
+}
+
+func (e *multiError) WrappedErrors() []error {
+	return e.errs
+}
+
+func TestTidyAggregate(t *testing.T) {
+	reset := errors.New("read: connection reset by peer")
+	banner := errors.New("remote: \nremote: ====\nremote: This deploy key does not have write access to this project.\nremote: \n")
+
+	tests := []struct {
+		name      string
+		err       error
+		wantMsg   string
+		wantClass ErrorClass
+	}{
+		{
+			name:      "joined errors",
+			err:       joinError{reset, reset, banner},
+			wantMsg:   "read: connection reset by peer (x2); remote: This deploy key does not have write access to this project.",
+			wantClass: ClassPermissionDenied,
+		},
+		{
+			name:      "nested multierror",
+			err:       &multiError{errs: []error{banner, joinError{reset, nil, banner}}},
+			wantMsg:   "remote: This deploy key does not have write access to this project. (x2); read: connection reset by peer",
+			wantClass: ClassPermissionDenied,
+		},
+		{
+			name:      "identical errors",
+			err:       joinError{banner, banner},
+			wantMsg:   "remote: This deploy key does not have write access to this project.",
+			wantClass: ClassPermissionDenied,
+		},
+		{
+			name:      "single error",
+			err:       reset,
+			wantMsg:   "read: connection reset by peer",
+			wantClass: ClassNetworkError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TidyAggregate(tt.err, LibGit2Error)
+			if err.Error() != tt.wantMsg {
+				t.Errorf("TidyAggregate() = %q, want %q", err.Error(), tt.wantMsg)
+			}
+			if class := Classify(err); class != tt.wantClass {
+				t.Errorf("Classify(TidyAggregate()) = %s, want %s", class, tt.wantClass)
+			}
+		})
+	}
+
+	if err := TidyAggregate(nil, GoGitError); err != nil {
+		t.Errorf("TidyAggregate(nil) = %v, want nil", err)
+	}
+}
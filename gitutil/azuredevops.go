@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// azureDevOpsCodeRegexp matches the TF error codes returned by Azure DevOps,
+// followed by the human-readable message.
+var azureDevOpsCodeRegexp = regexp.MustCompile(`\b(TF\d{5,6}):\s*([^\n]*)`)
+
+// azureDevOpsClasses maps the Azure DevOps error codes to error classes.
+var azureDevOpsClasses = map[string]ErrorClass{
+	// You need the Git 'GenericRead' or 'GenericContribute' permission.
+	"TF401027": ClassPermissionDenied,
+	// The repository does not exist or you do not have permissions.
+	"TF401019": ClassRepositoryNotFound,
+	// You are not authorized to access the resource.
+	"TF400813": ClassAuthFailed,
+	// Pushes to the branch are blocked by a branch policy.
+	"TF402455": ClassRemoteRejected,
+	// The branch is locked.
+	"TF402436": ClassRemoteRejected,
+}
+
+// AzureDevOpsError is an Azure DevOps remote error stripped from the banner
+// noise, holding the TF error code and the message.
+type AzureDevOpsError struct {
+	// Code is the TF error code, e.g. TF401027.
+	Code string
+	// Message is the human-readable message following the code.
+	Message string
+
+	err error
+}
+
+// Error returns the code and the message of the error.
+func (e *AzureDevOpsError) Error() string {
+	return "remote: " + e.Code + ": " + e.Message
+}
+
+// Unwrap returns the original error.
+func (e *AzureDevOpsError) Unwrap() error {
+	return e.err
+}
+
+// Class returns the class of the error, based on the error code.
+func (e *AzureDevOpsError) Class() ErrorClass {
+	if class, ok := azureDevOpsClasses[e.Code]; ok {
+		return class
+	}
+	return classifyMessage(e.Message)
+}
+
+// azureDevOpsError returns an AzureDevOpsError wrapping err if the error
+// message holds an Azure DevOps error code, nil otherwise.
+func azureDevOpsError(msg string, err error) error {
+	m := azureDevOpsCodeRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return nil
+	}
+
+	msg = strings.TrimSpace(m[2])
+	msg = strings.TrimSpace(strings.TrimSuffix(msg, "remote:"))
+	// Strip the closing parenthesis of the push rejection reason.
+	if strings.Count(msg, ")") > strings.Count(msg, "(") {
+		msg = strings.TrimSuffix(msg, ")")
+	}
+	return &AzureDevOpsError{
+		Code:    m[1],
+		Message: msg,
+		err:     err,
+	}
+}
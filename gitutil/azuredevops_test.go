@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAzureDevOpsError(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		wantCode    string
+		wantMessage string
+		wantClass   ErrorClass
+	}{
+		{
+			name: "missing permission",
+			msg: `remote: 
+remote: ------------------------------------------------------------------
+remote: Azure Repos
+remote: ------------------------------------------------------------------
+remote: TF401027: You need the Git 'GenericContribute' permission to perform this action. Details: identity 'Build\1234', scope 'repository'.
+remote: 
+`,
+			wantCode:    "TF401027",
+			wantMessage: "You need the Git 'GenericContribute' permission to perform this action. Details: identity 'Build\\1234', scope 'repository'.",
+			wantClass:   ClassPermissionDenied,
+		},
+		{
+			name:        "missing repository",
+			msg:         "remote: TF401019: The Git repository with name or identifier app does not exist or you do not have permissions for the operation you are attempting.",
+			wantCode:    "TF401019",
+			wantMessage: "The Git repository with name or identifier app does not exist or you do not have permissions for the operation you are attempting.",
+			wantClass:   ClassRepositoryNotFound,
+		},
+		{
+			name:        "branch policy",
+			msg:         " ! [remote rejected] main -> main (TF402455: Pushes to this branch are not permitted; you must use a pull request to update this branch.",
+			wantCode:    "TF402455",
+			wantMessage: "Pushes to this branch are not permitted; you must use a pull request to update this branch.",
+			wantClass:   ClassRemoteRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var adoErr *AzureDevOpsError
+				if !errors.As(err, &adoErr) {
+					t.Fatalf("%s() = %T, want *AzureDevOpsError", name, err)
+				}
+				if adoErr.Code != tt.wantCode {
+					t.Errorf("%s() code = %q, want %q", name, adoErr.Code, tt.wantCode)
+				}
+				if adoErr.Message != tt.wantMessage {
+					t.Errorf("%s() message = %q, want %q", name, adoErr.Message, tt.wantMessage)
+				}
+				if want := "remote: " + tt.wantCode + ": " + tt.wantMessage; err.Error() != want {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), want)
+				}
+				if class := Classify(err); class != tt.wantClass {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, tt.wantClass)
+				}
+				if !errors.Is(err, orig) {
+					t.Errorf("%s() doesn't wrap the original error", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrorClass is the category of a git error, it can be used to set
+// meaningful status conditions without matching on the error message.
+type ErrorClass int
+
+const (
+	// ClassUnknown is the class of the errors that couldn't be classified.
+	ClassUnknown ErrorClass = iota
+	// ClassAuthFailed is the class of the errors caused by missing
+	// or invalid credentials.
+	ClassAuthFailed
+	// ClassPermissionDenied is the class of the errors caused by valid
+	// credentials lacking the permission for the operation.
+	ClassPermissionDenied
+	// ClassRepositoryNotFound is the class of the errors caused by a
+	// repository that doesn't exist on the remote.
+	ClassRepositoryNotFound
+	// ClassNetworkError is the class of the errors caused by a failure
+	// to reach the remote.
+	ClassNetworkError
+	// ClassRemoteRejected is the class of the errors caused by the remote
+	// rejecting a push.
+	ClassRemoteRejected
+	// ClassHostKeyMismatch is the class of the errors caused by an SSH host
+	// key missing from, or not matching, the known hosts.
+	ClassHostKeyMismatch
+	// ClassReferenceNotFound is the class of the errors caused by a branch,
+	// tag or other reference that doesn't exist on the remote.
+	ClassReferenceNotFound
+	// ClassShallowClone is the class of the errors caused by a shallow
+	// clone the remote can't fetch or push from.
+	ClassShallowClone
+	// ClassTimeout is the class of the errors caused by the deadline of the
+	// operation context being exceeded.
+	ClassTimeout
+	// ClassCanceled is the class of the errors caused by the cancellation
+	// of the operation context.
+	ClassCanceled
+	// ClassRateLimited is the class of the errors caused by the provider
+	// throttling the requests.
+	ClassRateLimited
+	// ClassCredentialsExpired is the class of the errors caused by expired
+	// tokens or certificates, or by passwords no longer accepted.
+	ClassCredentialsExpired
+	// ClassLFS is the class of the errors caused by Git LFS, as opposed to
+	// the repository itself.
+	ClassLFS
+	// ClassPushConflict is the class of the errors caused by the remote
+	// rejecting a push that doesn't fast-forward the remote reference, which
+	// may succeed after a rebase.
+	ClassPushConflict
+	// ClassTLS is the class of the errors caused by a failure to verify the
+	// TLS certificate of the remote.
+	ClassTLS
+	// ClassProxy is the class of the errors caused by a failure to connect
+	// through an HTTP proxy.
+	ClassProxy
+	// ClassSubmodule is the class of the errors caused by a failure to
+	// fetch a submodule.
+	ClassSubmodule
+)
+
+// String returns the name of the class.
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassAuthFailed:
+		return "AuthFailed"
+	case ClassPermissionDenied:
+		return "PermissionDenied"
+	case ClassRepositoryNotFound:
+		return "RepositoryNotFound"
+	case ClassNetworkError:
+		return "NetworkError"
+	case ClassRemoteRejected:
+		return "RemoteRejected"
+	case ClassHostKeyMismatch:
+		return "HostKeyMismatch"
+	case ClassReferenceNotFound:
+		return "ReferenceNotFound"
+	case ClassShallowClone:
+		return "ShallowClone"
+	case ClassTimeout:
+		return "Timeout"
+	case ClassCanceled:
+		return "Canceled"
+	case ClassRateLimited:
+		return "RateLimited"
+	case ClassCredentialsExpired:
+		return "CredentialsExpired"
+	case ClassLFS:
+		return "LFS"
+	case ClassPushConflict:
+		return "PushConflict"
+	case ClassTLS:
+		return "TLS"
+	case ClassProxy:
+		return "Proxy"
+	case ClassSubmodule:
+		return "Submodule"
+	default:
+		return "Unknown"
+	}
+}
+
+// errorCodes maps the classes to stable codes, see ErrorClass.Code.
+var errorCodes = map[ErrorClass]string{
+	ClassUnknown:            "GitUnknownError",
+	ClassAuthFailed:         "GitAuthFailed",
+	ClassPermissionDenied:   "GitPermissionDenied",
+	ClassRepositoryNotFound: "GitRepoNotFound",
+	ClassNetworkError:       "GitNetworkError",
+	ClassRemoteRejected:     "GitRemoteRejected",
+	ClassHostKeyMismatch:    "GitHostKeyMismatch",
+	ClassReferenceNotFound:  "GitRefNotFound",
+	ClassShallowClone:       "GitShallowClone",
+	ClassTimeout:            "GitTimeout",
+	ClassCanceled:           "GitCanceled",
+	ClassRateLimited:        "GitRateLimited",
+	ClassCredentialsExpired: "GitCredentialsExpired",
+	ClassLFS:                "GitLFSError",
+	ClassPushConflict:       "GitPushConflict",
+	ClassTLS:                "GitTLSError",
+	ClassProxy:              "GitProxyError",
+	ClassSubmodule:          "GitSubmoduleError",
+}
+
+// Code returns the stable machine-readable code of the class, e.g.
+// "GitAuthFailed", which can be used as the Reason of a Kubernetes
+// condition or as an alert label. The codes never change once released.
+func (c ErrorClass) Code() string {
+	if code, ok := errorCodes[c]; ok {
+		return code
+	}
+	return errorCodes[ClassUnknown]
+}
+
+// ClassifiedError is implemented by the errors which know their class, like
+// the errors returned by GoGitError, LibGit2Error and GitCLIError when they
+// differ from the original error.
+type ClassifiedError interface {
+	error
+	Class() ErrorClass
+}
+
+// classRule maps the error messages containing any of the patterns to a class.
+type classRule struct {
+	class    ErrorClass
+	patterns []string
+}
+
+// classRules holds the rules used by Classify, in order of precedence. The
+// patterns are matched against the lower-cased error message.
+var classRules = []classRule{
+	{
+		class:    ClassTimeout,
+		patterns: []string{context.DeadlineExceeded.Error()},
+	},
+	{
+		class:    ClassCanceled,
+		patterns: []string{context.Canceled.Error()},
+	},
+	{
+		class:    ClassSubmodule,
+		patterns: []string{"submodule"},
+	},
+	{
+		class:    ClassLFS,
+		patterns: lfsPatterns,
+	},
+	{
+		class:    ClassRateLimited,
+		patterns: rateLimitPatterns,
+	},
+	{
+		class:    ClassCredentialsExpired,
+		patterns: credentialsExpiredPatterns(),
+	},
+	{
+		class:    ClassProxy,
+		patterns: proxyPatterns,
+	},
+	{
+		class:    ClassTLS,
+		patterns: tlsPatterns(false),
+	},
+	{
+		class:    ClassHostKeyMismatch,
+		patterns: hostKeyPatterns,
+	},
+	{
+		class:    ClassShallowClone,
+		patterns: shallowPatterns,
+	},
+	{
+		class: ClassAuthFailed,
+		patterns: []string{
+			"authentication required",
+			"authentication failed",
+			"invalid username or password",
+			"invalid credentials",
+			"unable to authenticate",
+			"permission denied (publickey)",
+		},
+	},
+	{
+		class: ClassRepositoryNotFound,
+		patterns: []string{
+			"repository not found",
+			"repository does not exist",
+			"does not appear to be a git repository",
+			"the project you were looking for could not be found",
+		},
+	},
+	{
+		class:    ClassReferenceNotFound,
+		patterns: referencePatterns,
+	},
+	{
+		class: ClassPermissionDenied,
+		patterns: []string{
+			"does not have write access",
+			"write access to repository not granted",
+			"permission denied",
+			"permission to",
+			"access denied",
+			"repository is archived",
+			"check git secret has write access",
+		},
+	},
+	{
+		class:    ClassPushConflict,
+		patterns: pushConflictPatterns(),
+	},
+	{
+		class: ClassRemoteRejected,
+		patterns: []string{
+			"[remote rejected]",
+			"pre-receive hook declined",
+			"branch restriction",
+			"push rejected",
+		},
+	},
+	{
+		class: ClassNetworkError,
+		patterns: []string{
+			"connection refused",
+			"connection reset",
+			"connection timed out",
+			"no such host",
+			"could not resolve host",
+			"network is unreachable",
+			"i/o timeout",
+		},
+	},
+}
+
+// Classify returns the class of the given error, based on the messages
+// returned by go-git, libgit2 and the git providers, and on the libgit2 error
+// codes. Errors returned by GoGitError and LibGit2Error are classified the
+// same way as the original library errors. The errors wrapping
+// context.DeadlineExceeded or context.Canceled are classified as ClassTimeout
+// and ClassCanceled, even when wrapped by a remote failure. It returns
+// ClassUnknown for nil or unrecognised errors.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+	return classify(err, err.Error())
+}
+
+// ErrorCode returns the stable machine-readable code of the class of the
+// given error, see ErrorClass.Code. It returns an empty string for nil.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	return Classify(err).Code()
+}
+
+// classify returns the class of the given error, falling back to the
+// class of the given message.
+func classify(err error, msg string) ErrorClass {
+	if class, ok := contextClass(err); ok {
+		return class
+	}
+
+	var classified ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class()
+	}
+
+	if class := libgit2Class(err); class != ClassUnknown {
+		return class
+	}
+
+	return classifyMessage(msg)
+}
+
+// classifyMessage returns the class of the given error message.
+func classifyMessage(msg string) ErrorClass {
+	msg = strings.ToLower(msg)
+	for _, rule := range classRules {
+		for _, pattern := range rule.patterns {
+			if strings.Contains(msg, pattern) {
+				return rule.class
+			}
+		}
+	}
+	return ClassUnknown
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{
+			name: "nil error",
+			want: ClassUnknown,
+		},
+		{
+			name: "go-git authentication required",
+			err:  errors.New("authentication required"),
+			want: ClassAuthFailed,
+		},
+		{
+			name: "ssh public key rejected",
+			err:  errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"),
+			want: ClassAuthFailed,
+		},
+		{
+			name: "GitHub deploy key without write access",
+			err:  errors.New("remote: ERROR: deploy key does not have write access"),
+			want: ClassPermissionDenied,
+		},
+		{
+			name: "GitHub permission denied",
+			err:  errors.New("remote: Permission to org/repo.git denied to user."),
+			want: ClassPermissionDenied,
+		},
+		{
+			name: "GitLab deploy key without write access",
+			err: LibGit2Error(errors.New(`remote: 
+remote: ========================================================================
+remote: 
+remote: This deploy key does not have write access to this project.
+remote: 
+remote: ========================================================================
+remote: 
+`)),
+			want: ClassPermissionDenied,
+		},
+		{
+			name: "go-git repository not found",
+			err:  errors.New("repository not found"),
+			want: ClassRepositoryNotFound,
+		},
+		{
+			name: "GitLab project not found",
+			err:  errors.New("remote: The project you were looking for could not be found or you don't have permission to view it."),
+			want: ClassRepositoryNotFound,
+		},
+		{
+			name: "connection refused",
+			err:  fmt.Errorf("failed to clone: %w", errors.New("dial tcp 127.0.0.1:22: connect: connection refused")),
+			want: ClassNetworkError,
+		},
+		{
+			name: "DNS failure",
+			err:  errors.New("dial tcp: lookup github.invalid: no such host"),
+			want: ClassNetworkError,
+		},
+		{
+			name: "rejected push",
+			err:  errors.New(" ! [remote rejected] main -> main (pre-receive hook declined)"),
+			want: ClassRemoteRejected,
+		},
+		{
+			name: "go-git blank remote error",
+			err:  GoGitError(errors.New("unknown error: remote: ")),
+			want: ClassPermissionDenied,
+		},
+		{
+			name: "unknown error",
+			err:  errors.New("object not found"),
+			want: ClassUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	codes := map[string]ErrorClass{}
+	for class := ClassUnknown; class <= ClassSubmodule; class++ {
+		code := class.Code()
+		if !strings.HasPrefix(code, "Git") {
+			t.Errorf("%s.Code() = %q, want Git prefix", class, code)
+		}
+		if other, ok := codes[code]; ok {
+			t.Errorf("%s.Code() = %q, same as %s", class, code, other)
+		}
+		codes[code] = class
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", want: ""},
+		{name: "missing reference", err: GoGitError(errors.New(`couldn't find remote ref "refs/heads/mian"`)), want: "GitRefNotFound"},
+		{name: "invalid credentials", err: errors.New("authentication required"), want: "GitAuthFailed"},
+		{name: "tidied message", err: LibGit2Error(errors.New("remote: \nremote: This repository has been archived and is read-only.\n")), want: "GitPermissionDenied"},
+		{name: "unknown error", err: errors.New("object not found"), want: "GitUnknownError"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode() = %q, want %q", got, tt.want)
+			}
+			if tt.err == nil {
+				return
+			}
+			var classified ClassifiedError
+			if errors.As(tt.err, &classified) && classified.Class().Code() != tt.want {
+				t.Errorf("Class().Code() = %q, want %q", classified.Class().Code(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// cliPrefixes holds the prefixes of the messages written by the git binary
+// to stderr, which are stripped from the CLIError messages.
+var cliPrefixes = []string{"fatal:", "error:", "remote:"}
+
+// CLIError is a failure of the git binary, holding the exit code and the
+// meaningful lines written to stderr.
+type CLIError struct {
+	// ExitCode is the exit code of the git binary, -1 if unknown.
+	ExitCode int
+	// Message is the tidied stderr output, with the credentials redacted,
+	// capped to the message limits.
+	Message string
+
+	err error
+}
+
+// Error returns the message of the error, or the exit code if the message
+// is empty.
+func (e *CLIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("git exited with code %d", e.ExitCode)
+	}
+	return e.Message
+}
+
+// Unwrap returns the original error.
+func (e *CLIError) Unwrap() error {
+	return e.err
+}
+
+// Class returns the class of the error, based on the message.
+func (e *CLIError) Class() ErrorClass {
+	return classifyMessage(e.Message)
+}
+
+// GitCLIError translates an error returned by running the git binary, e.g.
+// an *exec.ExitError, and the stderr output of the command into a typed error
+// classified the same way as GoGitError and LibGit2Error. It returns `nil` if
+// the error is `nil`. The returned error wraps the original one.
+func GitCLIError(err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+
+	exitCode := -1
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	msg := SanitizeErrorMessage(stderr)
+	if typedErr := typedError(msg, err); typedErr != nil {
+		return typedErr
+	}
+
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.TrimSpace(line)
+		// The hints are suggestions of git commands, not failure reasons.
+		if strings.HasPrefix(line, "hint:") {
+			continue
+		}
+		for _, prefix := range cliPrefixes {
+			line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		}
+		if line = strings.Trim(line, " \t="); line != "" && !isFence(line) {
+			lines = append(lines, line)
+		}
+	}
+	msg = strings.Join(lines, " ")
+	if tidied, ok := applyTidyRules(msg); ok {
+		msg = tidied
+	} else {
+		msg = strings.Join(limitLines(lines), " ")
+	}
+
+	return &CLIError{
+		ExitCode: exitCode,
+		Message:  limitMessage(msg),
+		err:      err,
+	}
+}
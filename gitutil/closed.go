@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrRemoteClosed is returned by GoGitError, wrapped in a RemoteClosedError,
+// when the remote closes the connection before the end of the response.
+var ErrRemoteClosed = errors.New("remote closed the connection unexpectedly")
+
+// RemoteClosedError is a connection closed early by the remote, which go-git
+// surfaces as an unexpected EOF or an empty remote message.
+type RemoteClosedError struct {
+	// Message is the original message, e.g. 'unexpected EOF'.
+	Message string
+
+	err error
+}
+
+// Error returns the failure and its likely causes.
+func (e *RemoteClosedError) Error() string {
+	return ErrRemoteClosed.Error() + " (" + e.Message + "); likely causes are a proxy buffering " +
+		"or cutting the response, or a crash of a server-side hook"
+}
+
+// Unwrap returns the original error.
+func (e *RemoteClosedError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrRemoteClosed.
+func (e *RemoteClosedError) Is(target error) bool {
+	return target == ErrRemoteClosed
+}
+
+// Class returns ClassNetworkError.
+func (e *RemoteClosedError) Class() ErrorClass {
+	return ClassNetworkError
+}
+
+// remoteClosedError returns a RemoteClosedError wrapping err if the go-git
+// error message is an unexpected EOF or an empty remote message, nil
+// otherwise.
+func remoteClosedError(msg string, err error) error {
+	msg = strings.TrimSpace(msg)
+	lower := strings.ToLower(msg)
+	eof := lower == "eof" || strings.HasSuffix(lower, ": eof") || strings.HasSuffix(lower, "unexpected eof")
+	// The remote messages are empty when the remote wrote nothing but
+	// blank lines and banners before closing the connection.
+	emptyRemote := strings.HasSuffix(lower, "remote:") &&
+		(!strings.Contains(msg, "\n") || len(ParseRemoteMessage(msg)) == 0)
+	if !eof && !emptyRemote {
+		return nil
+	}
+	return &RemoteClosedError{
+		Message: msg,
+		err:     err,
+	}
+}
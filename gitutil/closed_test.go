@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestRemoteClosedError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantMsg string
+	}{
+		{
+			name:    "EOF",
+			err:     io.EOF,
+			wantMsg: "EOF",
+		},
+		{
+			name:    "unexpected EOF",
+			err:     fmt.Errorf("failed to clone: %w", io.ErrUnexpectedEOF),
+			wantMsg: "failed to clone: unexpected EOF",
+		},
+		{
+			name:    "blank remote lines",
+			err:     errors.New("remote: \nremote: ======\nremote: "),
+			wantMsg: "remote: \nremote: ======\nremote:",
+		},
+		{
+			name:    "empty remote message",
+			err:     errors.New("remote: "),
+			wantMsg: "remote:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := GoGitError(tt.err)
+
+			var closedErr *RemoteClosedError
+			if !errors.As(err, &closedErr) {
+				t.Fatalf("GoGitError() = %T, want *RemoteClosedError", err)
+			}
+			if closedErr.Message != tt.wantMsg {
+				t.Errorf("GoGitError() message = %q, want %q", closedErr.Message, tt.wantMsg)
+			}
+			if !errors.Is(err, ErrRemoteClosed) {
+				t.Errorf("GoGitError() = %v, want ErrRemoteClosed", err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("GoGitError() = %v, want it to wrap %v", err, tt.err)
+			}
+			if !IsRetriable(err) {
+				t.Errorf("IsRetriable(GoGitError()) = false, want true")
+			}
+		})
+	}
+
+	// The blank remote errors of the pushes keep their likely cause.
+	err := GoGitError(errors.New("unknown error: remote: "))
+	if errors.Is(err, ErrRemoteClosed) {
+		t.Errorf("GoGitError() = %v, want push rejection", err)
+	}
+}
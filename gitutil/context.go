@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ContextError is a git operation interrupted by the deadline or the
+// cancellation of its context, as opposed to a failure of the remote.
+type ContextError struct {
+	// Cause is either context.DeadlineExceeded or context.Canceled.
+	Cause error
+
+	msg string
+	err error
+}
+
+// Error returns whether the operation timed out or was canceled, followed
+// by the original message.
+func (e *ContextError) Error() string {
+	prefix := "git operation canceled"
+	if e.Cause == context.DeadlineExceeded {
+		prefix = "git operation timed out"
+	}
+	if e.msg == "" || e.msg == e.Cause.Error() {
+		return prefix
+	}
+	return prefix + ": " + e.msg
+}
+
+// Unwrap returns the original error.
+func (e *ContextError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is the cause of the error, this holds even
+// if the library flattened the context error into its message.
+func (e *ContextError) Is(target error) bool {
+	return target == e.Cause
+}
+
+// Class returns ClassTimeout or ClassCanceled.
+func (e *ContextError) Class() ErrorClass {
+	if e.Cause == context.DeadlineExceeded {
+		return ClassTimeout
+	}
+	return ClassCanceled
+}
+
+// contextClass returns the class of the errors wrapping a context error.
+func contextClass(err error) (ErrorClass, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout, true
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled, true
+	default:
+		return ClassUnknown, false
+	}
+}
+
+// contextError returns a ContextError wrapping err if the error, or its
+// message, holds a context error, nil otherwise.
+func contextError(msg string, err error) error {
+	var cause error
+	switch class, _ := contextClass(err); {
+	case class == ClassTimeout || strings.Contains(msg, context.DeadlineExceeded.Error()):
+		cause = context.DeadlineExceeded
+	case class == ClassCanceled || strings.Contains(msg, context.Canceled.Error()):
+		cause = context.Canceled
+	default:
+		return nil
+	}
+
+	return &ContextError{
+		Cause: cause,
+		msg:   strings.TrimSpace(msg),
+		err:   err,
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestContextError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCause error
+		wantMsg   string
+		wantClass ErrorClass
+	}{
+		{
+			name:      "wrapped deadline",
+			err:       fmt.Errorf("unexpected client error: %w", context.DeadlineExceeded),
+			wantCause: context.DeadlineExceeded,
+			wantMsg:   "git operation timed out: unexpected client error: context deadline exceeded",
+			wantClass: ClassTimeout,
+		},
+		{
+			name:      "flattened deadline",
+			err:       errors.New("failed to connect to github.com: context deadline exceeded"),
+			wantCause: context.DeadlineExceeded,
+			wantMsg:   "git operation timed out: failed to connect to github.com: context deadline exceeded",
+			wantClass: ClassTimeout,
+		},
+		{
+			name:      "bare cancellation",
+			err:       context.Canceled,
+			wantCause: context.Canceled,
+			wantMsg:   "git operation canceled",
+			wantClass: ClassCanceled,
+		},
+		{
+			name:      "flattened cancellation",
+			err:       errors.New("remote: context canceled"),
+			wantCause: context.Canceled,
+			wantMsg:   "git operation canceled: remote: context canceled",
+			wantClass: ClassCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(tt.err)
+
+				var ctxErr *ContextError
+				if !errors.As(err, &ctxErr) {
+					t.Fatalf("%s() = %T, want *ContextError", name, err)
+				}
+				if ctxErr.Cause != tt.wantCause {
+					t.Errorf("%s() cause = %v, want %v", name, ctxErr.Cause, tt.wantCause)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, tt.wantCause) {
+					t.Errorf("%s() = %v, want errors.Is %v", name, err, tt.wantCause)
+				}
+				if class := Classify(err); class != tt.wantClass {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, tt.wantClass)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyContext(t *testing.T) {
+	// A remote failure wrapping a context error is labeled by the context error.
+	err := fmt.Errorf("remote: connection reset: %w", context.DeadlineExceeded)
+	if class := Classify(err); class != ClassTimeout {
+		t.Errorf("Classify() = %s, want %s", class, ClassTimeout)
+	}
+	if !IsRetriable(err) {
+		t.Error("IsRetriable() = false, want true")
+	}
+	if IsRetriable(fmt.Errorf("clone failed: %w", context.Canceled)) {
+		t.Error("IsRetriable() = true, want false")
+	}
+}
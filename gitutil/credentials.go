@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrCredentialsExpired is returned by GoGitError, LibGit2Error and
+// GitCLIError, wrapped in a CredentialsExpiredError or a TLSError, when the
+// credentials are expired or no longer accepted by the provider.
+var ErrCredentialsExpired = errors.New("credentials expired")
+
+// CredentialsKind is the kind of the expired credentials.
+type CredentialsKind string
+
+const (
+	// CredentialsPassword is an account password no longer accepted for
+	// git operations, e.g. by GitHub.
+	CredentialsPassword CredentialsKind = "password"
+	// CredentialsToken is an expired access or deploy token.
+	CredentialsToken CredentialsKind = "token"
+)
+
+// credentialsExpiredRules maps the lower-cased messages of the expired
+// credentials failures to the kind of credentials, in order of precedence.
+var credentialsExpiredRules = []struct {
+	kind     CredentialsKind
+	patterns []string
+}{
+	{
+		kind: CredentialsPassword,
+		patterns: []string{
+			"password authentication is not available",
+			"support for password authentication was removed",
+		},
+	},
+	{
+		kind: CredentialsToken,
+		patterns: []string{
+			"token has expired",
+			"token is expired",
+			"token expired",
+			"expired token",
+			"credentials have expired",
+		},
+	},
+}
+
+// credentialsRemediations holds the remediation hints of the kinds of
+// expired credentials.
+var credentialsRemediations = map[CredentialsKind]string{
+	CredentialsPassword: "password authentication is not supported by the provider, use an access token instead",
+	CredentialsToken:    "the access token has expired, renew the token in the git secret",
+}
+
+// CredentialsExpiredError is a failure caused by expired credentials,
+// holding the kind of credentials to renew.
+type CredentialsExpiredError struct {
+	// Kind is the kind of the expired credentials.
+	Kind CredentialsKind
+
+	err error
+}
+
+// Error returns the remediation hint of the failure.
+func (e *CredentialsExpiredError) Error() string {
+	return ErrCredentialsExpired.Error() + ": " + credentialsRemediations[e.Kind]
+}
+
+// Unwrap returns the original error.
+func (e *CredentialsExpiredError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrCredentialsExpired.
+func (e *CredentialsExpiredError) Is(target error) bool {
+	return target == ErrCredentialsExpired
+}
+
+// Class returns ClassCredentialsExpired.
+func (e *CredentialsExpiredError) Class() ErrorClass {
+	return ClassCredentialsExpired
+}
+
+// credentialsExpiredPatterns returns the patterns of all the kinds of
+// expired credentials, including the expired TLS certificates.
+func credentialsExpiredPatterns() []string {
+	var patterns []string
+	for _, rule := range credentialsExpiredRules {
+		patterns = append(patterns, rule.patterns...)
+	}
+	return append(patterns, tlsPatterns(true)...)
+}
+
+// credentialsExpiredError returns a CredentialsExpiredError wrapping err if
+// the error message holds an expired credentials failure, nil otherwise.
+func credentialsExpiredError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	for _, rule := range credentialsExpiredRules {
+		for _, pattern := range rule.patterns {
+			if strings.Contains(lower, pattern) {
+				return &CredentialsExpiredError{
+					Kind: rule.kind,
+					err:  err,
+				}
+			}
+		}
+	}
+	return nil
+}
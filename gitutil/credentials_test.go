@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCredentialsExpiredError(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		wantKind CredentialsKind
+		wantMsg  string
+	}{
+		{
+			name: "GitHub password authentication",
+			msg: `remote: Support for password authentication was removed on August 13, 2021.
+remote: Please see https://docs.github.com/en/get-started/getting-started-with-git/about-remote-repositories#cloning-with-https-urls for information on currently recommended modes of authentication.
+fatal: Authentication failed for 'https://github.com/org/repo.git/'`,
+			wantKind: CredentialsPassword,
+			wantMsg:  "credentials expired: password authentication is not supported by the provider, use an access token instead",
+		},
+		{
+			name:     "GitHub password not available",
+			msg:      "remote: Password authentication is not available for Git operations.",
+			wantKind: CredentialsPassword,
+			wantMsg:  "credentials expired: password authentication is not supported by the provider, use an access token instead",
+		},
+		{
+			name:     "GitLab expired token",
+			msg:      "remote: HTTP Basic: Access denied. Your token has expired.",
+			wantKind: CredentialsToken,
+			wantMsg:  "credentials expired: the access token has expired, renew the token in the git secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var ceErr *CredentialsExpiredError
+				if !errors.As(err, &ceErr) {
+					t.Fatalf("%s() = %T, want *CredentialsExpiredError", name, err)
+				}
+				if ceErr.Kind != tt.wantKind {
+					t.Errorf("%s() kind = %q, want %q", name, ceErr.Kind, tt.wantKind)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrCredentialsExpired) {
+					t.Errorf("%s() = %v, want ErrCredentialsExpired", name, err)
+				}
+				if class := Classify(err); class != ClassCredentialsExpired {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassCredentialsExpired)
+				}
+			}
+		})
+	}
+}
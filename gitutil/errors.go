@@ -17,18 +17,102 @@ limitations under the License.
 package gitutil
 
 import (
-	"errors"
-	"fmt"
 	"strings"
 )
 
+// tidyError is an error with a tidied message, it wraps the original
+// library error so that it can be matched with errors.Is and errors.As.
+type tidyError struct {
+	msg string
+	err error
+}
+
+// Error returns the tidied message.
+func (e *tidyError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error.
+func (e *tidyError) Unwrap() error {
+	return e.err
+}
+
+// Class returns the class of the original error, or of the tidied message.
+func (e *tidyError) Class() ErrorClass {
+	return classify(e.err, e.msg)
+}
+
+// tidiedError returns a tidyError with the given message capped to the
+// message limits, or err if the message is unchanged.
+func tidiedError(msg string, changed bool, err error) error {
+	limited := limitMessage(msg)
+	if !changed && limited == msg {
+		return err
+	}
+	return &tidyError{msg: limited, err: err}
+}
+
+// typedError returns the typed error matching the error message, wrapping
+// err, or nil if the message doesn't match any typed error.
+func typedError(msg string, err error) error {
+	if ctxErr := contextError(msg, err); ctxErr != nil {
+		return ctxErr
+	}
+	if subErr := submoduleError(msg, err); subErr != nil {
+		return subErr
+	}
+	if lfsErr := lfsError(msg, err); lfsErr != nil {
+		return lfsErr
+	}
+	if adoErr := azureDevOpsError(msg, err); adoErr != nil {
+		return adoErr
+	}
+	if hkErr := hostKeyError(msg, err); hkErr != nil {
+		return hkErr
+	}
+	if sshErr := sshAuthError(msg, err); sshErr != nil {
+		return sshErr
+	}
+	if proxyErr := proxyError(msg, err); proxyErr != nil {
+		return proxyErr
+	}
+	if tlsErr := tlsError(msg, err); tlsErr != nil {
+		return tlsErr
+	}
+	if pushErr := pushRejectedError(msg, err); pushErr != nil {
+		return pushErr
+	}
+	if refErr := referenceError(msg, err); refErr != nil {
+		return refErr
+	}
+	if shallowErr := shallowCloneError(msg, err); shallowErr != nil {
+		return shallowErr
+	}
+	if rlErr := rateLimitError(msg, err); rlErr != nil {
+		return rlErr
+	}
+	if ceErr := credentialsExpiredError(msg, err); ceErr != nil {
+		return ceErr
+	}
+	if httpErr := httpStatusError(msg, err); httpErr != nil {
+		return httpErr
+	}
+	return nil
+}
+
 // GoGitError translates an error from the go-git library, or returns
-// `nil` if the argument is `nil`.
+// `nil` if the argument is `nil`. The credentials embedded in the error
+// message are redacted, see SanitizeErrorMessage. The returned error
+// wraps the original one.
 func GoGitError(err error) error {
 	if err == nil {
 		return nil
 	}
-	switch strings.TrimSpace(err.Error()) {
+	msg, redacted := redactMessage(err.Error())
+	if typedErr := typedError(msg, err); typedErr != nil {
+		return typedErr
+	}
+	switch strings.TrimSpace(msg) {
 	case "unknown error: remote:":
 		// this unhelpful error arises because go-git takes the first
 		// line of the output on stderr, and for some git providers
@@ -36,42 +120,57 @@ func GoGitError(err error) error {
 		// start. The rest of stderr is thrown away, so we can't get
 		// the actual error; but at least we know what was being
 		// attempted, and the likely cause.
-		return fmt.Errorf("push rejected; check git secret has write access")
+		return &tidyError{msg: "push rejected; check git secret has write access", err: err}
 	default:
-		return err
+		if closedErr := remoteClosedError(msg, err); closedErr != nil {
+			return closedErr
+		}
+		tidied, ok := applyTidyRules(msg)
+		return tidiedError(tidied, ok || redacted, err)
 	}
 }
 
 // LibGit2Error translates an error from the libgit2 library, or
-// returns `nil` if the argument is `nil`.
+// returns `nil` if the argument is `nil`. The credentials embedded in
+// the error message are redacted, see SanitizeErrorMessage. The returned
+// error wraps the original one.
 func LibGit2Error(err error) error {
 	if err == nil {
 		return err
 	}
+	msg, redacted := redactMessage(err.Error())
+	if typedErr := typedError(msg, err); typedErr != nil {
+		return typedErr
+	}
+	if codeErr := libgit2CodeError(err); codeErr != nil {
+		return codeErr
+	}
 	// libgit2 returns the whole output from stderr, and we only need
-	// the message. GitLab likes to return a banner, so as an
-	// heuristic, strip any lines that are just "remote:" and spaces
-	// or fencing.
-	msg := err.Error()
-	lines := strings.Split(msg, "\n")
-	if len(lines) == 1 {
-		return err
+	// the message.
+	if !strings.Contains(msg, "\n") {
+		tidied, ok := applyTidyRules(msg)
+		return tidiedError(tidied, ok || redacted, err)
 	}
-	var b strings.Builder
 	// the following removes the prefix "remote:" from each line; to
 	// retain a bit of fidelity to the original error, start with it.
-	b.WriteString("remote: ")
+	lines := ParseRemoteMessage(msg)
+	if tidied, ok := applyTidyRules("remote: " + strings.Join(lines, " ")); ok {
+		return tidiedError(tidied, true, err)
+	}
+	return tidiedError("remote: "+strings.Join(limitLines(lines), " "), true, err)
+}
 
-	var appending bool
-	for _, line := range lines {
-		m := strings.TrimPrefix(line, "remote:")
-		if m = strings.Trim(m, " \t="); m != "" {
-			if appending {
-				b.WriteString(" ")
-			}
-			b.WriteString(m)
-			appending = true
+// ParseRemoteMessage returns the meaningful lines of the output of a git
+// remote, e.g. the stderr captured by libgit2. The "remote:" prefixes are
+// stripped, and since GitLab likes to return a banner, as an heuristic the
+// lines that are just spaces or fencing are dropped.
+func ParseRemoteMessage(msg string) []string {
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		m := strings.TrimPrefix(strings.TrimSpace(line), "remote:")
+		if m = strings.Trim(m, " \t=\r"); m != "" && !isFence(m) {
+			lines = append(lines, m)
 		}
 	}
-	return errors.New(b.String())
+	return lines
 }
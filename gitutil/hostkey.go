@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrHostKeyMismatch is returned by GoGitError and LibGit2Error, wrapped in
+// a HostKeyError, when the SSH host key of the remote can't be verified
+// against the known hosts.
+var ErrHostKeyMismatch = errors.New("host key verification failed")
+
+// hostKeyPatterns holds the lower-cased messages of the SSH host key
+// verification failures returned by go-git, libgit2 and OpenSSH.
+var hostKeyPatterns = []string{
+	"knownhosts: key mismatch",
+	"knownhosts: key is unknown",
+	"hostkey could not be verified",
+	"host key verification failed",
+	"invalid or unknown remote ssh hostkey",
+	"remote host identification has changed",
+	"host key is known for",
+}
+
+// hostKeyHostRegexps match the host of the remote in the host key
+// verification failures, in order of precedence.
+var hostKeyHostRegexps = []*regexp.Regexp{
+	// No ED25519 host key is known for github.com and you have requested strict checking.
+	regexp.MustCompile(`(?i)host key is known for \[?([^\s\]]+?)\]?(?::\d+)?\s`),
+	// Host key for github.com has changed and you have requested strict checking.
+	regexp.MustCompile(`(?i)host key for \[?([^\s\]]+?)\]?(?::\d+)? has changed`),
+	// knownhosts: key mismatch for host 'github.com'
+	regexp.MustCompile(`(?i)for host '?\[?([^\s'\]]+?)\]?(?::\d+)?'?(?:\s|$)`),
+	// ssh://git@github.com:22/org/repo
+	regexp.MustCompile(`ssh://(?:[^@/\s]+@)?\[?([^:/\s\]]+)`),
+}
+
+// hostKeyTypeRegexps match the type of the offending host key in the
+// host key verification failures, in order of precedence.
+var hostKeyTypeRegexps = []*regexp.Regexp{
+	// The fingerprint for the ED25519 key sent by the remote host is
+	regexp.MustCompile(`(?i)fingerprint for the (\w+) key sent by the remote host`),
+	// No ECDSA host key is known for github.com
+	regexp.MustCompile(`(?i)no (\w+) host key is known`),
+	// Offending RSA key in /root/.ssh/known_hosts:3
+	regexp.MustCompile(`(?i)offending (\w+) key in`),
+	// ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAA...
+	regexp.MustCompile(`\b(ssh-(?:ed25519|rsa|dss)|ecdsa-sha2-nistp(?:256|384|521))\b`),
+}
+
+// HostKeyError is an SSH host key verification failure, holding the host
+// and the type of the key presented by the remote when they are known.
+type HostKeyError struct {
+	// Host is the host of the remote, empty if unknown.
+	Host string
+	// KeyType is the type of the host key, e.g. ED25519 or ssh-rsa,
+	// empty if unknown.
+	KeyType string
+
+	err error
+}
+
+// Error returns an actionable description of the failure.
+func (e *HostKeyError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrHostKeyMismatch.Error())
+	if e.Host != "" {
+		fmt.Fprintf(&b, " for host '%s'", e.Host)
+	}
+	if e.KeyType != "" {
+		fmt.Fprintf(&b, " with %s key", e.KeyType)
+	}
+	b.WriteString(": the host key presented by the remote doesn't match the known_hosts entries")
+	return b.String()
+}
+
+// Unwrap returns the original error.
+func (e *HostKeyError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrHostKeyMismatch.
+func (e *HostKeyError) Is(target error) bool {
+	return target == ErrHostKeyMismatch
+}
+
+// Class returns ClassHostKeyMismatch.
+func (e *HostKeyError) Class() ErrorClass {
+	return ClassHostKeyMismatch
+}
+
+// hostKeyError returns a HostKeyError wrapping err if the error message
+// holds an SSH host key verification failure, nil otherwise.
+func hostKeyError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	var found bool
+	for _, pattern := range hostKeyPatterns {
+		if strings.Contains(lower, pattern) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	return &HostKeyError{
+		Host:    firstSubmatch(hostKeyHostRegexps, msg),
+		KeyType: firstSubmatch(hostKeyTypeRegexps, msg),
+		err:     err,
+	}
+}
+
+// firstSubmatch returns the first capture group of the first matching regexp,
+// or an empty string if none matches.
+func firstSubmatch(res []*regexp.Regexp, s string) string {
+	for _, re := range res {
+		if m := re.FindStringSubmatch(s); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
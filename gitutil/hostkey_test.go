@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHostKeyError(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		wantHost    string
+		wantKeyType string
+	}{
+		{
+			name: "go-git knownhosts mismatch",
+			msg:  "ssh: handshake failed: knownhosts: key mismatch",
+		},
+		{
+			name:     "go-git knownhosts unknown key with URL",
+			msg:      "unable to clone 'ssh://git@github.com:22/org/repo': ssh: handshake failed: knownhosts: key is unknown",
+			wantHost: "github.com",
+		},
+		{
+			name:        "libgit2 hostkey callback",
+			msg:         "ssh: handshake failed: hostkey could not be verified for host 'gitlab.example.com:2222' with ssh-ed25519 key",
+			wantHost:    "gitlab.example.com",
+			wantKeyType: "ssh-ed25519",
+		},
+		{
+			name: "OpenSSH changed host key",
+			msg: `@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+@    WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED!     @
+@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+The fingerprint for the ED25519 key sent by the remote host is
+SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU.
+Offending ED25519 key in /root/.ssh/known_hosts:1
+Host key for github.com has changed and you have requested strict checking.
+Host key verification failed.`,
+			wantHost:    "github.com",
+			wantKeyType: "ED25519",
+		},
+		{
+			name:        "OpenSSH unknown host key",
+			msg:         "No ECDSA host key is known for [git.example.com]:2222 and you have requested strict checking.\nHost key verification failed.",
+			wantHost:    "git.example.com",
+			wantKeyType: "ECDSA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var hkErr *HostKeyError
+				if !errors.As(err, &hkErr) {
+					t.Fatalf("%s() = %T, want *HostKeyError", name, err)
+				}
+				if hkErr.Host != tt.wantHost {
+					t.Errorf("%s() host = %q, want %q", name, hkErr.Host, tt.wantHost)
+				}
+				if hkErr.KeyType != tt.wantKeyType {
+					t.Errorf("%s() key type = %q, want %q", name, hkErr.KeyType, tt.wantKeyType)
+				}
+				if !errors.Is(err, ErrHostKeyMismatch) {
+					t.Errorf("%s() = %v, want ErrHostKeyMismatch", name, err)
+				}
+				if !errors.Is(err, orig) {
+					t.Errorf("%s() doesn't wrap the original error", name)
+				}
+				if class := Classify(err); class != ClassHostKeyMismatch {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassHostKeyMismatch)
+				}
+			}
+		})
+	}
+}
+
+func TestHostKeyErrorMessage(t *testing.T) {
+	err := &HostKeyError{Host: "github.com", KeyType: "ssh-rsa"}
+	want := "host key verification failed for host 'github.com' with ssh-rsa key: the host key presented by the remote doesn't match the known_hosts entries"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
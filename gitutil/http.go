@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// httpStatusRegexps match the HTTP status codes embedded in the smart HTTP
+// transport errors, the first capture group is the status code.
+var httpStatusRegexps = []*regexp.Regexp{
+	// go-git: unexpected client error: unexpected requesting https://... status code: 403
+	// libgit2: unexpected http status code: 403
+	regexp.MustCompile(`(?i)status code:?\s*(\d{3})\b`),
+	// git: fatal: unable to access '...': The requested URL returned error: 403
+	regexp.MustCompile(`(?i)the requested url returned error:?\s*(\d{3})\b`),
+	// git: error: RPC failed; HTTP 403 curl 22 The requested URL returned error: 403
+	regexp.MustCompile(`\bHTTP (\d{3})\b`),
+}
+
+// httpStatusClasses maps the HTTP status codes to error classes.
+var httpStatusClasses = map[int]ErrorClass{
+	http.StatusUnauthorized:    ClassAuthFailed,
+	http.StatusForbidden:       ClassPermissionDenied,
+	http.StatusNotFound:        ClassRepositoryNotFound,
+	http.StatusTooManyRequests: ClassRateLimited,
+}
+
+// HTTPError is a smart HTTP transport failure caused by an unexpected HTTP
+// response status.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response, e.g. 403.
+	StatusCode int
+	// URL is the requested URL, with the credentials redacted, empty if unknown.
+	URL string
+
+	err error
+}
+
+// Error returns the status code and the requested URL.
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("unexpected HTTP status %d", e.StatusCode)
+	if text := http.StatusText(e.StatusCode); text != "" {
+		msg += " " + text
+	}
+	if e.URL != "" {
+		msg += " from '" + e.URL + "'"
+	}
+	return msg
+}
+
+// Unwrap returns the original error.
+func (e *HTTPError) Unwrap() error {
+	return e.err
+}
+
+// Class returns the class of the error, based on the status code.
+func (e *HTTPError) Class() ErrorClass {
+	return httpStatusClasses[e.StatusCode]
+}
+
+// httpStatusError returns an HTTPError wrapping err if the error message
+// holds an unexpected HTTP status, nil otherwise.
+func httpStatusError(msg string, err error) error {
+	code := 0
+	for _, re := range httpStatusRegexps {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			code, _ = strconv.Atoi(m[1])
+			break
+		}
+	}
+	if code < http.StatusBadRequest {
+		return nil
+	}
+
+	return &HTTPError{
+		StatusCode: code,
+		URL:        strings.TrimRight(remoteURLRegexp.FindString(msg), ".,:;)"),
+		err:        err,
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrLFS is returned by GoGitError, LibGit2Error and GitCLIError, wrapped
+// in an LFSError, when a Git LFS operation fails.
+var ErrLFS = errors.New("git LFS failure")
+
+// lfsPatterns holds the lower-cased messages of the Git LFS failures.
+var lfsPatterns = []string{
+	"batch response:",
+	"smudge filter lfs failed",
+	"git-lfs filter-process",
+	"smudge error:",
+	"missing lfs object",
+	"lfs object not found",
+	"lfs: ",
+}
+
+// lfsPathRegexps match the path of the file whose LFS object failed to
+// download, the first capture group is the path.
+var lfsPathRegexps = []*regexp.Regexp{
+	// Smudge error: Error downloading charts/app.tgz (a1b2c3...): batch response: ...
+	regexp.MustCompile(`(?i)error downloading (\S+) \(`),
+	// fatal: charts/app.tgz: smudge filter lfs failed
+	regexp.MustCompile(`(?i)(\S+): smudge filter lfs failed`),
+}
+
+// lfsReasonRegexps match the reason of the LFS failures, the first capture
+// group is the reason.
+var lfsReasonRegexps = []*regexp.Regexp{
+	// batch response: Authentication required: ...
+	regexp.MustCompile(`(?i)batch response:\s*([^\n]+)`),
+	// LFS: Repository or object not found: ...
+	regexp.MustCompile(`(?i)\blfs:\s*([^\n]+)`),
+}
+
+// LFSError is a failure of Git LFS, e.g. a missing LFS object or an LFS
+// server denying access, as opposed to a failure of the repository itself.
+type LFSError struct {
+	// Path is the path of the file whose LFS object failed, empty if unknown.
+	Path string
+	// Reason is the failure reported by Git LFS.
+	Reason string
+
+	err error
+}
+
+// Error returns the reason of the failure and the path of the file.
+func (e *LFSError) Error() string {
+	msg := ErrLFS.Error()
+	if e.Path != "" {
+		msg += " for '" + e.Path + "'"
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Unwrap returns the original error.
+func (e *LFSError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrLFS.
+func (e *LFSError) Is(target error) bool {
+	return target == ErrLFS
+}
+
+// Class returns ClassLFS.
+func (e *LFSError) Class() ErrorClass {
+	return ClassLFS
+}
+
+// lfsError returns an LFSError wrapping err if the error message holds a
+// Git LFS failure, nil otherwise.
+func lfsError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	var found bool
+	for _, pattern := range lfsPatterns {
+		if strings.Contains(lower, pattern) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	reason := firstSubmatch(lfsReasonRegexps, msg)
+	if reason == "" {
+		if lines := ParseRemoteMessage(msg); len(lines) > 0 {
+			reason = lines[0]
+			for _, prefix := range cliPrefixes {
+				reason = strings.TrimSpace(strings.TrimPrefix(reason, prefix))
+			}
+		}
+	}
+	return &LFSError{
+		Path:   firstSubmatch(lfsPathRegexps, msg),
+		Reason: strings.TrimSpace(reason),
+		err:    err,
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLFSError(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        string
+		wantPath   string
+		wantReason string
+	}{
+		{
+			name: "batch response forbidden",
+			msg: `Downloading charts/app.tgz (12 KB)
+Error downloading object: charts/app.tgz (a1b2c3d): Smudge error: Error downloading charts/app.tgz (a1b2c3d4e5f6): batch response: Authorization error: https://github.com/org/repo.git/info/lfs/objects/batch
+Check that you have proper access to the repository
+error: external filter 'git-lfs filter-process' failed
+fatal: charts/app.tgz: smudge filter lfs failed`,
+			wantPath:   "charts/app.tgz",
+			wantReason: "Authorization error: https://github.com/org/repo.git/info/lfs/objects/batch",
+		},
+		{
+			name:       "missing object",
+			msg:        "LFS: Repository or object not found: https://gitlab.com/group/project.git/info/lfs/objects/batch",
+			wantReason: "Repository or object not found: https://gitlab.com/group/project.git/info/lfs/objects/batch",
+		},
+		{
+			name:       "smudge filter",
+			msg:        "error: external filter 'git-lfs filter-process' failed\nfatal: assets/logo.png: smudge filter lfs failed",
+			wantPath:   "assets/logo.png",
+			wantReason: "external filter 'git-lfs filter-process' failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{
+				"LibGit2Error": LibGit2Error,
+				"GoGitError":   GoGitError,
+				"GitCLIError":  func(err error) error { return GitCLIError(err, err.Error()) },
+			} {
+				err := tidy(orig)
+
+				var lfsErr *LFSError
+				if !errors.As(err, &lfsErr) {
+					t.Fatalf("%s() = %T, want *LFSError", name, err)
+				}
+				if lfsErr.Path != tt.wantPath {
+					t.Errorf("%s() path = %q, want %q", name, lfsErr.Path, tt.wantPath)
+				}
+				if lfsErr.Reason != tt.wantReason {
+					t.Errorf("%s() reason = %q, want %q", name, lfsErr.Reason, tt.wantReason)
+				}
+				if !errors.Is(err, ErrLFS) {
+					t.Errorf("%s() = %v, want ErrLFS", name, err)
+				}
+				if class := Classify(err); class != ClassLFS {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassLFS)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"reflect"
+)
+
+// LibGit2Code is a libgit2 error code, e.g. GIT_EAUTH.
+type LibGit2Code int
+
+// The libgit2 error codes mapped to error classes, see include/git2/errors.h.
+const (
+	LibGit2ErrNotFound       LibGit2Code = -3
+	LibGit2ErrNonFastForward LibGit2Code = -11
+	LibGit2ErrAuth           LibGit2Code = -16
+	LibGit2ErrCertificate    LibGit2Code = -17
+	LibGit2ErrTimeout        LibGit2Code = -37
+)
+
+// LibGit2ErrorClass is a libgit2 error class, e.g. GIT_ERROR_SSH.
+type LibGit2ErrorClass int
+
+// The libgit2 error classes used to refine the mapping of the error codes,
+// see include/git2/errors.h.
+const (
+	LibGit2ErrorClassReference  LibGit2ErrorClass = 4
+	LibGit2ErrorClassRepository LibGit2ErrorClass = 6
+	LibGit2ErrorClassNet        LibGit2ErrorClass = 12
+	LibGit2ErrorClassSSH        LibGit2ErrorClass = 23
+	LibGit2ErrorClassHTTP       LibGit2ErrorClass = 34
+)
+
+// libgit2Code returns the code and the class of the first libgit2 error in
+// the chain of the given error. The libgit2 errors are recognised by their
+// integer Code and Class fields, as in git2go's GitError, without depending
+// on the libgit2 bindings.
+func libgit2Code(err error) (LibGit2Code, LibGit2ErrorClass, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		code, class := v.FieldByName("Code"), v.FieldByName("Class")
+		if isIntValue(code) && isIntValue(class) {
+			return LibGit2Code(code.Int()), LibGit2ErrorClass(class.Int()), true
+		}
+	}
+	return 0, 0, false
+}
+
+// isIntValue reports whether the value is a valid signed integer.
+func isIntValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// libgit2Class returns the class of the given error based on its libgit2
+// error code, or ClassUnknown.
+func libgit2Class(err error) ErrorClass {
+	code, class, ok := libgit2Code(err)
+	if !ok {
+		return ClassUnknown
+	}
+
+	switch code {
+	case LibGit2ErrAuth:
+		return ClassAuthFailed
+	case LibGit2ErrCertificate:
+		if class == LibGit2ErrorClassSSH {
+			return ClassHostKeyMismatch
+		}
+		return ClassTLS
+	case LibGit2ErrNonFastForward:
+		return ClassPushConflict
+	case LibGit2ErrTimeout:
+		return ClassNetworkError
+	case LibGit2ErrNotFound:
+		switch class {
+		case LibGit2ErrorClassReference:
+			return ClassReferenceNotFound
+		case LibGit2ErrorClassRepository, LibGit2ErrorClassNet, LibGit2ErrorClassHTTP:
+			return ClassRepositoryNotFound
+		}
+	}
+	return ClassUnknown
+}
+
+// libgit2CodeError returns the typed error matching the libgit2 error code
+// of the given error, wrapping it, or nil if the code doesn't map to a typed
+// error.
+func libgit2CodeError(err error) error {
+	switch libgit2Class(err) {
+	case ClassHostKeyMismatch:
+		return &HostKeyError{err: err}
+	case ClassTLS:
+		return &TLSError{Reason: "certificate verification failed", err: err}
+	case ClassPushConflict:
+		return &PushRejectedError{Conflict: true, Reason: "non-fast-forward", err: err}
+	case ClassReferenceNotFound:
+		return &ReferenceError{err: err}
+	case ClassAuthFailed:
+		if _, class, _ := libgit2Code(err); class == LibGit2ErrorClassSSH {
+			return &SSHAuthError{Hint: SSHAuthHintKeyNotAuthorized, err: err}
+		}
+	}
+	return nil
+}
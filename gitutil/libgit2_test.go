@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// gitError mimics git2go's GitError.
+type gitError struct {
+	Message string
+	Class   LibGit2ErrorClass
+	Code    LibGit2Code
+}
+
+func (e *gitError) Error() string {
+	return e.Message
+}
+
+func TestLibGit2ErrorCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantErr   error
+		wantClass ErrorClass
+	}{
+		{
+			name:      "SSH authentication",
+			err:       &gitError{Message: "callback returned unsupported credentials type", Class: LibGit2ErrorClassSSH, Code: LibGit2ErrAuth},
+			wantErr:   ErrSSHAuthFailed,
+			wantClass: ClassAuthFailed,
+		},
+		{
+			name:      "HTTP authentication",
+			err:       &gitError{Message: "too many redirects or authentication replays", Class: LibGit2ErrorClassHTTP, Code: LibGit2ErrAuth},
+			wantClass: ClassAuthFailed,
+		},
+		{
+			name:      "SSH host key",
+			err:       &gitError{Message: "user rejected certificate", Class: LibGit2ErrorClassSSH, Code: LibGit2ErrCertificate},
+			wantErr:   ErrHostKeyMismatch,
+			wantClass: ClassHostKeyMismatch,
+		},
+		{
+			name:      "TLS certificate",
+			err:       &gitError{Message: "user rejected certificate", Class: LibGit2ErrorClassHTTP, Code: LibGit2ErrCertificate},
+			wantErr:   ErrTLS,
+			wantClass: ClassTLS,
+		},
+		{
+			name:      "missing reference",
+			err:       fmt.Errorf("unable to checkout: %w", &gitError{Message: "revspec 'mian' not found", Class: LibGit2ErrorClassReference, Code: LibGit2ErrNotFound}),
+			wantErr:   ErrReferenceNotFound,
+			wantClass: ClassReferenceNotFound,
+		},
+		{
+			name:      "non-fast-forward",
+			err:       &gitError{Message: "cannot push because a reference that you are trying to update on the remote contains commits that are not present locally.", Class: LibGit2ErrorClassReference, Code: LibGit2ErrNonFastForward},
+			wantErr:   ErrPushConflict,
+			wantClass: ClassPushConflict,
+		},
+		{
+			name:      "missing repository",
+			err:       &gitError{Message: "unexpected http status code: 404", Class: LibGit2ErrorClassHTTP, Code: LibGit2ErrNotFound},
+			wantClass: ClassRepositoryNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if class := Classify(tt.err); class != tt.wantClass {
+				t.Errorf("Classify() = %s, want %s", class, tt.wantClass)
+			}
+
+			err := LibGit2Error(tt.err)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("LibGit2Error() = %v, want %v", err, tt.wantErr)
+			}
+			if class := Classify(err); class != tt.wantClass {
+				t.Errorf("Classify(LibGit2Error()) = %s, want %s", class, tt.wantClass)
+			}
+			var gitErr *gitError
+			if !errors.As(err, &gitErr) {
+				t.Error("LibGit2Error() doesn't wrap the original error")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxMessageLines is the default maximum number of remote output
+	// lines kept in the tidied error messages.
+	DefaultMaxMessageLines = 50
+	// DefaultMaxMessageBytes is the default maximum size in bytes of the
+	// tidied error messages.
+	DefaultMaxMessageBytes = 4096
+)
+
+// truncatedMarker is appended to the messages truncated to the maximum size.
+const truncatedMarker = "... [truncated]"
+
+var (
+	// maxMessageLines and maxMessageBytes hold the message limits set with
+	// SetMessageLimits.
+	maxMessageLines = DefaultMaxMessageLines
+	maxMessageBytes = DefaultMaxMessageBytes
+	messageLimitsMu sync.RWMutex
+)
+
+// SetMessageLimits sets the maximum number of remote output lines and the
+// maximum size in bytes of the messages of the errors returned by the tidy
+// functions, e.g. to keep the pre-receive hooks dumping hundreds of lines
+// from inflating the status of the Kubernetes objects. The truncated messages
+// end with a marker. A limit lower than or equal to zero disables it. The
+// limits default to DefaultMaxMessageLines and DefaultMaxMessageBytes. It is
+// safe for concurrent use.
+func SetMessageLimits(maxLines, maxBytes int) {
+	messageLimitsMu.Lock()
+	defer messageLimitsMu.Unlock()
+	maxMessageLines = maxLines
+	maxMessageBytes = maxBytes
+}
+
+// messageLimits returns the current message limits.
+func messageLimits() (int, int) {
+	messageLimitsMu.RLock()
+	defer messageLimitsMu.RUnlock()
+	return maxMessageLines, maxMessageBytes
+}
+
+// limitLines returns the lines capped to the maximum number of lines, with
+// a marker line holding the number of truncated lines.
+func limitLines(lines []string) []string {
+	maxLines, _ := messageLimits()
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return lines
+	}
+	limited := append([]string{}, lines[:maxLines]...)
+	return append(limited, fmt.Sprintf("... [%d more lines truncated]", len(lines)-maxLines))
+}
+
+// limitMessage returns the message capped to the maximum size, including the
+// truncation marker. The message is cut on a rune boundary.
+func limitMessage(msg string) string {
+	_, maxBytes := messageLimits()
+	if maxBytes <= 0 || len(msg) <= maxBytes {
+		return msg
+	}
+	n := maxBytes - len(truncatedMarker)
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(msg[n]) {
+		n--
+	}
+	return strings.TrimSpace(msg[:n]) + truncatedMarker
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMessageLimits(t *testing.T) {
+	t.Cleanup(func() {
+		SetMessageLimits(DefaultMaxMessageLines, DefaultMaxMessageBytes)
+	})
+
+	var banner strings.Builder
+	banner.WriteString("remote: \nremote: ====\n")
+	for i := 1; i <= 200; i++ {
+		fmt.Fprintf(&banner, "remote: policy check %d failed\n", i)
+	}
+	orig := errors.New(banner.String())
+
+	err := LibGit2Error(orig)
+	if !strings.HasPrefix(err.Error(), "remote: policy check 1 failed policy check 2 failed") {
+		t.Errorf("LibGit2Error() = %q, want the first lines", err.Error())
+	}
+	if !strings.HasSuffix(err.Error(), "policy check 50 failed ... [150 more lines truncated]") {
+		t.Errorf("LibGit2Error() = %q, want truncated lines", err.Error())
+	}
+	if !errors.Is(err, orig) {
+		t.Error("LibGit2Error() doesn't wrap the original error")
+	}
+
+	SetMessageLimits(0, 64)
+	err = LibGit2Error(orig)
+	if len(err.Error()) > 64 {
+		t.Errorf("LibGit2Error() = %d bytes, want at most 64", len(err.Error()))
+	}
+	if !strings.HasSuffix(err.Error(), "... [truncated]") {
+		t.Errorf("LibGit2Error() = %q, want truncation marker", err.Error())
+	}
+
+	long := errors.New(strings.Repeat("é", 100))
+	err = GoGitError(long)
+	if want := strings.Repeat("é", 24) + "... [truncated]"; err.Error() != want {
+		t.Errorf("GoGitError() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, long) {
+		t.Error("GoGitError() doesn't wrap the original error")
+	}
+
+	SetMessageLimits(0, 0)
+	if err := LibGit2Error(orig); !strings.HasSuffix(err.Error(), "policy check 200 failed") {
+		t.Errorf("LibGit2Error() = %q, want untruncated message", err.Error())
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Provider is a git hosting provider.
+type Provider int
+
+const (
+	// ProviderUnknown is a provider that couldn't be detected.
+	ProviderUnknown Provider = iota
+	// ProviderGitHub is GitHub and GitHub Enterprise Server.
+	ProviderGitHub
+	// ProviderGitLab is gitlab.com and the self-hosted GitLab instances.
+	ProviderGitLab
+	// ProviderBitbucket is Bitbucket Cloud and Bitbucket Server.
+	ProviderBitbucket
+	// ProviderAzureDevOps is Azure DevOps Services and Server.
+	ProviderAzureDevOps
+	// ProviderGitea is Gitea and its forks, e.g. Forgejo.
+	ProviderGitea
+)
+
+// String returns the name of the provider.
+func (p Provider) String() string {
+	switch p {
+	case ProviderGitHub:
+		return "GitHub"
+	case ProviderGitLab:
+		return "GitLab"
+	case ProviderBitbucket:
+		return "Bitbucket"
+	case ProviderAzureDevOps:
+		return "AzureDevOps"
+	case ProviderGitea:
+		return "Gitea"
+	default:
+		return "Unknown"
+	}
+}
+
+// scpURLRegexp matches the SCP-like git URLs, e.g. 'git@github.com:org/repo'.
+var scpURLRegexp = regexp.MustCompile(`^(?:[^@/\s]+@)?([^:/\s]+):[^/]`)
+
+// remoteURLRegexp matches the remote URLs embedded in the error messages.
+var remoteURLRegexp = regexp.MustCompile(`(?:https?|ssh|git)://[^\s'"]+|[\w.-]+@[\w.-]+:[\w.~/-]+`)
+
+// DetectProvider returns the provider of the given remote URL, based on the
+// host and, for the self-hosted instances, the conventional host names and
+// paths. It returns ProviderUnknown for unrecognised or invalid URLs.
+func DetectProvider(remoteURL string) Provider {
+	host, path := splitRemoteURL(strings.TrimSpace(remoteURL))
+	host = strings.ToLower(host)
+
+	switch {
+	case host == "dev.azure.com" || host == "ssh.dev.azure.com" ||
+		strings.HasSuffix(host, ".visualstudio.com") || strings.Contains(path, "/_git/"):
+		return ProviderAzureDevOps
+	case host == "bitbucket.org" || strings.Contains(host, "bitbucket") || strings.HasPrefix(path, "/scm/"):
+		return ProviderBitbucket
+	case host == "gitlab.com" || strings.Contains(host, "gitlab"):
+		return ProviderGitLab
+	case host == "codeberg.org" || strings.Contains(host, "gitea") || strings.Contains(host, "forgejo"):
+		return ProviderGitea
+	case host == "github.com" || strings.Contains(host, "github"):
+		return ProviderGitHub
+	default:
+		return ProviderUnknown
+	}
+}
+
+// splitRemoteURL returns the host and the path of the given remote URL,
+// which may be an SCP-like URL.
+func splitRemoteURL(remoteURL string) (string, string) {
+	if !strings.Contains(remoteURL, "://") {
+		if m := scpURLRegexp.FindStringSubmatch(remoteURL); m != nil {
+			return m[1], "/" + remoteURL[len(m[0])-1:]
+		}
+		// A host without a scheme, e.g. 'github.com/org/repo'.
+		remoteURL = "https://" + remoteURL
+	}
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return "", ""
+	}
+	return u.Hostname(), u.Path
+}
+
+// messageProvider returns the provider of the first remote URL found in the
+// given error message, or ProviderUnknown.
+func messageProvider(msg string) Provider {
+	if u := remoteURLRegexp.FindString(msg); u != "" {
+		return DetectProvider(u)
+	}
+	return ProviderUnknown
+}
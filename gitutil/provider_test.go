@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectProvider(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Provider
+	}{
+		{url: "https://github.com/fluxcd/flux2", want: ProviderGitHub},
+		{url: "ssh://git@github.com/fluxcd/flux2.git", want: ProviderGitHub},
+		{url: "git@github.com:fluxcd/flux2.git", want: ProviderGitHub},
+		{url: "https://github.example.com/org/repo", want: ProviderGitHub},
+		{url: "https://gitlab.com/group/project.git", want: ProviderGitLab},
+		{url: "git@gitlab.internal:group/sub/project.git", want: ProviderGitLab},
+		{url: "https://bitbucket.org/workspace/repo.git", want: ProviderBitbucket},
+		{url: "https://git.example.com/scm/proj/repo.git", want: ProviderBitbucket},
+		{url: "https://dev.azure.com/org/project/_git/repo", want: ProviderAzureDevOps},
+		{url: "git@ssh.dev.azure.com:v3/org/project/repo", want: ProviderAzureDevOps},
+		{url: "https://org.visualstudio.com/project/_git/repo", want: ProviderAzureDevOps},
+		{url: "https://tfs.example.com/tfs/collection/project/_git/repo", want: ProviderAzureDevOps},
+		{url: "https://codeberg.org/org/repo.git", want: ProviderGitea},
+		{url: "https://gitea.example.com/org/repo.git", want: ProviderGitea},
+		{url: "github.com/fluxcd/flux2", want: ProviderGitHub},
+		{url: "https://git.example.com/org/repo.git", want: ProviderUnknown},
+		{url: "", want: ProviderUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := DetectProvider(tt.url); got != tt.want {
+				t.Errorf("DetectProvider() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTidyProviderRules(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "rule of the detected provider",
+			msg:  "failed to push to https://gitlab.example.com/group/project.git: GitLab: The project you were looking for could not be found.",
+			want: "remote: The project you were looking for could not be found.",
+		},
+		{
+			name: "rule of another provider",
+			msg:  "failed to push to https://github.com/org/repo.git: GitLab: mirrored message",
+			want: "failed to push to https://github.com/org/repo.git: GitLab: mirrored message",
+		},
+		{
+			name: "no remote URL",
+			msg:  "GitLab: You are not allowed to push code to this project.",
+			want: "remote: permission denied: not allowed to push code to this project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := GoGitError(errors.New(tt.msg)); err.Error() != tt.want {
+				t.Errorf("GoGitError() = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
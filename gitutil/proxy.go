@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrProxy is returned by GoGitError, LibGit2Error and GitCLIError, wrapped
+// in a ProxyError, when the connection through an HTTP proxy fails.
+var ErrProxy = errors.New("proxy connection failed")
+
+// proxyPatterns holds the lower-cased messages of the proxy failures
+// returned by Go, libgit2 and curl.
+var proxyPatterns = []string{
+	"proxyconnect",
+	"from proxy after connect",
+	"connect tunnel failed",
+	"could not resolve proxy",
+	"proxy authentication required",
+	"proxy returned unexpected status",
+}
+
+// proxyHostRegexps match the host of the proxy, the first capture group is
+// the host.
+var proxyHostRegexps = []*regexp.Regexp{
+	// Go: proxyconnect tcp: dial tcp 10.0.0.1:3128: connect: connection refused
+	regexp.MustCompile(`(?i)proxyconnect \w+: dial \w+ ([^\s:]+:\d+)`),
+	// curl: Could not resolve proxy: proxy.example.com
+	regexp.MustCompile(`(?i)could not resolve proxy:?\s*([^\s;]+)`),
+}
+
+// proxyStatusRegexps match the HTTP status code returned by the proxy, the
+// first capture group is the status code.
+var proxyStatusRegexps = []*regexp.Regexp{
+	// curl: Received HTTP code 407 from proxy after CONNECT
+	regexp.MustCompile(`(?i)http code (\d{3}) from proxy`),
+	// curl: CONNECT tunnel failed, response 403
+	regexp.MustCompile(`(?i)connect tunnel failed, response (\d{3})`),
+	// libgit2: proxy returned unexpected status: 407
+	regexp.MustCompile(`(?i)proxy returned unexpected status:?\s*(\d{3})`),
+	// Go: Proxy Authentication Required
+	regexp.MustCompile(`(?i)()proxy authentication required`),
+}
+
+// ProxyError is a failure to connect to the remote through an HTTP proxy,
+// holding the proxy host and the HTTP status returned by the proxy when
+// they are known.
+type ProxyError struct {
+	// Proxy is the host of the proxy, empty if unknown.
+	Proxy string
+	// StatusCode is the HTTP status code returned by the proxy, zero if the
+	// proxy couldn't be reached.
+	StatusCode int
+	// Reason is the failure reported by the transport.
+	Reason string
+
+	err error
+}
+
+// Error returns the proxy, and the status code or the reason of the failure.
+func (e *ProxyError) Error() string {
+	msg := "connection through proxy"
+	if e.Proxy != "" {
+		msg += " '" + e.Proxy + "'"
+	}
+	msg += " failed"
+	if e.StatusCode != 0 {
+		return msg + fmt.Sprintf(" with HTTP status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return msg + ": " + e.Reason
+}
+
+// Unwrap returns the original error.
+func (e *ProxyError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrProxy.
+func (e *ProxyError) Is(target error) bool {
+	return target == ErrProxy
+}
+
+// Class returns ClassProxy.
+func (e *ProxyError) Class() ErrorClass {
+	return ClassProxy
+}
+
+// proxyError returns a ProxyError wrapping err if the error message holds a
+// proxy failure, nil otherwise.
+func proxyError(msg string, err error) error {
+	for _, line := range strings.Split(msg, "\n") {
+		lower := strings.ToLower(line)
+		for _, pattern := range proxyPatterns {
+			if !strings.Contains(lower, pattern) {
+				continue
+			}
+
+			proxyErr := &ProxyError{
+				Proxy: firstSubmatch(proxyHostRegexps, msg),
+				err:   err,
+			}
+			for _, re := range proxyStatusRegexps {
+				if m := re.FindStringSubmatch(msg); m != nil {
+					proxyErr.StatusCode = http.StatusProxyAuthRequired
+					if m[1] != "" {
+						proxyErr.StatusCode, _ = strconv.Atoi(m[1])
+					}
+					break
+				}
+			}
+			proxyErr.Reason = strings.TrimSpace(line)
+			for _, prefix := range cliPrefixes {
+				proxyErr.Reason = strings.TrimSpace(strings.TrimPrefix(proxyErr.Reason, prefix))
+			}
+			return proxyErr
+		}
+	}
+	return nil
+}
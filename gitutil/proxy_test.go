@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProxyError(t *testing.T) {
+	tests := []struct {
+		name           string
+		msg            string
+		wantProxy      string
+		wantStatusCode int
+		wantMsg        string
+		wantRetriable  bool
+	}{
+		{
+			name:          "Go proxy unreachable",
+			msg:           `Get "https://github.com/org/repo/info/refs?service=git-upload-pack": proxyconnect tcp: dial tcp 10.0.0.1:3128: connect: connection refused`,
+			wantProxy:     "10.0.0.1:3128",
+			wantMsg:       `connection through proxy '10.0.0.1:3128' failed: Get "https://github.com/org/repo/info/refs?service=git-upload-pack": proxyconnect tcp: dial tcp 10.0.0.1:3128: connect: connection refused`,
+			wantRetriable: true,
+		},
+		{
+			name:           "Go proxy authentication",
+			msg:            `Get "https://github.com/org/repo/info/refs?service=git-upload-pack": Proxy Authentication Required`,
+			wantStatusCode: 407,
+			wantMsg:        "connection through proxy failed with HTTP status 407 Proxy Authentication Required",
+		},
+		{
+			name:           "curl CONNECT rejected",
+			msg:            "fatal: unable to access 'https://github.com/org/repo.git/': Received HTTP code 403 from proxy after CONNECT",
+			wantStatusCode: 403,
+			wantMsg:        "connection through proxy failed with HTTP status 403 Forbidden",
+		},
+		{
+			name:          "curl unresolved proxy",
+			msg:           "fatal: unable to access 'https://github.com/org/repo.git/': Could not resolve proxy: proxy.example.com",
+			wantProxy:     "proxy.example.com",
+			wantMsg:       "connection through proxy 'proxy.example.com' failed: unable to access 'https://github.com/org/repo.git/': Could not resolve proxy: proxy.example.com",
+			wantRetriable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var proxyErr *ProxyError
+				if !errors.As(err, &proxyErr) {
+					t.Fatalf("%s() = %T, want *ProxyError", name, err)
+				}
+				if proxyErr.Proxy != tt.wantProxy {
+					t.Errorf("%s() proxy = %q, want %q", name, proxyErr.Proxy, tt.wantProxy)
+				}
+				if proxyErr.StatusCode != tt.wantStatusCode {
+					t.Errorf("%s() status code = %d, want %d", name, proxyErr.StatusCode, tt.wantStatusCode)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrProxy) {
+					t.Errorf("%s() = %v, want ErrProxy", name, err)
+				}
+				if class := Classify(err); class != ClassProxy {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassProxy)
+				}
+				if retriable := IsRetriable(err); retriable != tt.wantRetriable {
+					t.Errorf("IsRetriable(%s()) = %v, want %v", name, retriable, tt.wantRetriable)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrPushConflict is returned by GoGitError, LibGit2Error and GitCLIError,
+	// wrapped in a PushRejectedError, when a push is rejected because it
+	// doesn't fast-forward the remote reference. The push may succeed after
+	// fetching and rebasing.
+	ErrPushConflict = errors.New("push rejected due to conflict")
+	// ErrPushDeclined is returned by GoGitError, LibGit2Error and GitCLIError,
+	// wrapped in a PushRejectedError, when a push is declined by a branch
+	// protection policy. Retrying the push won't succeed.
+	ErrPushDeclined = errors.New("push declined by policy")
+)
+
+// pushRejectionRules maps the lower-cased messages of the push rejections to
+// their reason, in order of precedence.
+var pushRejectionRules = []struct {
+	pattern  string
+	conflict bool
+	reason   string
+}{
+	{pattern: "non-fast-forward", conflict: true, reason: "non-fast-forward"},
+	{pattern: "non-fastforwardable", conflict: true, reason: "non-fast-forward"},
+	{pattern: "tip of your current branch is behind", conflict: true, reason: "non-fast-forward"},
+	{pattern: "(fetch first)", conflict: true, reason: "the remote contains commits missing locally"},
+	{pattern: "cannot lock ref", conflict: true, reason: "the reference was updated concurrently"},
+	{pattern: "protected branch hook declined", reason: "protected branch hook declined"},
+	{pattern: "gh006: protected branch update failed", reason: "protected branch update failed"},
+}
+
+// pushRefRegexps match the reference of the push rejections, the first
+// capture group is the reference.
+var pushRefRegexps = []*regexp.Regexp{
+	// go-git: non-fast-forward update: refs/heads/main
+	regexp.MustCompile(`(?i)non-fast-forward update:\s*(\S+)`),
+	// git: ! [rejected]        main -> main (non-fast-forward)
+	regexp.MustCompile(`(?i)\[(?:remote )?rejected\]\s+\S+\s+->\s+(\S+)`),
+	// git: error: cannot lock ref 'refs/heads/main': is at 1a2b3c but expected 4d5e6f
+	regexp.MustCompile(`(?i)cannot lock ref '([^']+)'`),
+	// GitHub: error: GH006: Protected branch update failed for refs/heads/main.
+	regexp.MustCompile(`(?i)protected branch update failed for (\S+?)\.?(?:\s|$)`),
+}
+
+// PushRejectedError is a push rejected by the remote, either due to a
+// conflict with the remote reference or by a branch protection policy.
+// Automated pushers can retry after a rebase only in case of conflict.
+type PushRejectedError struct {
+	// Ref is the rejected reference, empty if unknown.
+	Ref string
+	// Conflict reports whether the push was rejected due to a conflict, as
+	// opposed to a policy.
+	Conflict bool
+	// Reason is the reason of the rejection.
+	Reason string
+
+	err error
+}
+
+// Error returns the reason of the rejection and how to resolve it.
+func (e *PushRejectedError) Error() string {
+	msg := "push"
+	if e.Ref != "" {
+		msg += " to '" + e.Ref + "'"
+	}
+	if e.Conflict {
+		return msg + " rejected: " + e.Reason + "; fetch and rebase before retrying"
+	}
+	return msg + " declined by the remote: " + e.Reason
+}
+
+// Unwrap returns the original error.
+func (e *PushRejectedError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrPushConflict or ErrPushDeclined,
+// depending on the reason of the rejection.
+func (e *PushRejectedError) Is(target error) bool {
+	if e.Conflict {
+		return target == ErrPushConflict
+	}
+	return target == ErrPushDeclined
+}
+
+// Class returns ClassPushConflict for conflicts and ClassRemoteRejected for
+// policy rejections.
+func (e *PushRejectedError) Class() ErrorClass {
+	if e.Conflict {
+		return ClassPushConflict
+	}
+	return ClassRemoteRejected
+}
+
+// pushConflictPatterns returns the patterns of the push rejections caused
+// by conflicts.
+func pushConflictPatterns() []string {
+	var patterns []string
+	for _, rule := range pushRejectionRules {
+		if rule.conflict {
+			patterns = append(patterns, rule.pattern)
+		}
+	}
+	return patterns
+}
+
+// pushRejectedError returns a PushRejectedError wrapping err if the error
+// message holds a push rejection, nil otherwise.
+func pushRejectedError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	for _, rule := range pushRejectionRules {
+		if strings.Contains(lower, rule.pattern) {
+			return &PushRejectedError{
+				Ref:      firstSubmatch(pushRefRegexps, msg),
+				Conflict: rule.conflict,
+				Reason:   rule.reason,
+				err:      err,
+			}
+		}
+	}
+	return nil
+}
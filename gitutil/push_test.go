@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPushRejectedError(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantRef   string
+		wantMsg   string
+		wantErr   error
+		wantClass ErrorClass
+	}{
+		{
+			name:      "go-git non-fast-forward",
+			msg:       "non-fast-forward update: refs/heads/main",
+			wantRef:   "refs/heads/main",
+			wantMsg:   "push to 'refs/heads/main' rejected: non-fast-forward; fetch and rebase before retrying",
+			wantErr:   ErrPushConflict,
+			wantClass: ClassPushConflict,
+		},
+		{
+			name:      "libgit2 non-fast-forward",
+			msg:       "cannot push non-fastforwardable reference",
+			wantMsg:   "push rejected: non-fast-forward; fetch and rebase before retrying",
+			wantErr:   ErrPushConflict,
+			wantClass: ClassPushConflict,
+		},
+		{
+			name: "git fetch first",
+			msg: `To github.com:org/repo.git
+ ! [rejected]        main -> main (fetch first)
+error: failed to push some refs to 'github.com:org/repo.git'`,
+			wantRef:   "main",
+			wantMsg:   "push to 'main' rejected: the remote contains commits missing locally; fetch and rebase before retrying",
+			wantErr:   ErrPushConflict,
+			wantClass: ClassPushConflict,
+		},
+		{
+			name:      "concurrent update",
+			msg:       " ! [remote rejected] main -> main (cannot lock ref 'refs/heads/main': is at 1a2b3c but expected 4d5e6f)",
+			wantRef:   "main",
+			wantMsg:   "push to 'main' rejected: the reference was updated concurrently; fetch and rebase before retrying",
+			wantErr:   ErrPushConflict,
+			wantClass: ClassPushConflict,
+		},
+		{
+			name: "GitLab protected branch",
+			msg: `To https://gitlab.com/group/project.git
+ ! [remote rejected] main -> main (protected branch hook declined)`,
+			wantRef:   "main",
+			wantMsg:   "push to 'main' declined by the remote: protected branch hook declined",
+			wantErr:   ErrPushDeclined,
+			wantClass: ClassRemoteRejected,
+		},
+		{
+			name: "GitHub protected branch",
+			msg: `remote: error: GH006: Protected branch update failed for refs/heads/main.
+remote: error: Required status check "ci" is expected.`,
+			wantRef:   "refs/heads/main",
+			wantMsg:   "push to 'refs/heads/main' declined by the remote: protected branch update failed",
+			wantErr:   ErrPushDeclined,
+			wantClass: ClassRemoteRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var pushErr *PushRejectedError
+				if !errors.As(err, &pushErr) {
+					t.Fatalf("%s() = %T, want *PushRejectedError", name, err)
+				}
+				if pushErr.Ref != tt.wantRef {
+					t.Errorf("%s() ref = %q, want %q", name, pushErr.Ref, tt.wantRef)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s() = %v, want %v", name, err, tt.wantErr)
+				}
+				if class := Classify(err); class != tt.wantClass {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, tt.wantClass)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited is returned by GoGitError, LibGit2Error and GitCLIError,
+// wrapped in a RateLimitError, when the provider throttles the requests.
+var ErrRateLimited = errors.New("rate limited by the git provider")
+
+// rateLimitPatterns holds the lower-cased messages of the rate limit and
+// abuse detection failures returned by the git providers.
+var rateLimitPatterns = []string{
+	// GitHub primary and secondary rate limits.
+	"rate limit exceeded",
+	"exceeded a secondary rate limit",
+	"abuse detection mechanism",
+	// GitLab Rack::Attack throttling.
+	"requested too many times",
+	"retry later",
+	"too many requests",
+	"status code: 429",
+	"returned error: 429",
+}
+
+// retryAfterRegexps match the retry hints of the rate limit failures, the
+// capture groups are the amount and the optional unit.
+var retryAfterRegexps = []*regexp.Regexp{
+	// Retry-After: 60
+	regexp.MustCompile(`(?i)retry-after:?\s*(\d+)()`),
+	// Please retry in 30 seconds. / Try again in 2 minutes.
+	regexp.MustCompile(`(?i)(?:retry|try again)(?: after| in)\s+(\d+)\s*(s|sec|second|m|min|minute|h|hour)s?\b`),
+	// Please wait 5 minutes before you try again.
+	regexp.MustCompile(`(?i)wait\s+(\d+)\s*(s|sec|second|m|min|minute|h|hour)s?\b`),
+}
+
+// RateLimitError is a failure caused by the provider throttling the requests,
+// holding the time to wait before retrying when the provider hinted it.
+type RateLimitError struct {
+	// RetryAfter is the time to wait before retrying, zero if unknown.
+	RetryAfter time.Duration
+
+	err error
+}
+
+// Error returns the retry hint of the failure.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return ErrRateLimited.Error() + ", retry after " + e.RetryAfter.String()
+	}
+	return ErrRateLimited.Error() + ", retry later"
+}
+
+// Unwrap returns the original error.
+func (e *RateLimitError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Class returns ClassRateLimited.
+func (e *RateLimitError) Class() ErrorClass {
+	return ClassRateLimited
+}
+
+// rateLimitError returns a RateLimitError wrapping err if the error message
+// holds a rate limit failure, nil otherwise.
+func rateLimitError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	var found bool
+	for _, pattern := range rateLimitPatterns {
+		if strings.Contains(lower, pattern) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	return &RateLimitError{
+		RetryAfter: retryAfter(msg),
+		err:        err,
+	}
+}
+
+// retryAfter returns the retry hint found in the message, or zero.
+func retryAfter(msg string) time.Duration {
+	for _, re := range retryAfterRegexps {
+		m := re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		unit := time.Second
+		switch strings.ToLower(m[2]) {
+		case "m", "min", "minute":
+			unit = time.Minute
+		case "h", "hour":
+			unit = time.Hour
+		}
+		return time.Duration(n) * unit
+	}
+	return 0
+}
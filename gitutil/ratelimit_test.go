@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitError(t *testing.T) {
+	tests := []struct {
+		name           string
+		msg            string
+		wantRetryAfter time.Duration
+		wantMsg        string
+	}{
+		{
+			name: "GitHub secondary rate limit",
+			msg: `remote: 
+remote: You have exceeded a secondary rate limit. Please wait a few minutes before you try again.
+remote: `,
+			wantMsg: "rate limited by the git provider, retry later",
+		},
+		{
+			name:           "GitHub rate limit with Retry-After",
+			msg:            "remote: API rate limit exceeded for installation. Retry-After: 60",
+			wantRetryAfter: time.Minute,
+			wantMsg:        "rate limited by the git provider, retry after 1m0s",
+		},
+		{
+			name:           "GitLab throttling",
+			msg:            "remote: This endpoint has been requested too many times. Try again in 30 seconds.",
+			wantRetryAfter: 30 * time.Second,
+			wantMsg:        "rate limited by the git provider, retry after 30s",
+		},
+		{
+			name:    "HTTP 429",
+			msg:     "unexpected client error: unexpected requesting https://gitlab.com/group/project.git/info/refs status code: 429",
+			wantMsg: "rate limited by the git provider, retry later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var rlErr *RateLimitError
+				if !errors.As(err, &rlErr) {
+					t.Fatalf("%s() = %T, want *RateLimitError", name, err)
+				}
+				if rlErr.RetryAfter != tt.wantRetryAfter {
+					t.Errorf("%s() retry after = %v, want %v", name, rlErr.RetryAfter, tt.wantRetryAfter)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrRateLimited) {
+					t.Errorf("%s() = %v, want ErrRateLimited", name, err)
+				}
+				if class := Classify(err); class != ClassRateLimited {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassRateLimited)
+				}
+				if !IsRetriable(err) {
+					t.Errorf("IsRetriable(%s()) = false, want true", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrReferenceNotFound is returned by GoGitError, LibGit2Error and
+// GitCLIError, wrapped in a ReferenceError, when a branch, tag or other
+// reference doesn't exist.
+var ErrReferenceNotFound = errors.New("reference not found")
+
+// referencePatterns holds the lower-cased messages of the missing
+// reference failures.
+var referencePatterns = []string{
+	"couldn't find remote ref",
+	"reference not found",
+	"not found in upstream",
+	"cannot locate remote-tracking branch",
+}
+
+// referenceRegexps match the missing reference failures returned by go-git,
+// libgit2 and the git binary, the first capture group is the reference name.
+var referenceRegexps = []*regexp.Regexp{
+	// go-git: couldn't find remote ref "refs/heads/main"
+	// git: fatal: couldn't find remote ref refs/heads/main
+	regexp.MustCompile(`(?i)couldn't find remote ref "?([^"\s]+)"?`),
+	// libgit2: reference 'refs/heads/main' not found
+	regexp.MustCompile(`(?i)reference '([^']+)' not found`),
+	// git: warning: Remote branch main not found in upstream origin
+	regexp.MustCompile(`(?i)remote branch (\S+) not found in upstream`),
+	// libgit2: cannot locate remote-tracking branch 'origin/main'
+	regexp.MustCompile(`(?i)cannot locate remote-tracking branch '([^']+)'`),
+	// go-git wrapped by the Flux git clients: unable to resolve tag 'v1.0.0': reference not found
+	regexp.MustCompile(`(?i)'([^']+)':\s*reference not found`),
+	// go-git: reference not found
+	regexp.MustCompile(`(?i)()reference not found`),
+}
+
+// ReferenceError is a failure caused by a branch, tag or other reference
+// that doesn't exist, holding the name of the reference when known.
+type ReferenceError struct {
+	// Ref is the name of the missing reference, empty if unknown.
+	Ref string
+
+	err error
+}
+
+// Error returns the name of the missing reference.
+func (e *ReferenceError) Error() string {
+	if e.Ref == "" {
+		return ErrReferenceNotFound.Error()
+	}
+	return "reference '" + e.Ref + "' not found"
+}
+
+// Unwrap returns the original error.
+func (e *ReferenceError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrReferenceNotFound.
+func (e *ReferenceError) Is(target error) bool {
+	return target == ErrReferenceNotFound
+}
+
+// Class returns ClassReferenceNotFound.
+func (e *ReferenceError) Class() ErrorClass {
+	return ClassReferenceNotFound
+}
+
+// referenceError returns a ReferenceError wrapping err if the error message
+// holds a missing reference failure, nil otherwise.
+func referenceError(msg string, err error) error {
+	for _, re := range referenceRegexps {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			return &ReferenceError{
+				Ref: strings.TrimSpace(m[1]),
+				err: err,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReferenceError(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		wantRef string
+	}{
+		{
+			name: "go-git reference not found",
+			msg:  "reference not found",
+		},
+		{
+			name:    "go-git missing remote ref",
+			msg:     `couldn't find remote ref "refs/heads/mian"`,
+			wantRef: "refs/heads/mian",
+		},
+		{
+			name:    "git missing remote ref",
+			msg:     "fatal: couldn't find remote ref refs/heads/mian",
+			wantRef: "refs/heads/mian",
+		},
+		{
+			name:    "libgit2 missing reference",
+			msg:     "reference 'refs/tags/v1.0.0' not found",
+			wantRef: "refs/tags/v1.0.0",
+		},
+		{
+			name:    "libgit2 missing remote-tracking branch",
+			msg:     "cannot locate remote-tracking branch 'origin/mian'",
+			wantRef: "origin/mian",
+		},
+		{
+			name:    "git clone missing branch",
+			msg:     "warning: Could not find remote branch mian to clone.\nfatal: Remote branch mian not found in upstream origin",
+			wantRef: "mian",
+		},
+		{
+			name:    "wrapped go-git reference not found",
+			msg:     "unable to resolve tag 'v1.0.0': reference not found",
+			wantRef: "v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{
+				"LibGit2Error": LibGit2Error,
+				"GoGitError":   GoGitError,
+				"GitCLIError":  func(err error) error { return GitCLIError(err, err.Error()) },
+			} {
+				err := tidy(orig)
+
+				var refErr *ReferenceError
+				if !errors.As(err, &refErr) {
+					t.Fatalf("%s() = %T, want *ReferenceError", name, err)
+				}
+				if refErr.Ref != tt.wantRef {
+					t.Errorf("%s() ref = %q, want %q", name, refErr.Ref, tt.wantRef)
+				}
+				if !errors.Is(err, ErrReferenceNotFound) {
+					t.Errorf("%s() = %v, want ErrReferenceNotFound", name, err)
+				}
+				if class := Classify(err); class != ClassReferenceNotFound {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassReferenceNotFound)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// transientPatterns holds the lower-cased messages of the transient git
+// failures, which are likely to succeed when retried.
+var transientPatterns = []string{
+	"temporary failure in name resolution",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"unexpected eof",
+	"broken pipe",
+	"tls handshake timeout",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"status code: 500",
+	"status code: 502",
+	"status code: 503",
+	"status code: 504",
+}
+
+// IsRetriable reports whether the given error is a transient git failure,
+// e.g. a connection reset, a DNS failure, a rate limit or a 5xx response from
+// a smart HTTP server, as opposed to a permanent one like invalid credentials
+// or a missing reference. Cancelled contexts are not retriable, exceeded
+// context deadlines are.
+func IsRetriable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch Classify(err) {
+	case ClassNetworkError, ClassTimeout, ClassRateLimited:
+		return true
+	case ClassProxy:
+		// The proxy may be temporarily unreachable or failing, while its
+		// other responses, e.g. 407, won't change on retry.
+		var proxyErr *ProxyError
+		return errors.As(err, &proxyErr) &&
+			(proxyErr.StatusCode == 0 || proxyErr.StatusCode >= http.StatusInternalServerError)
+	case ClassUnknown:
+		// Check for the transient failures below.
+	default:
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range transientPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
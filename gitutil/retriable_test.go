@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIsRetriable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil error",
+			want: false,
+		},
+		{
+			name: "connection reset",
+			err:  errors.New("read tcp 10.0.0.1:1234->140.82.121.4:443: read: connection reset by peer"),
+			want: true,
+		},
+		{
+			name: "DNS failure",
+			err:  errors.New("dial tcp: lookup github.com on 10.96.0.10:53: no such host"),
+			want: true,
+		},
+		{
+			name: "remote hung up",
+			err:  errors.New("fatal: the remote end hung up unexpectedly"),
+			want: true,
+		},
+		{
+			name: "smart HTTP 503",
+			err:  errors.New("unexpected client error: unexpected requesting https://github.com/org/repo/info/refs status code: 503"),
+			want: true,
+		},
+		{
+			name: "net timeout",
+			err:  fmt.Errorf("clone failed: %w", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}),
+			want: true,
+		},
+		{
+			name: "cancelled context",
+			err:  fmt.Errorf("clone failed: %w", context.Canceled),
+			want: false,
+		},
+		{
+			name: "bad credentials",
+			err:  errors.New("authentication required"),
+			want: false,
+		},
+		{
+			name: "missing reference",
+			err:  errors.New("couldn't find remote ref refs/heads/main"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetriable(tt.err); got != tt.want {
+				t.Errorf("IsRetriable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrShallowClone is returned by GoGitError, LibGit2Error and GitCLIError,
+// wrapped in a ShallowCloneError, when an operation fails because the
+// local repository is a shallow clone.
+var ErrShallowClone = errors.New("operation not supported on shallow clone")
+
+// shallowPatterns holds the lower-cased messages of the failures caused by
+// shallow clones.
+var shallowPatterns = []string{
+	"server does not allow request for unadvertised object",
+	"does not support shallow",
+	"shallow update not allowed",
+	"shallow file has changed since we read it",
+	"--unshallow on a complete repository",
+	"unable to unshallow",
+}
+
+// ShallowCloneError is a failure caused by a shallow clone, e.g. fetching a
+// commit outside of the cloned history or pushing from a shallow clone to a
+// remote which doesn't accept it.
+type ShallowCloneError struct {
+	// Reason is the failure reported by git.
+	Reason string
+
+	err error
+}
+
+// Error returns the reason of the failure and suggests a full clone.
+func (e *ShallowCloneError) Error() string {
+	return ErrShallowClone.Error() + ": " + e.Reason + "; retry with a full clone"
+}
+
+// Unwrap returns the original error.
+func (e *ShallowCloneError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrShallowClone.
+func (e *ShallowCloneError) Is(target error) bool {
+	return target == ErrShallowClone
+}
+
+// Class returns ClassShallowClone.
+func (e *ShallowCloneError) Class() ErrorClass {
+	return ClassShallowClone
+}
+
+// shallowCloneError returns a ShallowCloneError wrapping err if the error
+// message holds a failure caused by a shallow clone, nil otherwise.
+func shallowCloneError(msg string, err error) error {
+	for _, line := range ParseRemoteMessage(msg) {
+		lower := strings.ToLower(line)
+		for _, pattern := range shallowPatterns {
+			if strings.Contains(lower, pattern) {
+				for _, prefix := range cliPrefixes {
+					line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+				}
+				return &ShallowCloneError{
+					Reason: strings.TrimSuffix(line, "."),
+					err:    err,
+				}
+			}
+		}
+	}
+	return nil
+}
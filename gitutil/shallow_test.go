@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShallowCloneError(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        string
+		wantReason string
+	}{
+		{
+			name:       "unadvertised object",
+			msg:        "error: Server does not allow request for unadvertised object 2b3c4d5e6f\nfatal: the remote end hung up unexpectedly",
+			wantReason: "Server does not allow request for unadvertised object 2b3c4d5e6f",
+		},
+		{
+			name:       "push from shallow clone",
+			msg:        "remote: \nremote: fatal: shallow update not allowed\n",
+			wantReason: "shallow update not allowed",
+		},
+		{
+			name:       "unshallow complete repository",
+			msg:        "fatal: --unshallow on a complete repository does not make sense",
+			wantReason: "--unshallow on a complete repository does not make sense",
+		},
+		{
+			name:       "dumb transport",
+			msg:        "fatal: dumb http transport does not support shallow capabilities",
+			wantReason: "dumb http transport does not support shallow capabilities",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{
+				"LibGit2Error": LibGit2Error,
+				"GoGitError":   GoGitError,
+				"GitCLIError":  func(err error) error { return GitCLIError(err, err.Error()) },
+			} {
+				err := tidy(orig)
+
+				var shallowErr *ShallowCloneError
+				if !errors.As(err, &shallowErr) {
+					t.Fatalf("%s() = %T, want *ShallowCloneError", name, err)
+				}
+				if shallowErr.Reason != tt.wantReason {
+					t.Errorf("%s() reason = %q, want %q", name, shallowErr.Reason, tt.wantReason)
+				}
+				if !errors.Is(err, ErrShallowClone) {
+					t.Errorf("%s() = %v, want ErrShallowClone", name, err)
+				}
+				if class := Classify(err); class != ClassShallowClone {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassShallowClone)
+				}
+			}
+		})
+	}
+}
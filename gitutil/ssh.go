@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrSSHAuthFailed is returned by GoGitError, LibGit2Error and GitCLIError,
+// wrapped in an SSHAuthError, when the SSH public key authentication fails.
+var ErrSSHAuthFailed = errors.New("SSH authentication failed")
+
+// SSHAuthHint is the likely cause of an SSH authentication failure.
+type SSHAuthHint string
+
+const (
+	// SSHAuthHintKeyNotAuthorized is a public key not added as a deploy key
+	// of the repository, or to the account of a user with access to it.
+	SSHAuthHintKeyNotAuthorized SSHAuthHint = "KeyNotAuthorized"
+	// SSHAuthHintWrongUser is an SSH user other than the one expected by the
+	// provider, e.g. 'git' for GitHub, GitLab, Bitbucket and Gitea.
+	SSHAuthHintWrongUser SSHAuthHint = "WrongUser"
+)
+
+// sshAuthRemediations holds the remediation messages of the SSH
+// authentication hints.
+var sshAuthRemediations = map[SSHAuthHint]string{
+	SSHAuthHintKeyNotAuthorized: "check that the public key is added as a deploy key of the repository, or to an account with access to it",
+	SSHAuthHintWrongUser:        "check the SSH user of the URL, the provider expects 'git'",
+}
+
+// sshAuthPatterns holds the lower-cased messages of the SSH authentication
+// failures returned by go-git, libgit2 and OpenSSH.
+var sshAuthPatterns = []string{
+	"ssh: unable to authenticate",
+	"failed to authenticate ssh session",
+	"permission denied (publickey",
+}
+
+// sshMethodsRegexp matches the authentication methods attempted by go-git,
+// e.g. 'attempted methods [none publickey]'.
+var sshMethodsRegexp = regexp.MustCompile(`(?i)attempted methods \[([^\]]*)\]`)
+
+// sshUserRegexps match the SSH user in the SSH authentication failures, the
+// first capture group is the user.
+var sshUserRegexps = []*regexp.Regexp{
+	// ssh://git@github.com/org/repo
+	regexp.MustCompile(`ssh://([^@/\s:]+)@`),
+	// git@github.com: Permission denied (publickey).
+	regexp.MustCompile(`(?i)([\w.-]+)@[\w.-]+: permission denied`),
+	// git@github.com:org/repo
+	regexp.MustCompile(`(?:^|[\s'"])([\w.-]+)@[\w.-]+:[\w.~-]`),
+}
+
+// sshUserProviders holds the providers which expect the 'git' SSH user.
+var sshUserProviders = map[Provider]bool{
+	ProviderGitHub:    true,
+	ProviderGitLab:    true,
+	ProviderBitbucket: true,
+	ProviderGitea:     true,
+}
+
+// SSHAuthError is an SSH public key authentication failure, holding the
+// likely cause of the failure.
+type SSHAuthError struct {
+	// User is the SSH user, empty if unknown.
+	User string
+	// Methods holds the attempted authentication methods, if known.
+	Methods []string
+	// Hint is the likely cause of the failure.
+	Hint SSHAuthHint
+
+	err error
+}
+
+// Error returns the user and the remediation of the failure.
+func (e *SSHAuthError) Error() string {
+	msg := ErrSSHAuthFailed.Error()
+	if e.User != "" {
+		msg += " for user '" + e.User + "'"
+	}
+	return msg + ": " + sshAuthRemediations[e.Hint]
+}
+
+// Unwrap returns the original error.
+func (e *SSHAuthError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrSSHAuthFailed.
+func (e *SSHAuthError) Is(target error) bool {
+	return target == ErrSSHAuthFailed
+}
+
+// Class returns ClassAuthFailed.
+func (e *SSHAuthError) Class() ErrorClass {
+	return ClassAuthFailed
+}
+
+// sshAuthError returns an SSHAuthError wrapping err if the error message
+// holds an SSH authentication failure, nil otherwise.
+func sshAuthError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	var found bool
+	for _, pattern := range sshAuthPatterns {
+		if strings.Contains(lower, pattern) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	sshErr := &SSHAuthError{
+		User: firstSubmatch(sshUserRegexps, msg),
+		Hint: SSHAuthHintKeyNotAuthorized,
+		err:  err,
+	}
+	if m := sshMethodsRegexp.FindStringSubmatch(msg); m != nil {
+		sshErr.Methods = strings.Fields(m[1])
+	}
+	if sshErr.User != "" && sshErr.User != "git" && sshUserProviders[messageProvider(msg)] {
+		sshErr.Hint = SSHAuthHintWrongUser
+	}
+	return sshErr
+}
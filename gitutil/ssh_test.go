@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSSHAuthError(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		wantUser    string
+		wantMethods []string
+		wantHint    SSHAuthHint
+		wantMsg     string
+	}{
+		{
+			name:        "go-git publickey",
+			msg:         "ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain",
+			wantMethods: []string{"none", "publickey"},
+			wantHint:    SSHAuthHintKeyNotAuthorized,
+			wantMsg:     "SSH authentication failed: check that the public key is added as a deploy key of the repository, or to an account with access to it",
+		},
+		{
+			name:        "go-git wrong user",
+			msg:         "unable to clone 'ssh://flux@github.com/org/repo': ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain",
+			wantUser:    "flux",
+			wantMethods: []string{"none", "publickey"},
+			wantHint:    SSHAuthHintWrongUser,
+			wantMsg:     "SSH authentication failed for user 'flux': check the SSH user of the URL, the provider expects 'git'",
+		},
+		{
+			name:     "libgit2 publickey",
+			msg:      "Failed to authenticate SSH session: Unable to send userauth-publickey request",
+			wantHint: SSHAuthHintKeyNotAuthorized,
+			wantMsg:  "SSH authentication failed: check that the public key is added as a deploy key of the repository, or to an account with access to it",
+		},
+		{
+			name:     "OpenSSH publickey",
+			msg:      "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.",
+			wantUser: "git",
+			wantHint: SSHAuthHintKeyNotAuthorized,
+			wantMsg:  "SSH authentication failed for user 'git': check that the public key is added as a deploy key of the repository, or to an account with access to it",
+		},
+		{
+			name:     "self-hosted user",
+			msg:      "ssh://gitolite@git.example.com/repo: ssh: handshake failed: ssh: unable to authenticate, attempted methods [publickey]",
+			wantUser: "gitolite",
+			wantMethods: []string{
+				"publickey",
+			},
+			wantHint: SSHAuthHintKeyNotAuthorized,
+			wantMsg:  "SSH authentication failed for user 'gitolite': check that the public key is added as a deploy key of the repository, or to an account with access to it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var sshErr *SSHAuthError
+				if !errors.As(err, &sshErr) {
+					t.Fatalf("%s() = %T, want *SSHAuthError", name, err)
+				}
+				if sshErr.User != tt.wantUser {
+					t.Errorf("%s() user = %q, want %q", name, sshErr.User, tt.wantUser)
+				}
+				if !reflect.DeepEqual(sshErr.Methods, tt.wantMethods) {
+					t.Errorf("%s() methods = %q, want %q", name, sshErr.Methods, tt.wantMethods)
+				}
+				if sshErr.Hint != tt.wantHint {
+					t.Errorf("%s() hint = %q, want %q", name, sshErr.Hint, tt.wantHint)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrSSHAuthFailed) {
+					t.Errorf("%s() = %v, want ErrSSHAuthFailed", name, err)
+				}
+				if class := Classify(err); class != ClassAuthFailed {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassAuthFailed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrSubmodule is returned by GoGitError, LibGit2Error and GitCLIError,
+// wrapped in a SubmoduleError, when a submodule can't be fetched.
+var ErrSubmodule = errors.New("submodule failure")
+
+// submoduleReasons maps the lower-cased messages of the submodule specific
+// failures to their reason.
+var submoduleReasons = []struct {
+	pattern string
+	reason  string
+}{
+	{pattern: "cannot strip one component off url", reason: "the relative URL can't be resolved against the URL of the superproject"},
+	{pattern: "no url found for submodule", reason: "no URL found in .gitmodules"},
+	{pattern: "but it did not contain", reason: "the commit recorded in the superproject doesn't exist on the submodule remote"},
+	{pattern: "submodule not found", reason: "the submodule doesn't exist in .gitmodules"},
+}
+
+// submodulePathRegexps match the path of the submodule, the first capture
+// group is the path.
+var submodulePathRegexps = []*regexp.Regexp{
+	// git: fatal: No url found for submodule path 'vendor/lib' in .gitmodules
+	regexp.MustCompile(`(?i)submodule path '([^']+)'`),
+	// git: Failed to clone 'vendor/lib'. Retry scheduled
+	regexp.MustCompile(`(?i)failed to clone '([^']+)'`),
+	// libgit2: submodule 'vendor/lib' has not been added yet
+	regexp.MustCompile(`(?i)submodule '([^']+)'`),
+}
+
+// submoduleURLRegexps match the URL of the submodule remote, the first
+// capture group is the URL.
+var submoduleURLRegexps = []*regexp.Regexp{
+	// git: fatal: clone of 'git@github.com:org/lib.git' into submodule path '...' failed
+	regexp.MustCompile(`(?i)clone of '([^']+)' into submodule`),
+	// git: fatal: cannot strip one component off url 'file:///'
+	regexp.MustCompile(`(?i)off url '([^']+)'`),
+}
+
+// SubmoduleError is a failure to fetch a submodule, holding the path of the
+// submodule and the cause of the failure when they are known.
+type SubmoduleError struct {
+	// Path is the path of the submodule in the superproject, empty if unknown.
+	Path string
+	// URL is the URL of the submodule remote, empty if unknown.
+	URL string
+	// Cause is the class of the submodule fetch failure, e.g.
+	// ClassAuthFailed for a submodule remote the credentials can't access.
+	Cause ErrorClass
+	// Reason is the submodule specific reason of the failure, empty if
+	// the failure isn't specific to submodules.
+	Reason string
+
+	err error
+}
+
+// Error returns the path of the submodule and the cause of the failure.
+func (e *SubmoduleError) Error() string {
+	msg := "submodule"
+	if e.Path != "" {
+		msg += " '" + e.Path + "'"
+	}
+	msg += " failed"
+	if e.URL != "" && e.Reason == "" {
+		msg += " to clone '" + e.URL + "'"
+	}
+	switch {
+	case e.Reason != "":
+		msg += ": " + e.Reason
+	case e.Cause != ClassUnknown:
+		msg += ": " + e.Cause.String()
+	}
+	return msg
+}
+
+// Unwrap returns the original error.
+func (e *SubmoduleError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrSubmodule.
+func (e *SubmoduleError) Is(target error) bool {
+	return target == ErrSubmodule
+}
+
+// Class returns ClassSubmodule.
+func (e *SubmoduleError) Class() ErrorClass {
+	return ClassSubmodule
+}
+
+// submoduleError returns a SubmoduleError wrapping err if the error message
+// holds a submodule failure, nil otherwise.
+func submoduleError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	var reason string
+	for _, r := range submoduleReasons {
+		if strings.Contains(lower, r.pattern) {
+			reason = r.reason
+			break
+		}
+	}
+	if reason == "" && !strings.Contains(lower, "submodule") {
+		return nil
+	}
+
+	subErr := &SubmoduleError{
+		Path:   firstSubmatch(submodulePathRegexps, msg),
+		URL:    firstSubmatch(submoduleURLRegexps, msg),
+		Reason: reason,
+		err:    err,
+	}
+	if reason == "" {
+		// Classify the failure of the submodule remote, ignoring the
+		// submodule lines themselves.
+		var lines []string
+		for _, line := range strings.Split(msg, "\n") {
+			if !strings.Contains(strings.ToLower(line), "submodule") {
+				lines = append(lines, line)
+			}
+		}
+		subErr.Cause = classifyMessage(strings.Join(lines, "\n"))
+	}
+	return subErr
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubmoduleError(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantPath  string
+		wantURL   string
+		wantCause ErrorClass
+		wantMsg   string
+	}{
+		{
+			name: "submodule remote access",
+			msg: `Cloning into '/tmp/repo/vendor/lib'...
+git@github.com: Permission denied (publickey).
+fatal: Could not read from remote repository.
+fatal: clone of 'git@github.com:org/lib.git' into submodule path '/tmp/repo/vendor/lib' failed
+Failed to clone 'vendor/lib'. Retry scheduled`,
+			wantPath:  "/tmp/repo/vendor/lib",
+			wantURL:   "git@github.com:org/lib.git",
+			wantCause: ClassAuthFailed,
+			wantMsg:   "submodule '/tmp/repo/vendor/lib' failed to clone 'git@github.com:org/lib.git': AuthFailed",
+		},
+		{
+			name: "missing submodule remote",
+			msg: `remote: Repository not found.
+fatal: repository 'https://github.com/org/lib.git/' not found
+fatal: clone of 'https://github.com/org/lib.git' into submodule path 'vendor/lib' failed`,
+			wantPath:  "vendor/lib",
+			wantURL:   "https://github.com/org/lib.git",
+			wantCause: ClassRepositoryNotFound,
+			wantMsg:   "submodule 'vendor/lib' failed to clone 'https://github.com/org/lib.git': RepositoryNotFound",
+		},
+		{
+			name:    "relative URL",
+			msg:     "fatal: cannot strip one component off url 'file:///'",
+			wantURL: "file:///",
+			wantMsg: "submodule failed: the relative URL can't be resolved against the URL of the superproject",
+		},
+		{
+			name:     "missing URL",
+			msg:      "fatal: No url found for submodule path 'vendor/lib' in .gitmodules",
+			wantPath: "vendor/lib",
+			wantMsg:  "submodule 'vendor/lib' failed: no URL found in .gitmodules",
+		},
+		{
+			name:    "go-git missing submodule",
+			msg:     "submodule not found",
+			wantMsg: "submodule failed: the submodule doesn't exist in .gitmodules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{
+				"LibGit2Error": LibGit2Error,
+				"GoGitError":   GoGitError,
+				"GitCLIError":  func(err error) error { return GitCLIError(err, err.Error()) },
+			} {
+				err := tidy(orig)
+
+				var subErr *SubmoduleError
+				if !errors.As(err, &subErr) {
+					t.Fatalf("%s() = %T, want *SubmoduleError", name, err)
+				}
+				if subErr.Path != tt.wantPath {
+					t.Errorf("%s() path = %q, want %q", name, subErr.Path, tt.wantPath)
+				}
+				if subErr.URL != tt.wantURL {
+					t.Errorf("%s() URL = %q, want %q", name, subErr.URL, tt.wantURL)
+				}
+				if subErr.Cause != tt.wantCause {
+					t.Errorf("%s() cause = %s, want %s", name, subErr.Cause, tt.wantCause)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrSubmodule) {
+					t.Errorf("%s() = %v, want ErrSubmodule", name, err)
+				}
+				if class := Classify(err); class != ClassSubmodule {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, ClassSubmodule)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// tidyRule rewrites the remote messages matching the pattern into a compact
+// form, the rewrite function receives the submatches of the pattern.
+type tidyRule struct {
+	// provider restricts the rule to the messages of the given provider,
+	// ProviderUnknown applies the rule to all the messages.
+	provider Provider
+	pattern  *regexp.Regexp
+	rewrite  func(match []string) string
+}
+
+// tidyRules holds the provider specific rules applied by the tidy functions,
+// the first matching rule wins. When the message holds a remote URL, only the
+// rules of the detected provider are applied.
+var tidyRules = []tidyRule{
+	// Bitbucket Cloud prefixes the SSH errors with the 'conq' conduit.
+	{
+		provider: ProviderBitbucket,
+		pattern:  regexp.MustCompile(`(?i)conq: repository access denied\.?\s*(.*)`),
+		rewrite: func(m []string) string {
+			if reason := strings.TrimSpace(m[1]); reason != "" {
+				return "remote: repository access denied: " + strings.TrimSuffix(reason, ".")
+			}
+			return "remote: repository access denied"
+		},
+	},
+	{
+		provider: ProviderBitbucket,
+		pattern:  regexp.MustCompile(`(?i)conq: repository does not exist`),
+		rewrite: func(m []string) string {
+			return "remote: repository does not exist"
+		},
+	},
+	// Bitbucket Server branch permissions.
+	{
+		provider: ProviderBitbucket,
+		pattern:  regexp.MustCompile(`(?i)branch (\S+) can only be modified through pull requests`),
+		rewrite: func(m []string) string {
+			return "remote: branch restriction: '" + m[1] + "' can only be modified through pull requests"
+		},
+	},
+	// Bitbucket Cloud branch restrictions.
+	{
+		provider: ProviderBitbucket,
+		pattern:  regexp.MustCompile(`(?i)permission denied to update branch (\S+?)\.?(?:\s|$)`),
+		rewrite: func(m []string) string {
+			return "remote: branch restriction: branch '" + m[1] + "' cannot be updated"
+		},
+	},
+	// Bitbucket Cloud and Server archived repositories.
+	{
+		provider: ProviderBitbucket,
+		pattern:  regexp.MustCompile(`(?i)repository (?:is|has been) archived`),
+		rewrite: func(m []string) string {
+			return "remote: repository is archived and read-only"
+		},
+	},
+	// Gitea pre-receive hook messages.
+	{
+		provider: ProviderGitea,
+		pattern:  regexp.MustCompile(`(?i)gitea: not allowed to push to protected branch (\S+)`),
+		rewrite: func(m []string) string {
+			return "remote: branch restriction: pushing to protected branch '" + m[1] + "' is not allowed"
+		},
+	},
+	{
+		provider: ProviderGitea,
+		pattern:  regexp.MustCompile(`(?i)gitea: branch (\S+) is protected from force push`),
+		rewrite: func(m []string) string {
+			return "remote: branch restriction: branch '" + m[1] + "' is protected from force push"
+		},
+	},
+	{
+		provider: ProviderGitea,
+		pattern:  regexp.MustCompile(`(?i)gitea: user permission denied for writing`),
+		rewrite: func(m []string) string {
+			return "remote: permission denied: user is not allowed to write to the repository"
+		},
+	},
+	// GitLab messages, the self-hosted instances prefix them with 'GitLab:'
+	// and may use different banners than gitlab.com.
+	{
+		provider: ProviderGitLab,
+		pattern:  regexp.MustCompile(`(?i)gitlab: you are not allowed to (force )?push code to (?:a )?protected branch`),
+		rewrite: func(m []string) string {
+			return "remote: branch restriction: " + strings.ToLower(m[1]) + "push to protected branches is not allowed"
+		},
+	},
+	{
+		provider: ProviderGitLab,
+		pattern:  regexp.MustCompile(`(?i)gitlab: you are not allowed to push code to this project`),
+		rewrite: func(m []string) string {
+			return "remote: permission denied: not allowed to push code to this project"
+		},
+	},
+	{
+		provider: ProviderGitLab,
+		pattern:  regexp.MustCompile(`(?i)gitlab:\s*(.+)`),
+		rewrite: func(m []string) string {
+			return "remote: " + m[1]
+		},
+	},
+}
+
+var (
+	// registeredTidyRules holds the rules registered with RegisterTidyRule.
+	registeredTidyRules   []tidyRule
+	registeredTidyRulesMu sync.RWMutex
+)
+
+// RegisterTidyRule registers a rule rewriting the remote messages matching
+// the pattern, e.g. the messages of custom pre-receive hooks. The rewrite
+// function receives the submatches of the pattern, and its result is the
+// message of the error returned by the tidy functions. Rules registered for
+// a provider other than ProviderUnknown only apply to the messages holding a
+// remote URL of that provider, or no remote URL. The registered rules take
+// precedence over the built-in ones, in order of registration. It is safe
+// for concurrent use.
+func RegisterTidyRule(provider Provider, pattern *regexp.Regexp, rewrite func(match []string) string) {
+	registeredTidyRulesMu.Lock()
+	defer registeredTidyRulesMu.Unlock()
+	registeredTidyRules = append(registeredTidyRules, tidyRule{
+		provider: provider,
+		pattern:  pattern,
+		rewrite:  rewrite,
+	})
+}
+
+// isFence reports whether the line is a banner separator.
+func isFence(line string) bool {
+	return strings.Trim(line, "-=*~_#─━ \t") == ""
+}
+
+// applyTidyRules rewrites the message with the first matching registered
+// or built-in tidy rule, and reports whether a rule matched.
+func applyTidyRules(msg string) (string, bool) {
+	registeredTidyRulesMu.RLock()
+	rules := append(append([]tidyRule{}, registeredTidyRules...), tidyRules...)
+	registeredTidyRulesMu.RUnlock()
+
+	provider := messageProvider(msg)
+	for _, rule := range rules {
+		if provider != ProviderUnknown && rule.provider != ProviderUnknown && rule.provider != provider {
+			continue
+		}
+		if m := rule.pattern.FindStringSubmatch(msg); m != nil {
+			return rule.rewrite(m), true
+		}
+	}
+	return msg, false
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTidyBitbucket(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   string
+		want  string
+		class ErrorClass
+	}{
+		{
+			name:  "Bitbucket Cloud read-only deployment key",
+			msg:   "conq: repository access denied. access via a deployment key is read-only.",
+			want:  "remote: repository access denied: access via a deployment key is read-only",
+			class: ClassPermissionDenied,
+		},
+		{
+			name:  "Bitbucket Cloud missing repository",
+			msg:   "conq: repository does not exist.",
+			want:  "remote: repository does not exist",
+			class: ClassRepositoryNotFound,
+		},
+		{
+			name: "Bitbucket Server branch permissions",
+			msg: `remote: 
+remote: ----------------------------------------------------------------
+remote: Branch refs/heads/main can only be modified through pull requests.
+remote: Check your branch permissions configuration with the project administrator.
+remote: ----------------------------------------------------------------
+remote: 
+`,
+			want:  "remote: branch restriction: 'refs/heads/main' can only be modified through pull requests",
+			class: ClassRemoteRejected,
+		},
+		{
+			name: "Bitbucket Cloud branch restriction",
+			msg: `remote: 
+remote: Permission denied to update branch main.
+remote: 
+`,
+			want:  "remote: branch restriction: branch 'main' cannot be updated",
+			class: ClassRemoteRejected,
+		},
+		{
+			name: "Bitbucket archived repository",
+			msg: `remote: 
+remote: This repository has been archived and is read-only.
+remote: 
+`,
+			want:  "remote: repository is archived and read-only",
+			class: ClassPermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(errors.New(tt.msg))
+				if err.Error() != tt.want {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.want)
+				}
+				if class := Classify(err); class != tt.class {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, tt.class)
+				}
+			}
+		})
+	}
+}
+
+// TestTidyCorpus checks the tidied output of the remote messages in
+// testdata/tidy, each <name>.txt message has its expected <name>.golden output.
+func TestTidyCorpus(t *testing.T) {
+	messages, err := filepath.Glob(filepath.Join("testdata", "tidy", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) == 0 {
+		t.Fatal("no messages found in corpus")
+	}
+
+	for _, message := range messages {
+		name := strings.TrimSuffix(filepath.Base(message), ".txt")
+		t.Run(name, func(t *testing.T) {
+			msg, err := os.ReadFile(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden, err := os.ReadFile(strings.TrimSuffix(message, ".txt") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := strings.TrimSpace(string(golden))
+			if got := LibGit2Error(errors.New(string(msg))).Error(); got != want {
+				t.Errorf("LibGit2Error() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRegisterTidyRule(t *testing.T) {
+	t.Cleanup(func() {
+		registeredTidyRulesMu.Lock()
+		registeredTidyRules = nil
+		registeredTidyRulesMu.Unlock()
+	})
+
+	RegisterTidyRule(ProviderUnknown, regexp.MustCompile(`(?i)policy-bot: commit (\w+) is not signed`), func(m []string) string {
+		return "remote: push rejected: commit " + m[1] + " must be signed"
+	})
+	RegisterTidyRule(ProviderGitHub, regexp.MustCompile(`(?i)GitLab: (.+)`), func(m []string) string {
+		return "remote: GitHub mirror: " + m[1]
+	})
+
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "global rule",
+			msg: `remote: 
+remote: policy-bot: commit 1a2b3c is not signed
+remote: `,
+			want: "remote: push rejected: commit 1a2b3c must be signed",
+		},
+		{
+			name: "provider rule takes precedence over built-in rule",
+			msg:  "failed to push to https://github.com/org/repo.git: GitLab: mirrored message",
+			want: "remote: GitHub mirror: mirrored message",
+		},
+		{
+			name: "provider rule skipped for other providers",
+			msg:  "failed to push to https://gitlab.com/group/project.git: GitLab: project not found",
+			want: "remote: project not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := LibGit2Error(errors.New(tt.msg)); err.Error() != tt.want {
+				t.Errorf("LibGit2Error() = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrTLS is returned by GoGitError, LibGit2Error and GitCLIError, wrapped
+// in a TLSError, when the TLS certificate of the remote can't be verified.
+var ErrTLS = errors.New("TLS verification failed")
+
+// tlsRules maps the lower-cased messages of the TLS failures returned by Go,
+// libgit2 and curl to their reason, in order of precedence.
+var tlsRules = []struct {
+	pattern string
+	reason  string
+	expired bool
+}{
+	{pattern: "certificate has expired", reason: "certificate has expired or is not yet valid", expired: true},
+	{pattern: "tls: expired certificate", reason: "client certificate has expired", expired: true},
+	{pattern: "certificate signed by unknown authority", reason: "certificate signed by unknown authority"},
+	{pattern: "unable to get local issuer certificate", reason: "certificate signed by unknown authority"},
+	{pattern: "self signed certificate", reason: "self-signed certificate"},
+	{pattern: "self-signed certificate", reason: "self-signed certificate"},
+	{pattern: "certificate is valid for", reason: "certificate doesn't match the host name"},
+	{pattern: "certificate is not valid for any names", reason: "certificate doesn't match the host name"},
+	{pattern: "no alternative certificate subject name matches", reason: "certificate doesn't match the host name"},
+	{pattern: "server certificate verification failed", reason: "certificate verification failed"},
+	{pattern: "certificate verify failed", reason: "certificate verification failed"},
+	{pattern: "ssl certificate is invalid", reason: "certificate verification failed"},
+	{pattern: "tls: bad certificate", reason: "client certificate rejected by the remote"},
+	{pattern: "tls: handshake failure", reason: "handshake failure"},
+}
+
+// tlsSubjectRegexps match the subject of the offending certificate, the
+// first capture group is the subject.
+var tlsSubjectRegexps = []*regexp.Regexp{
+	// x509: certificate signed by unknown authority (possibly because of "..." while trying to verify candidate authority certificate "Example CA")
+	regexp.MustCompile(`(?i)candidate authority certificate "([^"]+)"`),
+	// x509: certificate is valid for *.example.com, example.com, not git.example.org
+	regexp.MustCompile(`(?i)certificate is valid for (.+?), not \S+`),
+	// curl: subject: CN=git.example.com
+	regexp.MustCompile(`(?i)subject:\s*([^\n;]+)`),
+}
+
+// tlsHostRegexps match the host of the remote in the TLS failures, the first
+// capture group is the host.
+var tlsHostRegexps = []*regexp.Regexp{
+	// x509: certificate is valid for *.example.com, not git.example.org
+	regexp.MustCompile(`(?i)certificate is valid for .+?, not (\S+)`),
+	// x509: certificate is not valid for any names, but wanted to match git.example.org
+	regexp.MustCompile(`(?i)wanted to match (\S+)`),
+}
+
+// TLSError is a failure to verify the TLS certificate of the remote, holding
+// the host and the subject of the certificate when they are known.
+type TLSError struct {
+	// Host is the host of the remote, empty if unknown.
+	Host string
+	// Subject is the subject of the offending certificate, empty if unknown.
+	Subject string
+	// Reason is the reason of the failure.
+	Reason string
+
+	expired bool
+	err     error
+}
+
+// Error returns the host, the reason of the failure and the subject of the
+// certificate.
+func (e *TLSError) Error() string {
+	msg := ErrTLS.Error()
+	if e.Host != "" {
+		msg += " for host '" + e.Host + "'"
+	}
+	msg += ": " + e.Reason
+	if e.Subject != "" {
+		msg += " (certificate '" + e.Subject + "')"
+	}
+	return msg
+}
+
+// Unwrap returns the original error.
+func (e *TLSError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrTLS, or ErrCredentialsExpired for the
+// expired certificates.
+func (e *TLSError) Is(target error) bool {
+	return target == ErrTLS || (e.expired && target == ErrCredentialsExpired)
+}
+
+// Class returns ClassCredentialsExpired for the expired certificates,
+// ClassTLS otherwise.
+func (e *TLSError) Class() ErrorClass {
+	if e.expired {
+		return ClassCredentialsExpired
+	}
+	return ClassTLS
+}
+
+// tlsPatterns returns the patterns of the TLS failures caused, or not, by
+// expired certificates.
+func tlsPatterns(expired bool) []string {
+	var patterns []string
+	for _, rule := range tlsRules {
+		if rule.expired == expired {
+			patterns = append(patterns, rule.pattern)
+		}
+	}
+	return patterns
+}
+
+// tlsError returns a TLSError wrapping err if the error message holds a TLS
+// failure, nil otherwise.
+func tlsError(msg string, err error) error {
+	lower := strings.ToLower(msg)
+	for _, rule := range tlsRules {
+		if !strings.Contains(lower, rule.pattern) {
+			continue
+		}
+
+		host := firstSubmatch(tlsHostRegexps, msg)
+		if host == "" {
+			host, _ = splitRemoteURL(strings.TrimRight(remoteURLRegexp.FindString(msg), ".,:;)"))
+		}
+		return &TLSError{
+			Host:    host,
+			Subject: strings.TrimSpace(firstSubmatch(tlsSubjectRegexps, msg)),
+			Reason:  rule.reason,
+			expired: rule.expired,
+			err:     err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTLSError(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		wantHost    string
+		wantSubject string
+		wantMsg     string
+		wantClass   ErrorClass
+	}{
+		{
+			name:        "unknown authority",
+			msg:         `Get "https://git.example.com/org/repo/info/refs?service=git-upload-pack": x509: certificate signed by unknown authority (possibly because of "crypto/rsa: verification error" while trying to verify candidate authority certificate "Example CA")`,
+			wantHost:    "git.example.com",
+			wantSubject: "Example CA",
+			wantMsg:     "TLS verification failed for host 'git.example.com': certificate signed by unknown authority (certificate 'Example CA')",
+			wantClass:   ClassTLS,
+		},
+		{
+			name:        "host name mismatch",
+			msg:         `Get "https://10.0.0.1/org/repo/info/refs": x509: certificate is valid for *.example.com, example.com, not git.example.org`,
+			wantHost:    "git.example.org",
+			wantSubject: "*.example.com, example.com",
+			wantMsg:     "TLS verification failed for host 'git.example.org': certificate doesn't match the host name (certificate '*.example.com, example.com')",
+			wantClass:   ClassTLS,
+		},
+		{
+			name:      "expired certificate",
+			msg:       `Get "https://git.example.com/org/repo/info/refs": x509: certificate has expired or is not yet valid: current time 2023-01-02T00:00:00Z is after 2023-01-01T00:00:00Z`,
+			wantHost:  "git.example.com",
+			wantMsg:   "TLS verification failed for host 'git.example.com': certificate has expired or is not yet valid",
+			wantClass: ClassCredentialsExpired,
+		},
+		{
+			name:      "curl local issuer",
+			msg:       "fatal: unable to access 'https://git.example.com/org/repo.git/': SSL certificate problem: unable to get local issuer certificate",
+			wantHost:  "git.example.com",
+			wantMsg:   "TLS verification failed for host 'git.example.com': certificate signed by unknown authority",
+			wantClass: ClassTLS,
+		},
+		{
+			name:      "libgit2 invalid certificate",
+			msg:       "the SSL certificate is invalid",
+			wantMsg:   "TLS verification failed: certificate verification failed",
+			wantClass: ClassTLS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := errors.New(tt.msg)
+			for name, tidy := range map[string]func(error) error{"LibGit2Error": LibGit2Error, "GoGitError": GoGitError} {
+				err := tidy(orig)
+
+				var tlsErr *TLSError
+				if !errors.As(err, &tlsErr) {
+					t.Fatalf("%s() = %T, want *TLSError", name, err)
+				}
+				if tlsErr.Host != tt.wantHost {
+					t.Errorf("%s() host = %q, want %q", name, tlsErr.Host, tt.wantHost)
+				}
+				if tlsErr.Subject != tt.wantSubject {
+					t.Errorf("%s() subject = %q, want %q", name, tlsErr.Subject, tt.wantSubject)
+				}
+				if err.Error() != tt.wantMsg {
+					t.Errorf("%s() = %q, want %q", name, err.Error(), tt.wantMsg)
+				}
+				if !errors.Is(err, ErrTLS) {
+					t.Errorf("%s() = %v, want ErrTLS", name, err)
+				}
+				if expired := errors.Is(err, ErrCredentialsExpired); expired != (tt.wantClass == ClassCredentialsExpired) {
+					t.Errorf("errors.Is(%s(), ErrCredentialsExpired) = %v", name, expired)
+				}
+				if class := Classify(err); class != tt.wantClass {
+					t.Errorf("Classify(%s()) = %s, want %s", name, class, tt.wantClass)
+				}
+			}
+		})
+	}
+}
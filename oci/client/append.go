@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// Append creates a layer from the given directory, appends it to the existing
+// artifact at the given URL and uploads the resulting artifact to the same URL.
+// The layers of the existing artifact are not uploaded again.
+// The artifact annotations are updated with the given metadata.
+// It returns the digest of the new artifact.
+func (c *Client) Append(ctx context.Context, url, sourceDir string, meta Metadata, ignorePaths []string, opts ...BuildOption) (string, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	base, err := crane.Pull(url, c.optionsWithContext(ctx)...)
+	if err != nil {
+		return "", fmt.Errorf("pulling artifact failed: %w", wrapRegistryError(err))
+	}
+
+	tmpDir, err := c.mkdirTemp("append")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpFile := filepath.Join(tmpDir, "artifact.tgz")
+
+	if err := c.build(ctx, tmpFile, sourceDir, ignorePaths, opts...); err != nil {
+		return "", err
+	}
+
+	img, err := crane.Append(base, tmpFile)
+	if err != nil {
+		return "", fmt.Errorf("appeding content to artifact failed: %w", err)
+	}
+
+	meta.Created = time.Now().Format(time.RFC3339)
+	img = mutate.Annotations(img, c.artifactAnnotations(meta)).(gcrv1.Image)
+
+	if err := crane.Push(img, url, c.optionsWithContext(ctx)...); err != nil {
+		return "", fmt.Errorf("pushing artifact failed: %w", wrapRegistryError(err))
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("parsing artifact digest failed: %w", err)
+	}
+
+	return ref.Context().Digest(digest.String()).String(), nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	. "github.com/onsi/gomega"
+)
+
+func Test_Append(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-append"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev1",
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	baseManifest, err := image.Manifest()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tmpDir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(tmpDir, "extra.yaml"), []byte("extra"), 0o600)).To(Succeed())
+
+	metadata.Revision = "rev2"
+	digest, err := c.Append(ctx, url, tmpDir, metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err = crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	manifest, err := image.Manifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manifest.Layers).To(HaveLen(2))
+	g.Expect(manifest.Layers[0].Digest).To(Equal(baseManifest.Layers[0].Digest))
+
+	outDir := t.TempDir()
+	meta, err := c.Pull(ctx, url, outDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(meta.Digest).To(Equal(digest))
+	g.Expect(meta.Revision).To(Equal("rev2"))
+	g.Expect(filepath.Join(outDir, "testdata/artifact/deployment.yaml")).To(BeAnExistingFile())
+	g.Expect(filepath.Join(outDir, "extra.yaml")).To(BeAnExistingFile())
+}
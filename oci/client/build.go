@@ -18,7 +18,7 @@ package client
 
 import (
 	"archive/tar"
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -30,9 +30,109 @@ import (
 	"github.com/fluxcd/pkg/sourceignore"
 )
 
+// DefaultIgnorePatterns holds the VCS metadata and editor files patterns
+// excluded by default from the artifacts.
+var DefaultIgnorePatterns = []string{
+	".git/",
+	".hg/",
+	".svn/",
+	".DS_Store",
+	"*.swp",
+	"*.swo",
+	"*~",
+	".#*",
+}
+
+// BuildOption is a functional option for configuring Build.
+type BuildOption func(o *buildOptions)
+
+// buildOptions holds the options for Build.
+type buildOptions struct {
+	// disableDefaultIgnore disables the exclusion of the DefaultIgnorePatterns.
+	disableDefaultIgnore bool
+
+	// compressor is used to compress the tarball, defaults to GzipCompressor.
+	compressor Compressor
+
+	// includePaths holds the patterns of the files included in the archive.
+	includePaths []string
+
+	// report is filled with the build statistics when not nil.
+	report *BuildReport
+}
+
+// BuildReport holds the statistics of an artifact build.
+type BuildReport struct {
+	// Files is the number of regular files added to the archive.
+	Files int
+
+	// Excluded is the number of files and directories excluded
+	// by the ignore patterns.
+	Excluded int
+
+	// UncompressedSize is the size in bytes of the tarball before compression.
+	UncompressedSize int64
+
+	// CompressedSize is the size in bytes of the compressed artifact.
+	CompressedSize int64
+
+	// Duration is the time spent building the artifact.
+	Duration time.Duration
+}
+
+// WithoutDefaultIgnore configures Build to include the files matching
+// the DefaultIgnorePatterns in the artifact.
+func WithoutDefaultIgnore() BuildOption {
+	return func(o *buildOptions) {
+		o.disableDefaultIgnore = true
+	}
+}
+
+// WithCompressor configures Build to compress the tarball with the given
+// Compressor, e.g. ParallelGzipCompressor for large source directories.
+func WithCompressor(compressor Compressor) BuildOption {
+	return func(o *buildOptions) {
+		o.compressor = compressor
+	}
+}
+
+// WithIncludePaths configures Build to archive only the files matching at least
+// one of the given patterns, e.g. '**/*.yaml' or 'kustomization.yaml'.
+// The patterns use the .gitignore format and are relative to the source dir.
+// The ignore paths take precedence over the include patterns, and the directories
+// without included files are omitted from the archive.
+func WithIncludePaths(patterns ...string) BuildOption {
+	return func(o *buildOptions) {
+		o.includePaths = append(o.includePaths, patterns...)
+	}
+}
+
+// WithBuildReport configures Build to fill the given report with the
+// build statistics, e.g. to enforce artifact size budgets.
+func WithBuildReport(report *BuildReport) BuildOption {
+	return func(o *buildOptions) {
+		o.report = report
+	}
+}
+
 // Build archives the given directory as a tarball to the given local path.
 // While archiving, any environment specific data (for example, the user and group name) is stripped from file headers.
-func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string) (err error) {
+// Unless disabled, the files matching the DefaultIgnorePatterns are excluded from the archive,
+// the given ignore paths take precedence over the default patterns.
+func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string, opts ...BuildOption) error {
+	return c.build(context.Background(), artifactPath, sourceDir, ignorePaths, opts...)
+}
+
+// build archives the given directory, it stops and removes the partially
+// written archive when the context is cancelled.
+func (c *Client) build(ctx context.Context, artifactPath, sourceDir string, ignorePaths []string, opts ...BuildOption) (err error) {
+	o := &buildOptions{
+		compressor: GzipCompressor,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
 		return fmt.Errorf("invalid source dir path: %s", sourceDir)
 	}
@@ -48,7 +148,13 @@ func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string) (er
 		}
 	}()
 
-	ignore := strings.Join(ignorePaths, "\n")
+	var patterns []string
+	if !o.disableDefaultIgnore {
+		patterns = append(patterns, DefaultIgnorePatterns...)
+	}
+	patterns = append(patterns, ignorePaths...)
+
+	ignore := strings.Join(patterns, "\n")
 	var domain []string
 	if sourceDir != "." {
 		domain = strings.Split(filepath.Clean(sourceDir), string(filepath.Separator))
@@ -59,25 +165,57 @@ func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string) (er
 		return matcher.Match(strings.Split(p, string(filepath.Separator)), fi.IsDir())
 	}
 
+	var include func(p string) bool
+	if len(o.includePaths) > 0 {
+		includeMatcher := sourceignore.NewMatcher(sourceignore.ReadPatterns(strings.NewReader(strings.Join(o.includePaths, "\n")), domain))
+		include = func(p string) bool {
+			return includeMatcher.Match(strings.Split(p, string(filepath.Separator)), false)
+		}
+	}
+
+	start := time.Now()
+	report := BuildReport{}
+
 	sz := &writeCounter{}
 	mw := io.MultiWriter(tf, sz)
 
-	gw := gzip.NewWriter(mw)
-	tw := tar.NewWriter(gw)
+	gw, err := o.compressor(mw)
+	if err != nil {
+		tf.Close()
+		return err
+	}
+	usz := &writeCounter{}
+	tw := tar.NewWriter(io.MultiWriter(gw, usz))
 	if err := filepath.Walk(sourceDir, func(p string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Ignore anything that is not a file or directories e.g. symlinks
 		if m := fi.Mode(); !(m.IsRegular() || m.IsDir()) {
 			return nil
 		}
 
-		if len(ignorePaths) > 0 && filter(p, fi) {
+		if len(patterns) > 0 && filter(p, fi) {
+			report.Excluded++
 			return nil
 		}
 
+		if include != nil {
+			// Directories are created on extraction from the included files paths.
+			if fi.IsDir() {
+				return nil
+			}
+			if !include(p) {
+				report.Excluded++
+				return nil
+			}
+		}
+
 		header, err := tar.FileInfoHeader(fi, p)
 		if err != nil {
 			return err
@@ -110,6 +248,7 @@ func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string) (er
 		if !fi.Mode().IsRegular() {
 			return nil
 		}
+		report.Files++
 		f, err := os.Open(p)
 		if err != nil {
 			f.Close()
@@ -144,7 +283,18 @@ func (c *Client) Build(artifactPath, sourceDir string, ignorePaths []string) (er
 		return err
 	}
 
-	return fs.RenameWithFallback(tmpName, artifactPath)
+	if err := fs.RenameWithFallback(tmpName, artifactPath); err != nil {
+		return err
+	}
+
+	if o.report != nil {
+		report.UncompressedSize = usz.written
+		report.CompressedSize = sz.written
+		report.Duration = time.Since(start)
+		*o.report = report
+	}
+
+	return nil
 }
 
 type writeCounter struct {
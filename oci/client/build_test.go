@@ -146,3 +146,166 @@ func checkPathExists(t *testing.T, dir, testDir string, paths []string) {
 		g.Expect(os.IsNotExist(err)).To(BeTrue())
 	}
 }
+
+func TestBuild_DefaultIgnore(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, p := range []string{
+		"deploy/app.yaml",
+		"deploy/.app.yaml.swp",
+		"deploy/app.yaml~",
+		".git/config",
+		".hg/hgrc",
+		".DS_Store",
+	} {
+		g := NewWithT(t)
+		g.Expect(os.MkdirAll(filepath.Join(sourceDir, filepath.Dir(p)), 0o750)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(sourceDir, p), []byte(p), 0o600)).To(Succeed())
+	}
+
+	tests := []struct {
+		name       string
+		ignorePath []string
+		opts       []BuildOption
+		checkPaths []string
+	}{
+		{
+			name:       "default exclusions",
+			checkPaths: []string{"!deploy/app.yaml", "deploy/.app.yaml.swp", "deploy/app.yaml~", ".git/", ".hg/", ".DS_Store"},
+		},
+		{
+			name:       "ignore paths override default exclusions",
+			ignorePath: []string{"!.DS_Store"},
+			checkPaths: []string{"!deploy/app.yaml", "!.DS_Store", ".git/"},
+		},
+		{
+			name:       "default exclusions disabled",
+			opts:       []BuildOption{WithoutDefaultIgnore()},
+			checkPaths: []string{"!deploy/app.yaml", "!deploy/.app.yaml.swp", "!deploy/app.yaml~", "!.git/config", "!.hg/hgrc", "!.DS_Store"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := NewLocalClient()
+			artifactPath := filepath.Join(t.TempDir(), "files.tar.gz")
+
+			g.Expect(c.Build(artifactPath, sourceDir, tt.ignorePath, tt.opts...)).To(Succeed())
+
+			b, err := os.ReadFile(artifactPath)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			untarDir := t.TempDir()
+			g.Expect(tar.Untar(bytes.NewReader(b), untarDir, tar.WithMaxUntarSize(-1))).To(Succeed())
+
+			checkPathExists(t, untarDir, "", tt.checkPaths)
+		})
+	}
+}
+
+func TestBuild_ParallelGzipCompressor(t *testing.T) {
+	g := NewWithT(t)
+	c := NewLocalClient()
+
+	sourceDir := t.TempDir()
+	data := bytes.Repeat([]byte("apiVersion: v1\nkind: ConfigMap\n"), 1<<15)
+	g.Expect(os.WriteFile(filepath.Join(sourceDir, "large.yaml"), data, 0o600)).To(Succeed())
+
+	artifactPath := filepath.Join(t.TempDir(), "files.tar.gz")
+	err := c.Build(artifactPath, sourceDir, nil, WithCompressor(ParallelGzipCompressor(64<<10, 4)))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	b, err := os.ReadFile(artifactPath)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	untarDir := t.TempDir()
+	g.Expect(tar.Untar(bytes.NewReader(b), untarDir, tar.WithMaxUntarSize(-1))).To(Succeed())
+
+	got, err := os.ReadFile(filepath.Join(untarDir, "large.yaml"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(data))
+}
+
+func TestBuild_Report(t *testing.T) {
+	g := NewWithT(t)
+	c := NewLocalClient()
+
+	sourceDir := t.TempDir()
+	for _, p := range []string{"a.yaml", "deploy/b.yaml", "ignore.txt"} {
+		g.Expect(os.MkdirAll(filepath.Join(sourceDir, filepath.Dir(p)), 0o750)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(sourceDir, p), []byte(p), 0o600)).To(Succeed())
+	}
+
+	report := BuildReport{}
+	artifactPath := filepath.Join(t.TempDir(), "files.tar.gz")
+	err := c.Build(artifactPath, sourceDir, []string{"ignore.txt"}, WithBuildReport(&report))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	fi, err := os.Stat(artifactPath)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(report.Files).To(Equal(2))
+	g.Expect(report.Excluded).To(Equal(1))
+	g.Expect(report.CompressedSize).To(Equal(fi.Size()))
+	g.Expect(report.UncompressedSize % 512).To(BeZero())
+	g.Expect(report.UncompressedSize).To(BeNumerically(">", report.CompressedSize))
+	g.Expect(report.Duration).To(BeNumerically(">", 0))
+}
+
+func TestBuild_IncludePaths(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, p := range []string{
+		"kustomization.yaml",
+		"README.md",
+		"deploy/app.yaml",
+		"deploy/app.json",
+		"deploy/secret.yaml",
+		"src/main.go",
+	} {
+		g := NewWithT(t)
+		g.Expect(os.MkdirAll(filepath.Join(sourceDir, filepath.Dir(p)), 0o750)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(sourceDir, p), []byte(p), 0o600)).To(Succeed())
+	}
+
+	tests := []struct {
+		name         string
+		ignorePath   []string
+		includePaths []string
+		checkPaths   []string
+	}{
+		{
+			name:         "include yaml files",
+			includePaths: []string{"**/*.yaml"},
+			checkPaths:   []string{"!kustomization.yaml", "!deploy/app.yaml", "!deploy/secret.yaml", "README.md", "deploy/app.json", "src/"},
+		},
+		{
+			name:         "include directory and file",
+			includePaths: []string{"kustomization.yaml", "deploy/"},
+			checkPaths:   []string{"!kustomization.yaml", "!deploy/app.yaml", "!deploy/app.json", "README.md", "src/"},
+		},
+		{
+			name:         "ignore paths take precedence",
+			ignorePath:   []string{"secret.yaml"},
+			includePaths: []string{"*.yaml"},
+			checkPaths:   []string{"!kustomization.yaml", "!deploy/app.yaml", "deploy/secret.yaml", "deploy/app.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := NewLocalClient()
+			artifactPath := filepath.Join(t.TempDir(), "files.tar.gz")
+
+			g.Expect(c.Build(artifactPath, sourceDir, tt.ignorePath, WithIncludePaths(tt.includePaths...))).To(Succeed())
+
+			b, err := os.ReadFile(artifactPath)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			untarDir := t.TempDir()
+			g.Expect(tar.Untar(bytes.NewReader(b), untarDir, tar.WithMaxUntarSize(-1))).To(Succeed())
+
+			checkPathExists(t, untarDir, "", tt.checkPaths)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// WithPullChecksum configures Pull to verify the first artifact layer blob against
+// the given checksum before extracting it. The checksum must be in the
+// '<algorithm>:<hex>' format (e.g. 'sha256:a3b8...') or in the Subresource
+// Integrity '<algorithm>-<base64>' format (e.g. 'sha256-o7g...').
+// Supported algorithms are sha256, sha384 and sha512.
+func WithPullChecksum(checksum string) PullOption {
+	return func(o *pullOptions) {
+		o.checksum = checksum
+	}
+}
+
+// parseChecksum returns the hash function and the expected sum of the given checksum.
+func parseChecksum(checksum string) (hash.Hash, []byte, error) {
+	var algorithm, encoded string
+	var decode func(string) ([]byte, error)
+	if i := strings.Index(checksum, ":"); i > 0 {
+		algorithm, encoded, decode = checksum[:i], checksum[i+1:], hex.DecodeString
+	} else if i := strings.Index(checksum, "-"); i > 0 {
+		algorithm, encoded, decode = checksum[:i], checksum[i+1:], base64.StdEncoding.DecodeString
+	} else {
+		return nil, nil, fmt.Errorf("invalid checksum '%s': unknown format", checksum)
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, nil, fmt.Errorf("invalid checksum '%s': unsupported algorithm '%s'", checksum, algorithm)
+	}
+
+	sum, err := decode(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid checksum '%s': %w", checksum, err)
+	}
+	if len(sum) != h.Size() {
+		return nil, nil, fmt.Errorf("invalid checksum '%s': wrong length", checksum)
+	}
+
+	return h, sum, nil
+}
+
+// formatChecksum returns the given sum in the format and with the algorithm of the given
+// checksum, so that the actual and expected checksums of a mismatch are comparable.
+func formatChecksum(checksum string, sum []byte) string {
+	if i := strings.Index(checksum, ":"); i > 0 {
+		return checksum[:i] + ":" + hex.EncodeToString(sum)
+	}
+	if i := strings.Index(checksum, "-"); i > 0 {
+		return checksum[:i] + "-" + base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
+
+// writeVerified writes the blob to the given path and verifies its content
+// against the checksum. On success, it returns the file opened for reading.
+func writeVerified(blob io.Reader, path, checksum string) (*os.File, error) {
+	h, sum, err := parseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(io.MultiWriter(f, h), blob); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("downloading layer failed: %w", err)
+	}
+
+	if actual := h.Sum(nil); !bytes.Equal(actual, sum) {
+		f.Close()
+		return nil, fmt.Errorf("checksum mismatch: expected '%s', got '%s'", checksum, formatChecksum(checksum, actual))
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	. "github.com/onsi/gomega"
+)
+
+func Test_Pull_Checksum(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-checksum"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	layers, err := image.Layers()
+	g.Expect(err).ToNot(HaveOccurred())
+	blob, err := layers[0].Compressed()
+	g.Expect(err).ToNot(HaveOccurred())
+	h := sha256.New()
+	_, err = io.Copy(h, blob)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(blob.Close()).To(Succeed())
+	sum := h.Sum(nil)
+
+	tests := []struct {
+		name      string
+		checksum  string
+		expectErr string
+	}{
+		{
+			name:     "hex checksum",
+			checksum: "sha256:" + hex.EncodeToString(sum),
+		},
+		{
+			name:     "SRI checksum",
+			checksum: "sha256-" + base64.StdEncoding.EncodeToString(sum),
+		},
+		{
+			name:      "mismatch",
+			checksum:  "sha256:" + hex.EncodeToString(make([]byte, sha256.Size)),
+			expectErr: "got 'sha256:" + hex.EncodeToString(sum) + "'",
+		},
+		{
+			name:      "SRI mismatch",
+			checksum:  "sha256-" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)),
+			expectErr: "got 'sha256-" + base64.StdEncoding.EncodeToString(sum) + "'",
+		},
+		{
+			name:      "unsupported algorithm",
+			checksum:  "md5:d41d8cd98f00b204e9800998ecf8427e",
+			expectErr: "unsupported algorithm",
+		},
+		{
+			name:      "unknown format",
+			checksum:  hex.EncodeToString(sum),
+			expectErr: "unknown format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			tmpDir := t.TempDir()
+			_, err := c.Pull(ctx, url, tmpDir, WithPullChecksum(tt.checksum))
+			if tt.expectErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.expectErr))
+
+				entries, err := os.ReadDir(tmpDir)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(entries).To(BeEmpty())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(filepath.Join(tmpDir, "testdata/artifact/deployment.yaml")).To(BeAnExistingFile())
+		})
+	}
+}
@@ -18,30 +18,103 @@ package client
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 
 	"github.com/fluxcd/pkg/oci"
 )
 
+// tempDirPrefix is the name prefix of the temporary directories created by the Client.
+const tempDirPrefix = "flux-oci-"
+
 // Client holds the options for accessing remote OCI registries.
 type Client struct {
 	options []crane.Option
+
+	// tempDir is the directory under which temporary build files are created,
+	// defaults to the system temp dir.
+	tempDir string
+
+	// transport is the base HTTP transport used for the registry calls,
+	// defaults to remote.DefaultTransport.
+	transport http.RoundTripper
+
+	// bandwidth holds the rate limiters of the registry calls, nil means unlimited.
+	bandwidth *bandwidthLimiter
+
+	// mirrors holds the mirror hosts of the registry hosts.
+	mirrors map[string][]string
+
+	// annotations holds the default annotations set on every pushed artifact.
+	annotations map[string]string
+}
+
+// ClientOption is a functional option for configuring the Client.
+type ClientOption func(c *Client)
+
+// WithTempDir configures the Client to create its temporary files under the given
+// directory instead of the system temp dir.
+func WithTempDir(dir string) ClientOption {
+	return func(c *Client) {
+		c.tempDir = dir
+	}
+}
+
+// WithCraneOptions configures the Client with additional crane options,
+// e.g. crane.WithUserAgent, crane.WithPlatform or crane.WithNondistributable.
+// The options are applied after the Client defaults, hence they take precedence
+// over the default user agent and platform.
+// To configure the HTTP transport, use WithTransport instead of crane.WithTransport.
+func WithCraneOptions(opts ...crane.Option) ClientOption {
+	return func(c *Client) {
+		c.options = append(c.options, opts...)
+	}
+}
+
+// WithTransport configures the Client to use the given HTTP transport for
+// the registry calls, e.g. to configure TLS or proxy settings.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithDefaultAnnotations configures the Client to set the given annotations on
+// every pushed artifact, e.g. the team or environment owning the artifacts.
+// The annotations set in the push Metadata take precedence over the defaults.
+// The given map is copied, later changes to it don't affect the Client.
+func WithDefaultAnnotations(annotations map[string]string) ClientOption {
+	return func(c *Client) {
+		c.annotations = make(map[string]string, len(annotations))
+		for k, v := range annotations {
+			c.annotations[k] = v
+		}
+	}
 }
 
 // NewClient returns an OCI client configured with the given crane options.
-func NewClient(opts []crane.Option) *Client {
+func NewClient(opts []crane.Option, clientOpts ...ClientOption) *Client {
 	options := []crane.Option{
 		crane.WithUserAgent(oci.UserAgent),
 	}
 	options = append(options, opts...)
 
-	return &Client{options: options}
+	c := &Client{options: options}
+	for _, opt := range clientOpts {
+		opt(c)
+	}
+	return c
 }
 
 // NewLocalClient returns an OCI client configured with the Docker keychain helpers.
-func NewLocalClient() *Client {
+func NewLocalClient(clientOpts ...ClientOption) *Client {
 	options := []crane.Option{
 		crane.WithUserAgent(oci.UserAgent),
 		crane.WithPlatform(&gcrv1.Platform{
@@ -50,7 +123,11 @@ func NewLocalClient() *Client {
 			OSVersion:    "v2",
 		}),
 	}
-	return &Client{options: options}
+	c := &Client{options: options}
+	for _, opt := range clientOpts {
+		opt(c)
+	}
+	return c
 }
 
 // optionsWithContext returns the crane options for the given context.
@@ -58,5 +135,77 @@ func (c *Client) optionsWithContext(ctx context.Context) []crane.Option {
 	options := []crane.Option{
 		crane.WithContext(ctx),
 	}
-	return append(options, c.options...)
+	options = append(options, c.options...)
+	if c.transport != nil || c.bandwidth != nil {
+		options = append(options, crane.WithTransport(c.baseTransport()))
+	}
+	return options
+}
+
+// baseTransport returns the HTTP transport configured for the Client.
+func (c *Client) baseTransport() http.RoundTripper {
+	transport := c.transport
+	if transport == nil {
+		transport = remote.DefaultTransport
+	}
+	if c.bandwidth != nil {
+		transport = &throttledTransport{base: transport, bandwidth: c.bandwidth}
+	}
+	return transport
+}
+
+// artifactAnnotations returns the default annotations merged with the given metadata annotations.
+func (c *Client) artifactAnnotations(meta Metadata) map[string]string {
+	annotations := make(map[string]string, len(c.annotations))
+	for k, v := range c.annotations {
+		annotations[k] = v
+	}
+	for k, v := range meta.ToAnnotations() {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// mkdirTemp creates a temporary directory under the Client temp dir.
+func (c *Client) mkdirTemp(pattern string) (string, error) {
+	return os.MkdirTemp(c.tempDir, tempDirPrefix+pattern)
+}
+
+// SweepTempDirs removes the temporary directories created by the Client
+// which are older than the given age. These directories can be left behind
+// when the process is killed while pushing or diffing artifacts.
+func (c *Client) SweepTempDirs(maxAge time.Duration) error {
+	dir := c.tempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), tempDirPrefix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		if time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
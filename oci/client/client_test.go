@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	. "github.com/onsi/gomega"
+)
+
+func TestClient_TempDir(t *testing.T) {
+	g := NewWithT(t)
+	tmpDir := t.TempDir()
+	c := NewLocalClient(WithTempDir(tmpDir))
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-tempdir"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	_, err := c.Push(context.Background(), url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	entries, err := os.ReadDir(tmpDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	entries, err = os.ReadDir(tmpDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
+
+func TestClient_SweepTempDirs(t *testing.T) {
+	g := NewWithT(t)
+	tmpDir := t.TempDir()
+	c := NewLocalClient(WithTempDir(tmpDir))
+
+	orphan, err := c.mkdirTemp("push")
+	g.Expect(err).ToNot(HaveOccurred())
+	old := time.Now().Add(-2 * time.Hour)
+	g.Expect(os.Chtimes(orphan, old, old)).To(Succeed())
+
+	recent, err := c.mkdirTemp("push")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	unrelated := filepath.Join(tmpDir, "unrelated")
+	g.Expect(os.Mkdir(unrelated, 0o750)).To(Succeed())
+	g.Expect(os.Chtimes(unrelated, old, old)).To(Succeed())
+
+	g.Expect(c.SweepTempDirs(time.Hour)).To(Succeed())
+
+	_, err = os.Stat(orphan)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+	g.Expect(recent).To(BeADirectory())
+	g.Expect(unrelated).To(BeADirectory())
+}
+
+func TestClient_WithCraneOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	var userAgents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := NewLocalClient(WithCraneOptions(crane.WithUserAgent("custom-agent/v1")))
+	url := fmt.Sprintf("%s/%s:%s", strings.TrimPrefix(srv.URL, "http://"), "test-options", "v0.0.1")
+	_, err := c.Pull(context.Background(), url, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(userAgents).ToNot(BeEmpty())
+	for _, ua := range userAgents {
+		g.Expect(ua).To(HavePrefix("custom-agent/v1"))
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"compress/gzip"
+	"io"
+	"runtime"
+
+	"github.com/klauspost/pgzip"
+)
+
+// Compressor returns a writer which compresses the archive data written to w.
+// The artifacts are published with a gzip layer media type, hence the
+// compressed stream must be in the gzip format.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// GzipCompressor compresses the archive on a single core using compress/gzip.
+// This is the default Compressor used by Build.
+func GzipCompressor(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// ParallelGzipCompressor returns a Compressor which splits the archive in blocks
+// of the given size and compresses up to the given number of blocks in parallel.
+// If blockSize or blocks are zero, the pgzip defaults are used (1MB blocks,
+// as many blocks as available CPUs).
+func ParallelGzipCompressor(blockSize, blocks int) Compressor {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		zw := pgzip.NewWriter(w)
+		if blockSize > 0 || blocks > 0 {
+			if blockSize <= 0 {
+				blockSize = 1 << 20
+			}
+			if blocks <= 0 {
+				blocks = runtime.GOMAXPROCS(0)
+			}
+			if err := zw.SetConcurrency(blockSize, blocks); err != nil {
+				return nil, err
+			}
+		}
+		return zw, nil
+	}
+}
@@ -19,17 +19,46 @@ package client
 import (
 	"context"
 	"fmt"
+
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
 // Delete deletes a particular image from an OCI repository
-// If the url has no tag, the latest image is deleted
+// If the url has no tag, the latest image is deleted.
+// When the url points to a tag, only the tag is removed from the repository,
+// the manifest and the other tags pointing to it are left untouched.
+// If the registry doesn't support the deletion of tags, ErrDeleteUnsupported
+// is returned and the artifact can be deleted with DeleteDigest instead.
 func (c *Client) Delete(ctx context.Context, url string) error {
 	_, err := name.ParseReference(url)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
 
-	return crane.Delete(url, c.optionsWithContext(ctx)...)
+	return c.delete(ctx, url)
+}
+
+// DeleteDigest deletes the manifest with the given digest from an OCI
+// repository, all the tags pointing to the manifest are removed with it.
+// The url must be in the format '<repository>@<digest>'.
+// If the registry doesn't support the deletion of manifests,
+// ErrDeleteUnsupported is returned.
+func (c *Client) DeleteDigest(ctx context.Context, url string) error {
+	_, err := name.NewDigest(url)
+	if err != nil {
+		return fmt.Errorf("invalid digest URL: %w", err)
+	}
+
+	return c.delete(ctx, url)
+}
+
+func (c *Client) delete(ctx context.Context, url string) error {
+	if err := crane.Delete(url, c.optionsWithContext(ctx)...); err != nil {
+		if isUnsupported(err) {
+			return fmt.Errorf("%w: deleting '%s' failed: %w", ErrDeleteUnsupported, url, wrapRegistryError(err))
+		}
+		return wrapRegistryError(err)
+	}
+	return nil
 }
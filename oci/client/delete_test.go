@@ -18,6 +18,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/go-containerregistry/pkg/crane"
 	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -89,3 +90,39 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteDigest(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := "test-delete-digest" + randStringRunes(5)
+
+	img, err := random.Image(1024, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	dst := fmt.Sprintf("%s/%s:v0.0.1", dockerReg, repo)
+	err = crane.Push(img, dst, c.options...)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	digest, err := img.Digest()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	t.Run("rejects tag url", func(t *testing.T) {
+		g := NewWithT(t)
+		err := c.DeleteDigest(ctx, dst)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("invalid digest URL"))
+	})
+
+	t.Run("returns typed error when deletion is disabled", func(t *testing.T) {
+		g := NewWithT(t)
+		// The test registry runs without storage deletion enabled.
+		err := c.DeleteDigest(ctx, fmt.Sprintf("%s/%s@%s", dockerReg, repo, digest.String()))
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, ErrDeleteUnsupported)).To(BeTrue())
+		var rerr *RegistryError
+		g.Expect(errors.As(err, &rerr)).To(BeTrue())
+
+		_, err = crane.Pull(dst)
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+}
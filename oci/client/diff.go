@@ -30,14 +30,15 @@ import (
 )
 
 // Diff compares the files included in an OCI image with the local files in the given path
-// and returns an error if the contents is different
-func (c *Client) Diff(ctx context.Context, url, dir string, ignorePaths []string) error {
+// and returns an error if the contents is different.
+// The build options must match the ones used when pushing the artifact.
+func (c *Client) Diff(ctx context.Context, url, dir string, ignorePaths []string, opts ...BuildOption) error {
 	_, err := name.ParseReference(url)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
 
-	tmpBuildDir, err := os.MkdirTemp("", "ocibuild")
+	tmpBuildDir, err := c.mkdirTemp("diff")
 	if err != nil {
 		return fmt.Errorf("creating temp build dir failed: %w", err)
 	}
@@ -45,7 +46,7 @@ func (c *Client) Diff(ctx context.Context, url, dir string, ignorePaths []string
 
 	tmpFile := filepath.Join(tmpBuildDir, "artifact.tgz")
 
-	if err := c.Build(tmpFile, dir, ignorePaths); err != nil {
+	if err := c.build(ctx, tmpFile, dir, ignorePaths, opts...); err != nil {
 		return fmt.Errorf("building artifact failed: %w", err)
 	}
 
@@ -67,7 +68,7 @@ func (c *Client) Diff(ctx context.Context, url, dir string, ignorePaths []string
 
 	img, err := crane.Pull(url, c.optionsWithContext(ctx)...)
 	if err != nil {
-		return err
+		return wrapRegistryError(err)
 	}
 
 	layers, err := img.Layers()
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// FileChange is the type of change of a file between two artifacts.
+type FileChange string
+
+const (
+	// FileAdded means the file exists only in the second artifact.
+	FileAdded FileChange = "added"
+
+	// FileRemoved means the file exists only in the first artifact.
+	FileRemoved FileChange = "removed"
+
+	// FileModified means the file content differs between the artifacts.
+	FileModified FileChange = "modified"
+)
+
+// FileDiff holds the difference of a file between two artifacts.
+type FileDiff struct {
+	// Path is the path of the file in the artifact.
+	Path string
+
+	// Change is the type of change.
+	Change FileChange
+
+	// OldDigest is the sha256 digest of the file in the first artifact,
+	// empty for added files.
+	OldDigest string
+
+	// NewDigest is the sha256 digest of the file in the second artifact,
+	// empty for removed files.
+	NewDigest string
+}
+
+// DiffArtifacts downloads the artifacts the given urls point to and returns the
+// files which differ between them, sorted by path. The artifact layers are
+// streamed and hashed file by file without being extracted to disk.
+// The pull options can be used to pass the identities for encrypted artifacts.
+func (c *Client) DiffArtifacts(ctx context.Context, urlA, urlB string, opts ...PullOption) ([]FileDiff, error) {
+	o := &pullOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	filesA, err := c.artifactFiles(ctx, urlA, o.identities)
+	if err != nil {
+		return nil, fmt.Errorf("listing files of '%s' failed: %w", urlA, err)
+	}
+
+	filesB, err := c.artifactFiles(ctx, urlB, o.identities)
+	if err != nil {
+		return nil, fmt.Errorf("listing files of '%s' failed: %w", urlB, err)
+	}
+
+	var diffs []FileDiff
+	for p, digestA := range filesA {
+		digestB, ok := filesB[p]
+		switch {
+		case !ok:
+			diffs = append(diffs, FileDiff{Path: p, Change: FileRemoved, OldDigest: digestA})
+		case digestA != digestB:
+			diffs = append(diffs, FileDiff{Path: p, Change: FileModified, OldDigest: digestA, NewDigest: digestB})
+		}
+	}
+	for p, digestB := range filesB {
+		if _, ok := filesA[p]; !ok {
+			diffs = append(diffs, FileDiff{Path: p, Change: FileAdded, NewDigest: digestB})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// artifactFiles returns the sha256 digests of the regular files of the artifact
+// indexed by path, the files of the appended layers override the previous ones.
+func (c *Client) artifactFiles(ctx context.Context, url string, identities []age.Identity) (map[string]string, error) {
+	img, err := c.pullImage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	files := make(map[string]string)
+	for i, layer := range layers {
+		err := c.readLayer(layer, "", identities, func(content io.Reader) error {
+			return hashTarball(content, files)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer %d: %w", i, err)
+		}
+	}
+
+	return files, nil
+}
+
+// hashTarball adds the sha256 digests of the regular files of the gzip
+// compressed tarball to the given map.
+func hashTarball(r io.Reader, files map[string]string) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("requires gzip-compressed body: %w", err)
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tarball failed: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return fmt.Errorf("reading '%s' failed: %w", header.Name, err)
+		}
+		files[strings.TrimPrefix(path.Clean(header.Name), "/")] = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_DiffArtifacts(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := fmt.Sprintf("%s/%s", dockerReg, "test-diff-artifacts"+randStringRunes(5))
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	writeFiles := func(files map[string]string) string {
+		dir := t.TempDir()
+		for p, content := range files {
+			g.Expect(os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0o750)).To(Succeed())
+			g.Expect(os.WriteFile(filepath.Join(dir, p), []byte(content), 0o600)).To(Succeed())
+		}
+		return dir
+	}
+
+	dirA := writeFiles(map[string]string{
+		"unchanged.yaml":      "a",
+		"modified.yaml":       "b",
+		"deploy/removed.yaml": "c",
+	})
+	dirB := writeFiles(map[string]string{
+		"unchanged.yaml":    "a",
+		"modified.yaml":     "bb",
+		"deploy/added.yaml": "d",
+	})
+
+	urlA, err := c.Push(ctx, repo+":v0.0.1", dirA, metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	urlB, err := c.Push(ctx, repo+":v0.0.2", dirB, metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	diffs, err := c.DiffArtifacts(ctx, urlA, urlB)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(diffs).To(HaveLen(3))
+
+	g.Expect(diffs[0].Path).To(Equal("deploy/added.yaml"))
+	g.Expect(diffs[0].Change).To(Equal(FileAdded))
+	g.Expect(diffs[0].OldDigest).To(BeEmpty())
+
+	g.Expect(diffs[1].Path).To(Equal("deploy/removed.yaml"))
+	g.Expect(diffs[1].Change).To(Equal(FileRemoved))
+	g.Expect(diffs[1].NewDigest).To(BeEmpty())
+
+	g.Expect(diffs[2].Path).To(Equal("modified.yaml"))
+	g.Expect(diffs[2].Change).To(Equal(FileModified))
+	g.Expect(diffs[2].OldDigest).ToNot(Equal(diffs[2].NewDigest))
+
+	diffs, err = c.DiffArtifacts(ctx, urlA, repo+":v0.0.1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(diffs).To(BeEmpty())
+}
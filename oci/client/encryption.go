@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+)
+
+// WithPushEncryption configures Push to encrypt the artifact layer
+// for the given age recipients.
+func WithPushEncryption(recipients ...age.Recipient) PushOption {
+	return func(o *pushOptions) {
+		o.recipients = recipients
+	}
+}
+
+// WithPullDecryption configures Pull to decrypt age encrypted layers
+// with the given identities.
+func WithPullDecryption(identities ...age.Identity) PullOption {
+	return func(o *pullOptions) {
+		o.identities = identities
+	}
+}
+
+// encryptFile encrypts the content of the src file for the given recipients
+// and writes the result to the dst file.
+func encryptFile(src, dst string, recipients []age.Recipient) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	w, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("initializing encryption failed: %w", err)
+	}
+
+	if _, err := io.Copy(w, in); err != nil {
+		w.Close()
+		out.Close()
+		return fmt.Errorf("encrypting artifact failed: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// decryptReader returns a reader for the plain content of the given
+// age encrypted blob.
+func decryptReader(blob io.Reader, identities []age.Identity) (io.Reader, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("artifact layer is encrypted but no decryption identities were provided")
+	}
+
+	r, err := age.Decrypt(blob, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting layer failed: %w", err)
+	}
+
+	return r, nil
+}
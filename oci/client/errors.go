@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+var (
+	// ErrTagExists is returned when pushing to a tag that already resolves
+	// to a different artifact and overwriting is not allowed.
+	ErrTagExists = errors.New("tag already exists")
+
+	// ErrDeleteUnsupported is returned when the registry rejects the deletion
+	// of a tag or a manifest, e.g. because deletion is disabled or because
+	// the registry only supports the deletion of manifests by digest.
+	ErrDeleteUnsupported = errors.New("deletion not supported by registry")
+
+	// ErrArtifactStale is returned when pulling an artifact created outside
+	// the accepted time window.
+	ErrArtifactStale = errors.New("artifact is stale")
+)
+
+// Registry error categories, the errors returned by the Client for failed
+// registry calls match one of these with errors.Is.
+var (
+	// ErrNotFound is returned when the repository, manifest or blob doesn't exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized is returned when the registry rejects the credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrDenied is returned when the credentials lack the permission for the operation.
+	ErrDenied = errors.New("access denied")
+
+	// ErrTooManyRequests is returned when the registry rate limits the client.
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// ErrManifestInvalid is returned when the registry rejects a pushed manifest.
+	ErrManifestInvalid = errors.New("manifest invalid")
+)
+
+// RegistryError is returned for failed registry calls, it holds the HTTP status
+// and the error code returned by the registry. The error matches the category
+// sentinel (e.g. ErrNotFound) with errors.Is and the underlying
+// transport.Error with errors.As.
+type RegistryError struct {
+	// StatusCode is the HTTP status code of the registry response.
+	StatusCode int
+
+	// Code is the first error code returned by the registry, e.g. MANIFEST_UNKNOWN.
+	Code string
+
+	category error
+	err      error
+}
+
+// Error returns the message of the underlying error.
+func (e *RegistryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *RegistryError) Unwrap() error {
+	return e.err
+}
+
+// Is checks if the error belongs to the given category.
+func (e *RegistryError) Is(target error) bool {
+	return e.category != nil && target == e.category
+}
+
+// registryErrorCategories maps the registry error codes to categories.
+var registryErrorCategories = map[transport.ErrorCode]error{
+	transport.BlobUnknownErrorCode:         ErrNotFound,
+	transport.ManifestUnknownErrorCode:     ErrNotFound,
+	transport.NameUnknownErrorCode:         ErrNotFound,
+	transport.UnauthorizedErrorCode:        ErrUnauthorized,
+	transport.DeniedErrorCode:              ErrDenied,
+	transport.TooManyRequestsErrorCode:     ErrTooManyRequests,
+	transport.ManifestInvalidErrorCode:     ErrManifestInvalid,
+	transport.ManifestBlobUnknownErrorCode: ErrManifestInvalid,
+	transport.ManifestUnverifiedErrorCode:  ErrManifestInvalid,
+}
+
+// registryStatusCategories maps the HTTP status codes to categories,
+// for registries which don't return error codes.
+var registryStatusCategories = map[int]error{
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrDenied,
+	http.StatusTooManyRequests: ErrTooManyRequests,
+}
+
+// wrapRegistryError wraps the registry transport errors in a RegistryError,
+// other errors are returned unchanged.
+func wrapRegistryError(err error) error {
+	var terr *transport.Error
+	if err == nil || !errors.As(err, &terr) {
+		return err
+	}
+
+	var rerr *RegistryError
+	if errors.As(err, &rerr) {
+		return err
+	}
+
+	rerr = &RegistryError{
+		StatusCode: terr.StatusCode,
+		err:        err,
+	}
+	for _, diagnostic := range terr.Errors {
+		if rerr.Code == "" {
+			rerr.Code = string(diagnostic.Code)
+		}
+		if category, ok := registryErrorCategories[diagnostic.Code]; ok {
+			rerr.category = category
+			break
+		}
+	}
+	if rerr.category == nil {
+		rerr.category = registryStatusCategories[terr.StatusCode]
+	}
+	return rerr
+}
+
+// isNotFound checks if the given error is a registry not found error.
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
+
+// isUnsupported checks if the given error is a registry unsupported operation error.
+func isUnsupported(err error) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return false
+	}
+	if terr.StatusCode == http.StatusMethodNotAllowed {
+		return true
+	}
+	for _, diagnostic := range terr.Errors {
+		if diagnostic.Code == transport.UnsupportedErrorCode {
+			return true
+		}
+	}
+	return false
+}
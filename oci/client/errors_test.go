@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	. "github.com/onsi/gomega"
+)
+
+func Test_wrapRegistryError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantCategory error
+		wantCode     string
+	}{
+		{
+			name:         "error code",
+			err:          &transport.Error{StatusCode: http.StatusNotFound, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}},
+			wantCategory: ErrNotFound,
+			wantCode:     "MANIFEST_UNKNOWN",
+		},
+		{
+			name:         "first known error code",
+			err:          &transport.Error{StatusCode: http.StatusBadRequest, Errors: []transport.Diagnostic{{Code: transport.UnknownErrorCode}, {Code: transport.ManifestInvalidErrorCode}}},
+			wantCategory: ErrManifestInvalid,
+			wantCode:     "UNKNOWN",
+		},
+		{
+			name:         "status code",
+			err:          &transport.Error{StatusCode: http.StatusTooManyRequests},
+			wantCategory: ErrTooManyRequests,
+		},
+		{
+			name:         "wrapped transport error",
+			err:          fmt.Errorf("pulling failed: %w", &transport.Error{StatusCode: http.StatusForbidden, Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}}),
+			wantCategory: ErrDenied,
+			wantCode:     "DENIED",
+		},
+		{
+			name: "uncategorized",
+			err:  &transport.Error{StatusCode: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := wrapRegistryError(tt.err)
+
+			var rerr *RegistryError
+			g.Expect(errors.As(err, &rerr)).To(BeTrue())
+			g.Expect(rerr.Code).To(Equal(tt.wantCode))
+			g.Expect(err.Error()).To(Equal(tt.err.Error()))
+
+			var terr *transport.Error
+			g.Expect(errors.As(err, &terr)).To(BeTrue())
+
+			for _, category := range []error{ErrNotFound, ErrUnauthorized, ErrDenied, ErrTooManyRequests, ErrManifestInvalid} {
+				g.Expect(errors.Is(err, category)).To(Equal(category == tt.wantCategory))
+			}
+		})
+	}
+
+	g := NewWithT(t)
+	plain := errors.New("plain error")
+	g.Expect(wrapRegistryError(plain)).To(Equal(plain))
+	g.Expect(wrapRegistryError(nil)).To(BeNil())
+}
+
+func Test_Pull_NotFound(t *testing.T) {
+	g := NewWithT(t)
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-not-found"+randStringRunes(5), "v0.0.1")
+
+	_, err := c.Pull(context.Background(), url, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+
+	var rerr *RegistryError
+	g.Expect(errors.As(err, &rerr)).To(BeTrue())
+	g.Expect(rerr.StatusCode).To(Equal(http.StatusNotFound))
+}
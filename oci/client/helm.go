@@ -0,0 +1,331 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/pkg/oci"
+)
+
+// HelmChart holds the information about a Helm chart pulled from an OCI repository.
+type HelmChart struct {
+	// Name is the chart name.
+	Name string `json:"name"`
+
+	// Version is the chart SemVer.
+	Version string `json:"version"`
+
+	// Digest is the chart artifact digest.
+	Digest string `json:"digest"`
+
+	// ChartPath is the local path of the chart archive.
+	ChartPath string `json:"chartPath"`
+
+	// ProvenancePath is the local path of the chart provenance file,
+	// empty if the artifact doesn't contain a provenance file.
+	ProvenancePath string `json:"provenancePath,omitempty"`
+}
+
+// PushHelmChart uploads the given Helm chart archive, and optionally its provenance
+// file, to the given OCI repository using the Helm media types. It returns the digest
+// of the chart artifact.
+func (c *Client) PushHelmChart(ctx context.Context, url, chartPath, provPath string) (string, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	config, err := readHelmChartMetadata(chartPath)
+	if err != nil {
+		return "", err
+	}
+
+	chartLayer, err := newFileLayer(chartPath, oci.HelmChartContentMediaType)
+	if err != nil {
+		return "", fmt.Errorf("reading chart failed: %w", err)
+	}
+	layers := []*fileLayer{chartLayer}
+
+	if provPath != "" {
+		provLayer, err := newFileLayer(provPath, oci.HelmChartProvenanceMediaType)
+		if err != nil {
+			return "", fmt.Errorf("reading chart provenance failed: %w", err)
+		}
+		layers = append(layers, provLayer)
+	}
+
+	img, err := partial.CompressedToImage(&artifactImage{
+		config:          config,
+		configMediaType: oci.HelmConfigMediaType,
+		layers:          layers,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := crane.Push(img, url, c.optionsWithContext(ctx)...); err != nil {
+		return "", fmt.Errorf("pushing chart failed: %w", wrapRegistryError(err))
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("parsing chart digest failed: %w", err)
+	}
+
+	return ref.Context().Digest(digest.String()).String(), nil
+}
+
+// PullHelmChart downloads a Helm chart from an OCI repository and writes the chart
+// archive and its provenance file, if any, to the given directory using the
+// '<name>-<version>.tgz' and '<name>-<version>.tgz.prov' file names.
+// Charts with a name or version which are invalid for Helm are rejected.
+func (c *Client) PullHelmChart(ctx context.Context, url, outDir string) (*HelmChart, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	img, err := c.pullImage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest failed: %w", err)
+	}
+
+	if manifest.Config.MediaType != oci.HelmConfigMediaType {
+		return nil, fmt.Errorf("artifact is not a Helm chart, config media type: '%s'", manifest.Config.MediaType)
+	}
+
+	rawConfig, err := img.RawConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("fetching chart metadata failed: %w", err)
+	}
+
+	chart := &HelmChart{}
+	if err := json.Unmarshal(rawConfig, chart); err != nil {
+		return nil, fmt.Errorf("parsing chart metadata failed: %w", err)
+	}
+	if err := validateHelmChartMetadata(chart); err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("parsing digest failed: %w", err)
+	}
+	chart.Digest = ref.Context().Digest(digest.String()).String()
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating output directory failed: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s.tgz", chart.Name, chart.Version)
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("parsing layer media type failed: %w", err)
+		}
+
+		switch mediaType {
+		case oci.HelmChartContentMediaType:
+			chart.ChartPath = filepath.Join(outDir, fileName)
+			if err := writeLayer(layer, chart.ChartPath); err != nil {
+				return nil, fmt.Errorf("writing chart failed: %w", err)
+			}
+		case oci.HelmChartProvenanceMediaType:
+			chart.ProvenancePath = filepath.Join(outDir, fileName+".prov")
+			if err := writeLayer(layer, chart.ProvenancePath); err != nil {
+				return nil, fmt.Errorf("writing chart provenance failed: %w", err)
+			}
+		}
+	}
+
+	if chart.ChartPath == "" {
+		return nil, fmt.Errorf("no chart layer found in artifact")
+	}
+
+	return chart, nil
+}
+
+// helmChartName matches the chart names accepted by Helm.
+var helmChartName = regexp.MustCompile("^[a-zA-Z0-9._-]+$")
+
+// validateHelmChartMetadata checks the chart name and version against the Helm
+// rules, as they are used to compose the paths of the pulled files.
+func validateHelmChartMetadata(chart *HelmChart) error {
+	if chart.Name == "" || chart.Version == "" {
+		return fmt.Errorf("chart metadata must contain the name and version")
+	}
+	if !helmChartName.MatchString(chart.Name) || chart.Name == "." || chart.Name == ".." {
+		return fmt.Errorf("invalid chart name '%s'", chart.Name)
+	}
+	if _, err := semver.NewVersion(chart.Version); err != nil {
+		return fmt.Errorf("invalid chart version '%s': %w", chart.Version, err)
+	}
+	return nil
+}
+
+// readHelmChartMetadata returns the content of the Chart.yaml file found at the root
+// of the given chart archive, encoded as JSON.
+func readHelmChartMetadata(chartPath string) ([]byte, error) {
+	f, err := os.Open(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading chart archive failed: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading chart archive failed: %w", err)
+		}
+
+		// the chart archive contains a single top directory named after the chart
+		dir, file := path.Split(path.Clean(header.Name))
+		if file != "Chart.yaml" || dir == "" || path.Dir(path.Clean(dir)) != "." {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading Chart.yaml failed: %w", err)
+		}
+
+		metadata, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Chart.yaml failed: %w", err)
+		}
+		return metadata, nil
+	}
+
+	return nil, fmt.Errorf("Chart.yaml not found in chart archive")
+}
+
+// writeLayer writes the layer blob to the given path.
+func writeLayer(layer gcrv1.Layer, path string) error {
+	blob, err := layer.Compressed()
+	if err != nil {
+		return wrapRegistryError(err)
+	}
+	defer blob.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, blob); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// artifactImage is an OCI artifact made of an arbitrary config blob and file layers.
+type artifactImage struct {
+	config          []byte
+	configMediaType types.MediaType
+	layers          []*fileLayer
+}
+
+var _ partial.CompressedImageCore = &artifactImage{}
+
+// RawConfigFile returns the config blob.
+func (i *artifactImage) RawConfigFile() ([]byte, error) {
+	return i.config, nil
+}
+
+// MediaType returns the OCI manifest media type.
+func (i *artifactImage) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+// RawManifest returns the serialized OCI manifest.
+func (i *artifactImage) RawManifest() ([]byte, error) {
+	configDigest, configSize, err := gcrv1.SHA256(bytes.NewReader(i.config))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := gcrv1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config: gcrv1.Descriptor{
+			MediaType: i.configMediaType,
+			Size:      configSize,
+			Digest:    configDigest,
+		},
+	}
+
+	for _, l := range i.layers {
+		manifest.Layers = append(manifest.Layers, gcrv1.Descriptor{
+			MediaType: l.mediaType,
+			Size:      l.size,
+			Digest:    l.digest,
+		})
+	}
+
+	return json.Marshal(manifest)
+}
+
+// LayerByDigest returns the layer with the given digest.
+func (i *artifactImage) LayerByDigest(h gcrv1.Hash) (partial.CompressedLayer, error) {
+	for _, l := range i.layers {
+		if l.digest == h {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s not found", h)
+}
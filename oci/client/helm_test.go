@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/oci"
+)
+
+func Test_Push_Pull_HelmChart(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := "test-helm" + randStringRunes(5)
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, repo, "1.0.0")
+
+	tmpDir := t.TempDir()
+	chartPath := filepath.Join(tmpDir, "podinfo-1.0.0.tgz")
+	writeChartArchive(t, chartPath, map[string]string{
+		"podinfo/Chart.yaml":              "apiVersion: v2\nname: podinfo\nversion: 1.0.0\n",
+		"podinfo/values.yaml":             "replicaCount: 1\n",
+		"podinfo/charts/redis/Chart.yaml": "apiVersion: v2\nname: redis\nversion: 2.0.0\n",
+	})
+	provPath := chartPath + ".prov"
+	g.Expect(os.WriteFile(provPath, []byte("provenance"), 0o600)).To(Succeed())
+
+	digest, err := c.PushHelmChart(ctx, url, chartPath, provPath)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	manifest, err := image.Manifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest.Config.MediaType)).To(Equal(oci.HelmConfigMediaType))
+	g.Expect(manifest.Layers).To(HaveLen(2))
+	g.Expect(string(manifest.Layers[0].MediaType)).To(Equal(oci.HelmChartContentMediaType))
+	g.Expect(string(manifest.Layers[1].MediaType)).To(Equal(oci.HelmChartProvenanceMediaType))
+
+	outDir := filepath.Join(t.TempDir(), "charts", "podinfo")
+	chart, err := c.PullHelmChart(ctx, url, outDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(chart.Name).To(Equal("podinfo"))
+	g.Expect(chart.Version).To(Equal("1.0.0"))
+	g.Expect(chart.Digest).To(Equal(digest))
+	g.Expect(chart.ChartPath).To(Equal(filepath.Join(outDir, "podinfo-1.0.0.tgz")))
+	g.Expect(chart.ProvenancePath).To(Equal(filepath.Join(outDir, "podinfo-1.0.0.tgz.prov")))
+
+	expected, err := os.ReadFile(chartPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.ReadFile(chart.ChartPath)).To(Equal(expected))
+	g.Expect(os.ReadFile(chart.ProvenancePath)).To(Equal([]byte("provenance")))
+}
+
+func Test_PullHelmChart_NotAChart(t *testing.T) {
+	g := NewWithT(t)
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-helm-invalid"+randStringRunes(5), "v0.0.1")
+
+	img, err := random.Image(1024, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crane.Push(img, url, c.options...)).To(Succeed())
+
+	_, err = c.PullHelmChart(context.Background(), url, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("not a Helm chart"))
+}
+
+func Test_PullHelmChart_InvalidMetadata(t *testing.T) {
+	chartPath := filepath.Join(t.TempDir(), "podinfo-1.0.0.tgz")
+	writeChartArchive(t, chartPath, map[string]string{
+		"podinfo/Chart.yaml": "apiVersion: v2\nname: podinfo\nversion: 1.0.0\n",
+	})
+
+	tests := []struct {
+		name      string
+		config    string
+		expectErr string
+	}{
+		{
+			name:      "path traversal in name",
+			config:    `{"name":"../../x","version":"1.0.0"}`,
+			expectErr: "invalid chart name",
+		},
+		{
+			name:      "parent directory name",
+			config:    `{"name":"..","version":"1.0.0"}`,
+			expectErr: "invalid chart name",
+		},
+		{
+			name:      "path separator in version",
+			config:    `{"name":"podinfo","version":"1.0.0/../../x"}`,
+			expectErr: "invalid chart version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := NewLocalClient()
+			url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-helm-malicious"+randStringRunes(5), "1.0.0")
+
+			layer, err := newFileLayer(chartPath, oci.HelmChartContentMediaType)
+			g.Expect(err).ToNot(HaveOccurred())
+			img, err := partial.CompressedToImage(&artifactImage{
+				config:          []byte(tt.config),
+				configMediaType: oci.HelmConfigMediaType,
+				layers:          []*fileLayer{layer},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(crane.Push(img, url, c.options...)).To(Succeed())
+
+			tmpDir := t.TempDir()
+			_, err = c.PullHelmChart(context.Background(), url, filepath.Join(tmpDir, "a", "b"))
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(tt.expectErr))
+
+			entries, err := os.ReadDir(tmpDir)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(entries).To(BeEmpty())
+		})
+	}
+}
+
+func writeChartArchive(t *testing.T, path string, files map[string]string) {
+	g := NewWithT(t)
+
+	f, err := os.Create(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		g.Expect(tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0o600,
+			Size: int64(len(content)),
+		})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Expect(tw.Close()).To(Succeed())
+	g.Expect(gw.Close()).To(Succeed())
+}
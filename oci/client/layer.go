@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io"
+	"os"
+
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// fileLayer is a layer backed by a local file whose content is uploaded as-is,
+// without being compressed again. It is used for blobs that are not plain
+// tarballs, hence the diff ID matches the digest.
+type fileLayer struct {
+	path      string
+	digest    gcrv1.Hash
+	size      int64
+	mediaType types.MediaType
+}
+
+// newFileLayer computes the digest and size of the given file and returns
+// a layer of the given media type.
+func newFileLayer(path string, mediaType types.MediaType) (*fileLayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digest, size, err := gcrv1.SHA256(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileLayer{
+		path:      path,
+		digest:    digest,
+		size:      size,
+		mediaType: mediaType,
+	}, nil
+}
+
+// Digest returns the SHA256 of the file content.
+func (l *fileLayer) Digest() (gcrv1.Hash, error) {
+	return l.digest, nil
+}
+
+// DiffID returns the SHA256 of the file content.
+func (l *fileLayer) DiffID() (gcrv1.Hash, error) {
+	return l.digest, nil
+}
+
+// Compressed returns a reader for the file content.
+func (l *fileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Uncompressed returns a reader for the file content.
+func (l *fileLayer) Uncompressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Size returns the size of the file in bytes.
+func (l *fileLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+// MediaType returns the media type of the layer.
+func (l *fileLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
@@ -42,9 +42,9 @@ type ListOptions struct {
 // List fetches the tags and their manifests for a given OCI repository.
 func (c *Client) List(ctx context.Context, url string, opts ListOptions) ([]Metadata, error) {
 	metas := make([]Metadata, 0)
-	tags, err := crane.ListTags(url, c.options...)
+	tags, err := c.Tags(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("listing tags failed: %w", err)
+		return nil, err
 	}
 
 	sort.Slice(tags, func(i, j int) bool { return tags[i] > tags[j] })
@@ -93,7 +93,7 @@ func (c *Client) List(ctx context.Context, url string, opts ListOptions) ([]Meta
 
 		manifestJSON, err := crane.Manifest(meta.URL, c.optionsWithContext(ctx)...)
 		if err != nil {
-			return nil, fmt.Errorf("fetching manifest failed: %w", err)
+			return nil, fmt.Errorf("fetching manifest failed: %w", wrapRegistryError(err))
 		}
 
 		manifest, err := gcrv1.ParseManifest(bytes.NewReader(manifestJSON))
@@ -109,7 +109,7 @@ func (c *Client) List(ctx context.Context, url string, opts ListOptions) ([]Meta
 
 		digest, err := crane.Digest(meta.URL, c.optionsWithContext(ctx)...)
 		if err != nil {
-			return nil, fmt.Errorf("fetching digest failed: %w", err)
+			return nil, fmt.Errorf("fetching digest failed: %w", wrapRegistryError(err))
 		}
 		meta.Digest = digest
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/fluxcd/pkg/oci"
@@ -29,6 +30,8 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 // LoginWithCredentials configures the client with static credentials, accepts a single token
@@ -80,3 +83,81 @@ func (c *Client) LoginWithProvider(ctx context.Context, url string, provider oci
 	c.options = append(c.options, crane.WithAuth(authenticator))
 	return nil
 }
+
+// OAuth2Config holds the settings for exchanging client credentials
+// for registry access tokens against an OAuth2 token endpoint.
+type OAuth2Config struct {
+	// TokenURL is the OAuth2 token endpoint of the identity provider.
+	TokenURL string
+
+	// ClientID is the OAuth2 client ID.
+	ClientID string
+
+	// ClientSecret is the OAuth2 client secret.
+	ClientSecret string
+
+	// Scopes holds the optional scopes requested for the access token.
+	Scopes []string
+
+	// EndpointParams holds additional parameters sent to the token endpoint,
+	// e.g. the audience required by some identity providers.
+	EndpointParams map[string][]string
+
+	// Username configures the client to present the access token as a password
+	// with the given username to the registry token service. When empty, the
+	// access token is sent to the registry as a bearer token.
+	Username string
+}
+
+// LoginWithOAuth2 configures the client to authenticate to the registry with
+// access tokens obtained from a custom OAuth2 token endpoint using the client
+// credentials flow. The tokens are fetched on first use and refreshed when they
+// expire, using the given context which must outlive the client. The token
+// endpoint is called with the transport configured with WithTransport.
+func (c *Client) LoginWithOAuth2(ctx context.Context, config OAuth2Config) error {
+	if config.TokenURL == "" {
+		return errors.New("token URL cannot be empty")
+	}
+	if config.ClientID == "" {
+		return errors.New("client ID cannot be empty")
+	}
+
+	cc := clientcredentials.Config{
+		ClientID:       config.ClientID,
+		ClientSecret:   config.ClientSecret,
+		TokenURL:       config.TokenURL,
+		Scopes:         config.Scopes,
+		EndpointParams: config.EndpointParams,
+	}
+
+	// Fetch the tokens with the Client transport to honour its TLS and proxy settings.
+	if c.transport != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: c.transport})
+	}
+
+	c.options = append(c.options, crane.WithAuth(&oauth2Authenticator{
+		tokenSource: cc.TokenSource(ctx),
+		username:    config.Username,
+	}))
+	return nil
+}
+
+// oauth2Authenticator is an authn.Authenticator which returns the access
+// tokens of an OAuth2 token source.
+type oauth2Authenticator struct {
+	tokenSource oauth2.TokenSource
+	username    string
+}
+
+// Authorization returns the registry credentials for the current access token.
+func (a *oauth2Authenticator) Authorization() (*authn.AuthConfig, error) {
+	token, err := a.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("fetching OAuth2 token failed: %w", err)
+	}
+
+	if a.username != "" {
+		return &authn.AuthConfig{Username: a.username, Password: token.AccessToken}, nil
+	}
+	return &authn.AuthConfig{RegistryToken: token.AccessToken}, nil
+}
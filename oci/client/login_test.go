@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -81,3 +82,90 @@ func Test_Login(t *testing.T) {
 		})
 	}
 }
+
+func Test_LoginWithOAuth2(t *testing.T) {
+	g := NewWithT(t)
+
+	var tokenRequests int
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"oauth2-token","token_type":"bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	tests := []struct {
+		name         string
+		username     string
+		expectedAuth string
+	}{
+		{
+			name:         "bearer token",
+			expectedAuth: "Bearer oauth2-token",
+		},
+		{
+			name:         "token as password",
+			username:     "oauth2accesstoken",
+			expectedAuth: "Basic b2F1dGgyYWNjZXNzdG9rZW46b2F1dGgyLXRva2Vu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := NewLocalClient()
+			ctx := context.Background()
+			err := c.LoginWithOAuth2(ctx, OAuth2Config{
+				TokenURL:     tokenServer.URL,
+				ClientID:     "client",
+				ClientSecret: "secret",
+				Username:     tt.username,
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			transportFunc := mockTransport{
+				response: &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader(`{}`)),
+				},
+			}
+			c.options = append(c.options, crane.WithTransport(&transportFunc))
+
+			err = crane.Delete(fmt.Sprintf("%s/%s:%s", dockerReg, "test", "test"), c.optionsWithContext(ctx)...)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(transportFunc.request.Header.Get("Authorization")).To(Equal(tt.expectedAuth))
+		})
+	}
+
+	g.Expect(tokenRequests).To(Equal(2))
+	g.Expect(NewLocalClient().LoginWithOAuth2(context.Background(), OAuth2Config{ClientID: "client"})).ToNot(Succeed())
+}
+
+func Test_LoginWithOAuth2_Transport(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"oauth2-token","token_type":"bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-oauth2-transport"+randStringRunes(5), "v0.0.1")
+	_, err := NewLocalClient().Push(ctx, url, "testdata/artifact", Metadata{Source: "github.com/fluxcd/flux2", Revision: "rev"}, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The token server certificate is only trusted by the server's own transport.
+	c := NewLocalClient(WithTransport(tokenServer.Client().Transport))
+	g.Expect(c.LoginWithOAuth2(ctx, OAuth2Config{
+		TokenURL: tokenServer.URL,
+		ClientID: "client",
+	})).To(Succeed())
+
+	_, err = crane.Digest(url, c.optionsWithContext(ctx)...)
+	g.Expect(err).ToNot(HaveOccurred())
+}
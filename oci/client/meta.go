@@ -30,15 +30,21 @@ type Metadata struct {
 	Revision string `json:"source_revision"`
 	Digest   string `json:"digest"`
 	URL      string `json:"url"`
+
+	// Annotations holds additional annotations set on the artifact at push time.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
-// ToAnnotations returns the OpenContainers annotations map.
+// ToAnnotations returns the OpenContainers annotations map merged with the
+// additional annotations, the OpenContainers annotations take precedence.
 func (m *Metadata) ToAnnotations() map[string]string {
-	annotations := map[string]string{
-		oci.CreatedAnnotation:  m.Created,
-		oci.SourceAnnotation:   m.Source,
-		oci.RevisionAnnotation: m.Revision,
+	annotations := make(map[string]string, len(m.Annotations)+3)
+	for k, v := range m.Annotations {
+		annotations[k] = v
 	}
+	annotations[oci.CreatedAnnotation] = m.Created
+	annotations[oci.SourceAnnotation] = m.Source
+	annotations[oci.RevisionAnnotation] = m.Revision
 
 	return annotations
 }
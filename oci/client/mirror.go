@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// WithMirrors configures the Client to pull the artifacts hosted on the given
+// registry host from the given mirror hosts, in order, before falling back to
+// the origin registry. The repository path is preserved on the mirrors.
+// Tags are resolved to digests against the origin registry, the artifacts are
+// then pulled by digest from the mirrors, which ensures that the mirrored
+// content matches the origin manifest. When pulling by digest, the origin
+// registry is contacted only if all the mirrors fail.
+func WithMirrors(host string, mirrors ...string) ClientOption {
+	return func(c *Client) {
+		if c.mirrors == nil {
+			c.mirrors = make(map[string][]string)
+		}
+		c.mirrors[host] = append(c.mirrors[host], mirrors...)
+	}
+}
+
+// pullImage fetches the image from the configured mirrors of the url registry,
+// falling back to the url registry if no mirror can serve the image.
+func (c *Client) pullImage(ctx context.Context, url string) (gcrv1.Image, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	mirrors := c.mirrors[ref.Context().RegistryStr()]
+	if len(mirrors) == 0 {
+		img, err := crane.Pull(url, c.optionsWithContext(ctx)...)
+		return img, wrapRegistryError(err)
+	}
+
+	digest := ref.Identifier()
+	if _, ok := ref.(name.Tag); ok {
+		desc, err := crane.Head(url, c.optionsWithContext(ctx)...)
+		if err != nil {
+			return nil, fmt.Errorf("resolving digest from origin failed: %w", wrapRegistryError(err))
+		}
+		digest = desc.Digest.String()
+	}
+
+	var errs []string
+	for _, mirror := range mirrors {
+		mirrorURL := fmt.Sprintf("%s/%s@%s", mirror, ref.Context().RepositoryStr(), digest)
+		img, err := crane.Pull(mirrorURL, c.optionsWithContext(ctx)...)
+		if err == nil {
+			return img, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Sprintf("mirror '%s': %s", mirror, err))
+	}
+
+	img, err := crane.Pull(ref.Context().Digest(digest).String(), c.optionsWithContext(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("pulling from origin failed: %w, pulling from mirrors failed: %s", wrapRegistryError(err), strings.Join(errs, "; "))
+	}
+	return img, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/gomega"
+)
+
+// hostRecorder records the hosts of the manifest requests.
+type hostRecorder struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (r *hostRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.Contains(req.URL.Path, "/manifests/") {
+		r.mu.Lock()
+		r.hosts = append(r.hosts, req.Method+" "+req.URL.Host)
+		r.mu.Unlock()
+	}
+	return remote.DefaultTransport.RoundTrip(req)
+}
+
+func TestClient_WithMirrors(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	repo := "test-mirrors" + randStringRunes(5)
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	// The origin and the mirror are the same registry reached with different hosts.
+	mirror := dockerReg
+	origin := strings.Replace(dockerReg, "localhost", "127.0.0.1", 1)
+	unreachable := "localhost:1"
+
+	digestURL, err := NewLocalClient().Push(ctx, fmt.Sprintf("%s/%s:v0.0.1", mirror, repo), "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	digest := digestURL[strings.LastIndex(digestURL, "@")+1:]
+
+	tests := []struct {
+		name     string
+		url      string
+		mirrors  []string
+		expected []string
+	}{
+		{
+			name:     "tag resolved at origin and pulled from mirror",
+			url:      fmt.Sprintf("%s/%s:v0.0.1", origin, repo),
+			mirrors:  []string{mirror},
+			expected: []string{"HEAD " + origin, "GET " + mirror},
+		},
+		{
+			name:     "digest pulled from mirror without contacting origin",
+			url:      fmt.Sprintf("%s/%s@%s", origin, repo, digest),
+			mirrors:  []string{mirror},
+			expected: []string{"GET " + mirror},
+		},
+		{
+			name:     "fallback to origin",
+			url:      fmt.Sprintf("%s/%s@%s", origin, repo, digest),
+			mirrors:  []string{unreachable},
+			expected: []string{"GET " + origin},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			recorder := &hostRecorder{}
+			c := NewLocalClient(WithTransport(recorder), WithMirrors(origin, tt.mirrors...))
+
+			meta, err := c.Pull(ctx, tt.url, t.TempDir())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(meta.Digest).To(Equal(fmt.Sprintf("%s/%s@%s", origin, repo, digest)))
+			g.Expect(recorder.hosts).To(Equal(tt.expected))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"os"
+	"regexp"
+
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	// provenanceStatementType is the in-toto statement type.
+	provenanceStatementType = "https://in-toto.io/Statement/v0.1"
+
+	// provenancePredicateType is the SLSA provenance predicate type.
+	provenancePredicateType = "https://slsa.dev/provenance/v0.2"
+
+	// provenanceBuildType is the build type recorded for artifacts built by this package.
+	provenanceBuildType = "https://fluxcd.io/oci/build/v1"
+
+	// provenanceBuilderID is the builder identity recorded outside of the known CI systems,
+	// it identifies this package instead of the machine the artifact was built on.
+	provenanceBuilderID = "https://fluxcd.io/oci/client"
+)
+
+var sha1Regexp = regexp.MustCompile(`[0-9a-f]{40}$`)
+
+// Provenance is an in-toto statement holding the SLSA provenance of an artifact.
+// Ref: https://slsa.dev/provenance/v0.2
+type Provenance struct {
+	Type          string              `json:"_type"`
+	PredicateType string              `json:"predicateType"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	Predicate     ProvenancePredicate `json:"predicate"`
+}
+
+// ProvenanceSubject identifies the artifact content the provenance refers to.
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ProvenancePredicate describes how the artifact was built.
+type ProvenancePredicate struct {
+	Builder    ProvenanceBuilder    `json:"builder"`
+	BuildType  string               `json:"buildType"`
+	Invocation ProvenanceInvocation `json:"invocation"`
+	Metadata   ProvenanceMetadata   `json:"metadata"`
+	Materials  []ProvenanceMaterial `json:"materials,omitempty"`
+}
+
+// ProvenanceBuilder identifies the entity that built the artifact.
+type ProvenanceBuilder struct {
+	ID string `json:"id"`
+}
+
+// ProvenanceInvocation holds the environment in which the build was invoked.
+type ProvenanceInvocation struct {
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// ProvenanceMetadata holds the build timestamp.
+type ProvenanceMetadata struct {
+	BuildStartedOn string `json:"buildStartedOn,omitempty"`
+}
+
+// ProvenanceMaterial identifies the upstream source of the artifact.
+type ProvenanceMaterial struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// WithPushProvenance configures Push to annotate the artifact with its SLSA provenance.
+// When the builder ID is empty, it is detected from the CI environment, and defaults to an identifier of this package.
+func WithPushProvenance(builderID string) PushOption {
+	return func(o *pushOptions) {
+		o.provenance = true
+		o.builderID = builderID
+	}
+}
+
+// NewProvenance returns the SLSA provenance of the artifact content with the given
+// digest, built from the upstream source described by the metadata.
+// The builder identity and the invocation environment are detected from
+// GitHub Actions and GitLab CI environment variables, an explicit builder ID
+// takes precedence over the detected one.
+func NewProvenance(name string, digest gcrv1.Hash, meta Metadata, builderID string) *Provenance {
+	detectedID, env := detectBuildEnvironment()
+	if builderID == "" {
+		builderID = detectedID
+	}
+
+	p := &Provenance{
+		Type:          provenanceStatementType,
+		PredicateType: provenancePredicateType,
+		Subject: []ProvenanceSubject{
+			{
+				Name:   name,
+				Digest: map[string]string{digest.Algorithm: digest.Hex},
+			},
+		},
+		Predicate: ProvenancePredicate{
+			Builder:    ProvenanceBuilder{ID: builderID},
+			BuildType:  provenanceBuildType,
+			Invocation: ProvenanceInvocation{Environment: env},
+			Metadata:   ProvenanceMetadata{BuildStartedOn: meta.Created},
+		},
+	}
+
+	if meta.Source != "" {
+		material := ProvenanceMaterial{URI: meta.Source}
+		if sha := sha1Regexp.FindString(meta.Revision); sha != "" {
+			material.Digest = map[string]string{"sha1": sha}
+		}
+		p.Predicate.Materials = append(p.Predicate.Materials, material)
+	}
+
+	return p
+}
+
+// detectBuildEnvironment returns the builder ID and the invocation environment
+// of the CI system the process runs in.
+func detectBuildEnvironment() (string, map[string]string) {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		env := lookupEnv("GITHUB_REPOSITORY", "GITHUB_WORKFLOW", "GITHUB_RUN_ID",
+			"GITHUB_RUN_ATTEMPT", "GITHUB_SHA", "GITHUB_REF", "GITHUB_ACTOR")
+		server := os.Getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		return server + "/" + env["GITHUB_REPOSITORY"] + "/actions/runs/" + env["GITHUB_RUN_ID"], env
+	case os.Getenv("GITLAB_CI") == "true":
+		env := lookupEnv("CI_PROJECT_PATH", "CI_PIPELINE_ID", "CI_JOB_ID",
+			"CI_COMMIT_SHA", "CI_COMMIT_REF_NAME", "GITLAB_USER_LOGIN")
+		return os.Getenv("CI_JOB_URL"), env
+	default:
+		return provenanceBuilderID, nil
+	}
+}
+
+// lookupEnv returns the values of the given environment variables that are set.
+func lookupEnv(keys ...string) map[string]string {
+	env := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if val, ok := os.LookupEnv(key); ok {
+			env[key] = val
+		}
+	}
+	return env
+}
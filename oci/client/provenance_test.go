@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/oci"
+)
+
+func TestNewProvenance(t *testing.T) {
+	digest := gcrv1.Hash{Algorithm: "sha256", Hex: "a3b8"}
+	meta := Metadata{
+		Source:   "https://github.com/fluxcd/flux2",
+		Revision: "main/6e2e6b2bd3d4e9c9a7a2b8e8a1b7a4b5d6a1f2c3",
+		Created:  "2022-10-10T10:10:10Z",
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		builderID   string
+		wantBuilder string
+		wantEnv     map[string]string
+	}{
+		{
+			name: "GitHub Actions",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITLAB_CI":         "",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "fluxcd/flux2",
+				"GITHUB_RUN_ID":     "42",
+			},
+			wantBuilder: "https://github.com/fluxcd/flux2/actions/runs/42",
+			wantEnv: map[string]string{
+				"GITHUB_REPOSITORY": "fluxcd/flux2",
+				"GITHUB_RUN_ID":     "42",
+			},
+		},
+		{
+			name: "GitLab CI",
+			env: map[string]string{
+				"GITHUB_ACTIONS":  "",
+				"GITLAB_CI":       "true",
+				"CI_JOB_URL":      "https://gitlab.com/fluxcd/flux2/-/jobs/7",
+				"CI_PROJECT_PATH": "fluxcd/flux2",
+			},
+			wantBuilder: "https://gitlab.com/fluxcd/flux2/-/jobs/7",
+			wantEnv: map[string]string{
+				"CI_PROJECT_PATH": "fluxcd/flux2",
+			},
+		},
+		{
+			name: "local build",
+			env: map[string]string{
+				"GITHUB_ACTIONS": "",
+				"GITLAB_CI":      "",
+			},
+			wantBuilder: provenanceBuilderID,
+		},
+		{
+			name: "explicit builder ID",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITLAB_CI":         "",
+				"GITHUB_REPOSITORY": "fluxcd/flux2",
+			},
+			builderID:   "https://example.com/builder",
+			wantBuilder: "https://example.com/builder",
+			wantEnv: map[string]string{
+				"GITHUB_REPOSITORY": "fluxcd/flux2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			p := NewProvenance("registry/repo", digest, meta, tt.builderID)
+			g.Expect(p.PredicateType).To(Equal(provenancePredicateType))
+			g.Expect(p.Subject).To(HaveLen(1))
+			g.Expect(p.Subject[0].Digest).To(HaveKeyWithValue("sha256", "a3b8"))
+			g.Expect(p.Predicate.Builder.ID).To(Equal(tt.wantBuilder))
+			for k, v := range tt.wantEnv {
+				g.Expect(p.Predicate.Invocation.Environment).To(HaveKeyWithValue(k, v))
+			}
+			g.Expect(p.Predicate.Metadata.BuildStartedOn).To(Equal(meta.Created))
+			g.Expect(p.Predicate.Materials).To(HaveLen(1))
+			g.Expect(p.Predicate.Materials[0].URI).To(Equal(meta.Source))
+			g.Expect(p.Predicate.Materials[0].Digest).To(HaveKeyWithValue("sha1", "6e2e6b2bd3d4e9c9a7a2b8e8a1b7a4b5d6a1f2c3"))
+		})
+	}
+}
+
+func Test_Push_Provenance(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := "test-push-provenance" + randStringRunes(5)
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, repo, "v0.0.1")
+
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushProvenance("https://example.com/builder"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := image.Manifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manifest.Annotations).To(HaveKey(oci.ProvenanceAnnotation))
+
+	var p Provenance
+	g.Expect(json.Unmarshal([]byte(manifest.Annotations[oci.ProvenanceAnnotation]), &p)).To(Succeed())
+	g.Expect(p.Predicate.Builder.ID).To(Equal("https://example.com/builder"))
+	g.Expect(p.Subject[0].Digest).To(HaveKeyWithValue("sha256", manifest.Layers[0].Digest.Hex))
+}
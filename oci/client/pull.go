@@ -17,22 +17,113 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
 
+	"filippo.io/age"
 	"github.com/fluxcd/pkg/tar"
-	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/fluxcd/pkg/oci"
 )
 
+// PullOption is a functional option for configuring Pull.
+type PullOption func(o *pullOptions)
+
+// pullOptions holds the options for Pull.
+type pullOptions struct {
+	// identities holds the age identities used to decrypt encrypted layers.
+	identities []age.Identity
+
+	// checksum holds the expected checksum of the first layer blob.
+	checksum string
+
+	// archive disables the extraction of the artifact layer.
+	archive bool
+
+	// notBefore and notAfter define the accepted creation time window.
+	notBefore time.Time
+	notAfter  time.Time
+
+	// maxAge is the maximum accepted age of the artifact.
+	maxAge time.Duration
+}
+
+// WithPullMaxAge configures Pull to fail with ErrArtifactStale if the artifact
+// created annotation is older than the given age.
+func WithPullMaxAge(maxAge time.Duration) PullOption {
+	return func(o *pullOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithPullTimeWindow configures Pull to fail with ErrArtifactStale if the
+// artifact created annotation is outside the given time window.
+// A zero notBefore or notAfter leaves the window open on that side.
+func WithPullTimeWindow(notBefore, notAfter time.Time) PullOption {
+	return func(o *pullOptions) {
+		o.notBefore = notBefore
+		o.notAfter = notAfter
+	}
+}
+
+// checkFreshness verifies the artifact creation time against the freshness policy.
+func (o *pullOptions) checkFreshness(meta *Metadata) error {
+	if o.maxAge == 0 && o.notBefore.IsZero() && o.notAfter.IsZero() {
+		return nil
+	}
+
+	created, err := time.Parse(time.RFC3339, meta.Created)
+	if err != nil {
+		return fmt.Errorf("parsing '%s' annotation failed: %w", oci.CreatedAnnotation, err)
+	}
+
+	if o.maxAge > 0 && time.Since(created) > o.maxAge {
+		return fmt.Errorf("%w: created at %s, older than %s", ErrArtifactStale, meta.Created, o.maxAge)
+	}
+	if !o.notBefore.IsZero() && created.Before(o.notBefore) {
+		return fmt.Errorf("%w: created at %s, before %s", ErrArtifactStale, meta.Created, o.notBefore.Format(time.RFC3339))
+	}
+	if !o.notAfter.IsZero() && created.After(o.notAfter) {
+		return fmt.Errorf("%w: created at %s, after %s", ErrArtifactStale, meta.Created, o.notAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// WithPullArchive configures Pull to write the artifact layer as a gzip
+// compressed tarball at the given output path instead of extracting it.
+// Encrypted layers are decrypted before being written.
+// Pulling artifacts with more than one layer as an archive is not supported.
+// See PullArchive for the verification of the written archive.
+func WithPullArchive() PullOption {
+	return func(o *pullOptions) {
+		o.archive = true
+	}
+}
+
 // Pull downloads an artifact from an OCI repository and extracts the content to the given directory.
-func (c *Client) Pull(ctx context.Context, url, outDir string) (*Metadata, error) {
+// The artifact layers are extracted in order, the files of the appended layers overriding
+// the ones of the previous layers. The layers which aren't tarballs, e.g. provenance or
+// signature blobs, are skipped.
+func (c *Client) Pull(ctx context.Context, url, outDir string, opts ...PullOption) (*Metadata, error) {
+	o := &pullOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	ref, err := name.ParseReference(url)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	img, err := crane.Pull(url, c.optionsWithContext(ctx)...)
+	img, err := c.pullImage(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -53,23 +144,186 @@ func (c *Client) Pull(ctx context.Context, url, outDir string) (*Metadata, error
 	}
 	meta.Digest = ref.Context().Digest(digest.String()).String()
 
-	layers, err := img.Layers()
+	if err := o.checkFreshness(meta); err != nil {
+		return nil, err
+	}
+
+	layers, err := artifactLayers(img)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list layers: %w", err)
+		return nil, err
 	}
 
 	if len(layers) < 1 {
 		return nil, fmt.Errorf("no layers found in artifact")
 	}
 
-	blob, err := layers[0].Compressed()
-	if err != nil {
-		return nil, fmt.Errorf("extracting first layer failed: %w", err)
+	if o.archive {
+		if len(layers) > 1 {
+			return nil, fmt.Errorf("artifact has %d layers, only single layer artifacts can be pulled as archive", len(layers))
+		}
+		if err := c.writeLayerArchive(layers[0], outDir, o.checksum, o.identities); err != nil {
+			return nil, fmt.Errorf("failed to write layer archive: %w", err)
+		}
+		return meta, nil
 	}
 
-	if err = tar.Untar(blob, outDir, tar.WithMaxUntarSize(-1)); err != nil {
-		return nil, fmt.Errorf("failed to untar first layer: %w", err)
+	for i, layer := range layers {
+		checksum := ""
+		if i == 0 {
+			checksum = o.checksum
+		}
+		if err := c.extractLayer(layer, outDir, checksum, o.identities); err != nil {
+			return nil, fmt.Errorf("failed to extract layer %d: %w", i, err)
+		}
 	}
 
 	return meta, nil
 }
+
+// PullArchive downloads an artifact from an OCI repository and writes its layer
+// as a gzip compressed tarball to the given file, without extracting it.
+// The layer is verified against its digest while being written, the file is
+// created only if the verification succeeds. The WithPullChecksum option can be
+// used to verify the layer against an out-of-band checksum instead.
+func (c *Client) PullArchive(ctx context.Context, url, destFile string, opts ...PullOption) (*Metadata, error) {
+	return c.Pull(ctx, url, destFile, append(opts, WithPullArchive())...)
+}
+
+// artifactLayers returns the layers of the given image holding a gzip compressed
+// tarball, plain or encrypted, in order.
+func artifactLayers(img gcrv1.Image) ([]gcrv1.Layer, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	var result []gcrv1.Layer
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("parsing layer media type failed: %w", err)
+		}
+		switch mediaType {
+		case types.DockerLayer, types.OCILayer, oci.AgeEncryptedLayerMediaType:
+			result = append(result, layer)
+		}
+	}
+	return result, nil
+}
+
+// extractLayer untars the given layer to the output directory.
+func (c *Client) extractLayer(layer gcrv1.Layer, outDir, checksum string, identities []age.Identity) error {
+	return c.readLayer(layer, checksum, identities, func(content io.Reader) error {
+		return tar.Untar(content, outDir, tar.WithMaxUntarSize(-1))
+	})
+}
+
+// writeLayerArchive writes the given layer tarball to the given path. The
+// layer blob is verified while being written against the given checksum,
+// or the layer digest if no checksum is specified, and the file is moved
+// to the given path only if the verification succeeds.
+func (c *Client) writeLayerArchive(layer gcrv1.Layer, path, checksum string, identities []age.Identity) error {
+	if checksum == "" {
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("parsing layer digest failed: %w", err)
+		}
+		checksum = digest.String()
+	}
+
+	h, sum, err := parseChecksum(checksum)
+	if err != nil {
+		return err
+	}
+
+	mediaType, err := layer.MediaType()
+	if err != nil {
+		return fmt.Errorf("parsing layer media type failed: %w", err)
+	}
+
+	blob, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("fetching layer failed: %w", wrapRegistryError(err))
+	}
+	defer blob.Close()
+
+	verified := io.TeeReader(blob, h)
+	content := verified
+	if mediaType == oci.AgeEncryptedLayerMediaType {
+		content, err = decryptReader(verified, identities)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Split(path))
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return fmt.Errorf("downloading layer failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Consume the remainder of the blob, e.g. the trailing bytes not read
+	// by the decryption, so that the whole blob is verified.
+	if _, err := io.Copy(io.Discard, verified); err != nil {
+		return fmt.Errorf("downloading layer failed: %w", err)
+	}
+	if actual := h.Sum(nil); !bytes.Equal(actual, sum) {
+		return fmt.Errorf("checksum mismatch: expected '%s', got '%s'", checksum, formatChecksum(checksum, actual))
+	}
+
+	return os.Rename(tmpName, path)
+}
+
+// readLayer passes the content of the given layer to the given function. If a
+// checksum is specified, the layer blob is verified before being read.
+// Encrypted layers are decrypted with the given identities.
+func (c *Client) readLayer(layer gcrv1.Layer, checksum string, identities []age.Identity, fn func(content io.Reader) error) error {
+	blob, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("fetching layer failed: %w", wrapRegistryError(err))
+	}
+	defer blob.Close()
+
+	mediaType, err := layer.MediaType()
+	if err != nil {
+		return fmt.Errorf("parsing layer media type failed: %w", err)
+	}
+
+	var content io.Reader = blob
+	if checksum != "" {
+		tmpDir, err := c.mkdirTemp("pull")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+
+		f, err := writeVerified(blob, filepath.Join(tmpDir, "layer"), checksum)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		content = f
+	}
+
+	if mediaType == oci.AgeEncryptedLayerMediaType {
+		content, err = decryptReader(content, identities)
+		if err != nil {
+			return err
+		}
+	}
+
+	return fn(content)
+}
@@ -18,27 +18,102 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"filippo.io/age"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	"github.com/fluxcd/pkg/oci"
 )
 
+// PushOption is a functional option for configuring Push.
+type PushOption func(o *pushOptions)
+
+// pushOptions holds the options for Push.
+type pushOptions struct {
+	// recipients holds the age recipients the artifact layer is encrypted for.
+	recipients []age.Recipient
+
+	// provenance enables the SLSA provenance annotation.
+	provenance bool
+
+	// builderID overrides the builder identity recorded in the provenance.
+	builderID string
+
+	// buildOpts holds the options for building the artifact.
+	buildOpts []BuildOption
+
+	// failIfExists forbids overwriting a tag that points to a different artifact.
+	failIfExists bool
+
+	// dryRun skips the upload of the artifact.
+	dryRun bool
+
+	// created overrides the artifact created timestamp, defaults to now.
+	created time.Time
+
+	// signer signs the artifact after upload.
+	signer Signer
+}
+
+// WithPushDryRun configures Push to build the artifact and compute its digest
+// without uploading it to the registry. Combined with WithPushCreated, the
+// returned digest can be compared with the digest of an existing artifact.
+// Note that encrypted artifacts have a different digest on every push.
+func WithPushDryRun() PushOption {
+	return func(o *pushOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithPushCreated configures Push to record the given timestamp as the
+// artifact created time instead of the current time.
+func WithPushCreated(created time.Time) PushOption {
+	return func(o *pushOptions) {
+		o.created = created
+	}
+}
+
+// WithPushFailIfExists configures Push to fail with ErrTagExists if the
+// target tag already resolves to a different digest. Note that the artifact
+// digest includes the created timestamp, hence pushing the same content twice
+// results in different digests.
+func WithPushFailIfExists() PushOption {
+	return func(o *pushOptions) {
+		o.failIfExists = true
+	}
+}
+
+// WithPushBuildOptions configures the options used by Push for building the artifact.
+func WithPushBuildOptions(opts ...BuildOption) PushOption {
+	return func(o *pushOptions) {
+		o.buildOpts = opts
+	}
+}
+
 // Push creates an artifact from the given directory, uploads the artifact
 // to the given OCI repository and returns the digest.
-func (c *Client) Push(ctx context.Context, url, sourceDir string, meta Metadata, ignorePaths []string) (string, error) {
+// When WithPushDryRun is set, the upload is skipped and only the digest is returned.
+func (c *Client) Push(ctx context.Context, url, sourceDir string, meta Metadata, ignorePaths []string, opts ...PushOption) (string, error) {
+	o := &pushOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	ref, err := name.ParseReference(url)
 	if err != nil {
 		return "", fmt.Errorf("invalid URL: %w", err)
 	}
 
-	tmpDir, err := os.MkdirTemp("", "oci")
+	tmpDir, err := c.mkdirTemp("push")
 	if err != nil {
 		return "", err
 	}
@@ -46,27 +121,88 @@ func (c *Client) Push(ctx context.Context, url, sourceDir string, meta Metadata,
 
 	tmpFile := filepath.Join(tmpDir, "artifact.tgz")
 
-	if err := c.Build(tmpFile, sourceDir, ignorePaths); err != nil {
+	if err := c.build(ctx, tmpFile, sourceDir, ignorePaths, o.buildOpts...); err != nil {
 		return "", err
 	}
 
-	img, err := crane.Append(empty.Image, tmpFile)
-	if err != nil {
-		return "", fmt.Errorf("appeding content to artifact failed: %w", err)
+	var img gcrv1.Image
+	if len(o.recipients) > 0 {
+		encryptedFile := tmpFile + ".age"
+		if err := encryptFile(tmpFile, encryptedFile, o.recipients); err != nil {
+			return "", err
+		}
+
+		layer, err := newFileLayer(encryptedFile, oci.AgeEncryptedLayerMediaType)
+		if err != nil {
+			return "", fmt.Errorf("creating encrypted layer failed: %w", err)
+		}
+
+		img, err = mutate.AppendLayers(empty.Image, layer)
+		if err != nil {
+			return "", fmt.Errorf("appeding content to artifact failed: %w", err)
+		}
+	} else {
+		img, err = crane.Append(empty.Image, tmpFile)
+		if err != nil {
+			return "", fmt.Errorf("appeding content to artifact failed: %w", err)
+		}
 	}
 
-	ct := time.Now()
+	ct := o.created
+	if ct.IsZero() {
+		ct = time.Now()
+	}
 	meta.Created = ct.Format(time.RFC3339)
-	img = mutate.Annotations(img, meta.ToAnnotations()).(gcrv1.Image)
+	annotations := c.artifactAnnotations(meta)
 
-	if err := crane.Push(img, url, c.optionsWithContext(ctx)...); err != nil {
-		return "", fmt.Errorf("pushing artifact failed: %w", err)
+	if o.provenance {
+		layers, err := img.Layers()
+		if err != nil {
+			return "", fmt.Errorf("listing artifact layers failed: %w", err)
+		}
+		layerDigest, err := layers[0].Digest()
+		if err != nil {
+			return "", fmt.Errorf("parsing layer digest failed: %w", err)
+		}
+
+		provenance, err := json.Marshal(NewProvenance(ref.Context().Name(), layerDigest, meta, o.builderID))
+		if err != nil {
+			return "", fmt.Errorf("encoding provenance failed: %w", err)
+		}
+		annotations[oci.ProvenanceAnnotation] = string(provenance)
 	}
 
+	img = mutate.Annotations(img, annotations).(gcrv1.Image)
+
 	digest, err := img.Digest()
 	if err != nil {
 		return "", fmt.Errorf("parsing artifact digest failed: %w", err)
 	}
 
-	return ref.Context().Digest(digest.String()).String(), err
+	if o.dryRun {
+		return ref.Context().Digest(digest.String()).String(), nil
+	}
+
+	if o.failIfExists {
+		existing, err := crane.Digest(url, c.optionsWithContext(ctx)...)
+		switch {
+		case err == nil && existing != digest.String():
+			return "", fmt.Errorf("%w: '%s' points to '%s'", ErrTagExists, url, existing)
+		case err != nil && !isNotFound(err):
+			return "", fmt.Errorf("resolving existing tag failed: %w", wrapRegistryError(err))
+		}
+	}
+
+	if err := crane.Push(img, url, c.optionsWithContext(ctx)...); err != nil {
+		return "", fmt.Errorf("pushing artifact failed: %w", wrapRegistryError(err))
+	}
+
+	digestURL := ref.Context().Digest(digest.String()).String()
+	if o.signer != nil {
+		if _, err := c.Sign(ctx, digestURL, o.signer); err != nil {
+			return digestURL, fmt.Errorf("signing artifact failed: %w", err)
+		}
+	}
+
+	return digestURL, nil
 }
@@ -18,13 +18,20 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"filippo.io/age"
+	"github.com/fluxcd/pkg/tar"
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
 	. "github.com/onsi/gomega"
 
 	"github.com/fluxcd/pkg/oci"
@@ -75,3 +82,303 @@ func Test_Push_Pull(t *testing.T) {
 		return nil
 	})
 }
+
+func Test_Pull_MixedLayers(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-pull-mixed"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Attach a layer which isn't a tarball, e.g. a provenance file.
+	img, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	img, err = mutate.AppendLayers(img, static.NewLayer([]byte("provenance"), oci.HelmChartProvenanceMediaType))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crane.Push(img, url)).To(Succeed())
+
+	tmpDir := t.TempDir()
+	_, err = c.Pull(ctx, url, tmpDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(filepath.Join(tmpDir, "testdata/artifact/deployment.yaml")).To(BeARegularFile())
+
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	_, err = c.PullArchive(ctx, url, archivePath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(archivePath).To(BeARegularFile())
+}
+
+func Test_Push_Pull_Encrypted(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	tag := "v0.0.1"
+	repo := "test-push-encrypted" + randStringRunes(5)
+
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, repo, tag)
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	testDir := "testdata/artifact"
+	_, err = c.Push(ctx, url, testDir, metadata, nil, WithPushEncryption(identity.Recipient()))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	image, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	layers, err := image.Layers()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(layers).To(HaveLen(1))
+
+	mediaType, err := layers[0].MediaType()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(mediaType)).To(Equal(oci.AgeEncryptedLayerMediaType))
+
+	_, err = c.Pull(ctx, url, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+
+	otherIdentity, err := age.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = c.Pull(ctx, url, t.TempDir(), WithPullDecryption(otherIdentity))
+	g.Expect(err).To(HaveOccurred())
+
+	tmpDir := t.TempDir()
+	_, err = c.Pull(ctx, url, tmpDir, WithPullDecryption(identity))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = filepath.Walk(testDir, func(path string, info fs.FileInfo, err error) error {
+		tmpPath := filepath.Join(tmpDir, path)
+		if _, err := os.Stat(tmpPath); err != nil && os.IsNotExist(err) {
+			return fmt.Errorf("path '%s' doesn't exist in archive", path)
+		}
+
+		return nil
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func Test_Push_FailIfExists(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := "test-push-immutable" + randStringRunes(5)
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, repo, "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	digest, err := c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushFailIfExists())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	metadata.Revision = "rev2"
+	_, err = c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushFailIfExists())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(errors.Is(err, ErrTagExists)).To(BeTrue())
+
+	existing, err := crane.Digest(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(digest).To(HaveSuffix(existing))
+
+	_, err = c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func Test_Push_DryRun(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-push-dry-run"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+	created := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	dryRunDigest, err := c.Push(ctx, url, "testdata/artifact", metadata, nil,
+		WithPushDryRun(), WithPushCreated(created))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = crane.Digest(url)
+	g.Expect(err).To(HaveOccurred())
+
+	digest, err := c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushCreated(created))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(digest).To(Equal(dryRunDigest))
+
+	meta, err := c.Pull(ctx, url, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(meta.Created).To(Equal(created.Format(time.RFC3339)))
+}
+
+func Test_Pull_Archive(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-pull-archive"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tmpDir := t.TempDir()
+	archivePath := filepath.Join(tmpDir, "out", "artifact.tar.gz")
+	_, err = c.Pull(ctx, url, archivePath, WithPullArchive())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	f, err := os.Open(archivePath)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	untarDir := t.TempDir()
+	g.Expect(tar.Untar(f, untarDir, tar.WithMaxUntarSize(-1))).To(Succeed())
+	g.Expect(filepath.Join(untarDir, "testdata/artifact/deployment.yaml")).To(BeARegularFile())
+}
+
+func Test_Push_DefaultAnnotations(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	defaults := map[string]string{
+		"io.fluxcd.team":        "platform",
+		"io.fluxcd.environment": "staging",
+		oci.SourceAnnotation:    "default-source",
+	}
+	c := NewLocalClient(WithDefaultAnnotations(defaults))
+	// Changing the map after the Client is created has no effect.
+	defaults["io.fluxcd.team"] = "changed"
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-push-annotations"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+		Annotations: map[string]string{
+			"io.fluxcd.environment": "production",
+		},
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := crane.Manifest(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring(`"io.fluxcd.team":"platform"`))
+	g.Expect(string(manifest)).To(ContainSubstring(`"io.fluxcd.environment":"production"`))
+	g.Expect(string(manifest)).To(ContainSubstring(`"org.opencontainers.image.source":"github.com/fluxcd/flux2"`))
+}
+
+func Test_Pull_Freshness(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-pull-freshness"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+	created := time.Now().Add(-2 * time.Hour).UTC()
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushCreated(created))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tests := []struct {
+		name    string
+		opts    []PullOption
+		wantErr bool
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name: "within max age",
+			opts: []PullOption{WithPullMaxAge(3 * time.Hour)},
+		},
+		{
+			name:    "older than max age",
+			opts:    []PullOption{WithPullMaxAge(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name: "within time window",
+			opts: []PullOption{WithPullTimeWindow(created.Add(-time.Minute), created.Add(time.Minute))},
+		},
+		{
+			name:    "before time window",
+			opts:    []PullOption{WithPullTimeWindow(created.Add(time.Minute), time.Time{})},
+			wantErr: true,
+		},
+		{
+			name:    "after time window",
+			opts:    []PullOption{WithPullTimeWindow(time.Time{}, created.Add(-time.Minute))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			tmpDir := t.TempDir()
+			_, err := c.Pull(ctx, url, tmpDir, tt.opts...)
+			if tt.wantErr {
+				g.Expect(errors.Is(err, ErrArtifactStale)).To(BeTrue())
+				entries, err := os.ReadDir(tmpDir)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(entries).To(BeEmpty())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func Test_PullArchive(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-pull-archive-file"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	_, err := c.Push(ctx, url, "testdata/artifact", metadata, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	img, err := crane.Pull(url)
+	g.Expect(err).ToNot(HaveOccurred())
+	layers, err := img.Layers()
+	g.Expect(err).ToNot(HaveOccurred())
+	layerDigest, err := layers[0].Digest()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	destFile := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	meta, err := c.PullArchive(ctx, url, destFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(meta.Revision).To(Equal("rev"))
+
+	b, err := os.ReadFile(destFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fmt.Sprintf("sha256:%x", sha256.Sum256(b))).To(Equal(layerDigest.String()))
+
+	mismatchFile := filepath.Join(t.TempDir(), "mismatch.tar.gz")
+	_, err = c.PullArchive(ctx, url, mismatchFile,
+		WithPullChecksum("sha256:0000000000000000000000000000000000000000000000000000000000000000"))
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("checksum mismatch"))
+	g.Expect(mismatchFile).ToNot(BeAnExistingFile())
+	entries, err := os.ReadDir(filepath.Dir(mismatchFile))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
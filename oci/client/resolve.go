@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Resolve returns the digest and the manifest size of the artifact the given
+// url points to. Only the manifest descriptor is fetched from the registry with
+// a HEAD request, which makes Resolve suitable for polling for changes.
+func (c *Client) Resolve(ctx context.Context, url string) (string, int64, error) {
+	if _, err := name.ParseReference(url); err != nil {
+		return "", 0, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	desc, err := crane.Head(url, c.optionsWithContext(ctx)...)
+	if err != nil {
+		return "", 0, fmt.Errorf("resolving artifact failed: %w", wrapRegistryError(err))
+	}
+
+	return desc.Digest.String(), desc.Size, nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/gomega"
+)
+
+func Test_Resolve(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	recorder := &hostRecorder{}
+	c := NewLocalClient(WithTransport(recorder))
+	url := fmt.Sprintf("%s/%s:v0.0.1", dockerReg, "test-resolve"+randStringRunes(5))
+
+	img, err := random.Image(1024, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crane.Push(img, url)).To(Succeed())
+
+	expectedDigest, err := img.Digest()
+	g.Expect(err).ToNot(HaveOccurred())
+	expectedSize, err := img.Size()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	digest, size, err := c.Resolve(ctx, url)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(digest).To(Equal(expectedDigest.String()))
+	g.Expect(size).To(Equal(expectedSize))
+	g.Expect(recorder.hosts).To(Equal([]string{"HEAD " + dockerReg}))
+
+	_, _, err = c.Resolve(ctx, fmt.Sprintf("%s/%s:v0.0.1", dockerReg, "test-resolve-not-found"))
+	g.Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+}
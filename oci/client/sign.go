@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/fluxcd/pkg/oci"
+)
+
+// Signer signs the artifacts pushed by the Client, it allows plugging in
+// KMS or HSM backed keys and in-house signing services.
+type Signer interface {
+	// Sign returns the signature of the given payload. The payload is the
+	// simple signing document holding the artifact digest.
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+
+	// CertificateChain returns the certificates of the signing identity,
+	// starting with the signing certificate. Signers using bare keys
+	// return an empty chain.
+	CertificateChain() ([][]byte, error)
+}
+
+// simpleSigning is the payload format signed by cosign compatible signers.
+type simpleSigning struct {
+	Critical simpleSigningCritical `json:"critical"`
+	Optional map[string]string     `json:"optional"`
+}
+
+type simpleSigningCritical struct {
+	Identity simpleSigningIdentity `json:"identity"`
+	Image    simpleSigningImage    `json:"image"`
+	Type     string                `json:"type"`
+}
+
+type simpleSigningIdentity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+type simpleSigningImage struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// WithPushSigner configures Push to sign the artifact with the given Signer
+// and to attach the signature to the artifact. If signing fails, Push returns
+// the digest URL of the uploaded artifact along with the error.
+func WithPushSigner(signer Signer) PushOption {
+	return func(o *pushOptions) {
+		o.signer = signer
+	}
+}
+
+// Sign signs the artifact with the given digest URL using the given Signer and
+// attaches the signature to the artifact in the cosign format, by pushing it
+// to the '<repository>:<alg>-<hex>.sig' tag. It returns the signature URL.
+func (c *Client) Sign(ctx context.Context, url string, signer Signer) (string, error) {
+	ref, err := name.NewDigest(url)
+	if err != nil {
+		return "", fmt.Errorf("invalid digest URL: %w", err)
+	}
+
+	payload, err := json.Marshal(simpleSigning{
+		Critical: simpleSigningCritical{
+			Identity: simpleSigningIdentity{DockerReference: ref.Context().Name()},
+			Image:    simpleSigningImage{DockerManifestDigest: ref.DigestStr()},
+			Type:     "cosign container image signature",
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding signature payload failed: %w", err)
+	}
+
+	signature, err := signer.Sign(ctx, payload)
+	if err != nil {
+		return "", fmt.Errorf("signing artifact failed: %w", err)
+	}
+
+	annotations := map[string]string{
+		oci.CosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature),
+	}
+
+	chain, err := signer.CertificateChain()
+	if err != nil {
+		return "", fmt.Errorf("reading certificate chain failed: %w", err)
+	}
+	if len(chain) > 0 {
+		annotations[oci.CosignCertificateAnnotation] = string(encodeCertificate(chain[0]))
+		var certs bytes.Buffer
+		for _, cert := range chain[1:] {
+			certs.Write(encodeCertificate(cert))
+		}
+		if certs.Len() > 0 {
+			annotations[oci.CosignChainAnnotation] = certs.String()
+		}
+	}
+
+	sigURL := ref.Context().Tag(strings.Replace(ref.DigestStr(), ":", "-", 1) + oci.CosignSignatureTagSuffix).String()
+
+	// Append the signature to the existing ones, if any.
+	var base gcrv1.Image = empty.Image
+	if existing, err := crane.Pull(sigURL, c.optionsWithContext(ctx)...); err == nil {
+		base = existing
+	} else if !isNotFound(err) {
+		return "", fmt.Errorf("pulling existing signatures failed: %w", wrapRegistryError(err))
+	}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer:       static.NewLayer(payload, oci.CosignSimpleSigningMediaType),
+		Annotations: annotations,
+	})
+	if err != nil {
+		return "", fmt.Errorf("appending signature failed: %w", err)
+	}
+	img = mutate.ConfigMediaType(img, types.OCIConfigJSON)
+
+	if err := crane.Push(img, sigURL, c.optionsWithContext(ctx)...); err != nil {
+		return "", fmt.Errorf("pushing signature failed: %w", wrapRegistryError(err))
+	}
+
+	return sigURL, nil
+}
+
+// encodeCertificate returns the PEM encoding of the given DER certificate.
+func encodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/oci"
+)
+
+type testSigner struct {
+	key  *ecdsa.PrivateKey
+	cert []byte
+}
+
+func (s *testSigner) Sign(_ context.Context, payload []byte) ([]byte, error) {
+	h := sha256.Sum256(payload)
+	return ecdsa.SignASN1(rand.Reader, s.key, h[:])
+}
+
+func (s *testSigner) CertificateChain() ([][]byte, error) {
+	return [][]byte{s.cert}, nil
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign(context.Context, []byte) ([]byte, error) {
+	return nil, errors.New("key unavailable")
+}
+
+func (failingSigner) CertificateChain() ([][]byte, error) {
+	return nil, nil
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	g := NewWithT(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "flux"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return &testSigner{key: key, cert: cert}
+}
+
+func Test_Push_Signer(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	signer := newTestSigner(t)
+	repo := fmt.Sprintf("%s/%s", dockerReg, "test-push-signer"+randStringRunes(5))
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	digestURL, err := c.Push(ctx, repo+":v0.0.1", "testdata/artifact", metadata, nil, WithPushSigner(signer))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	digest := digestURL[strings.LastIndex(digestURL, "@")+1:]
+	sigURL := fmt.Sprintf("%s:%s.sig", repo, strings.Replace(digest, ":", "-", 1))
+
+	// Signing again appends a second signature.
+	_, err = c.Sign(ctx, digestURL, signer)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	sig, err := crane.Pull(sigURL)
+	g.Expect(err).ToNot(HaveOccurred())
+	manifest, err := sig.Manifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manifest.Layers).To(HaveLen(2))
+
+	layers, err := sig.Layers()
+	g.Expect(err).ToNot(HaveOccurred())
+	for i, layer := range layers {
+		desc := manifest.Layers[i]
+		g.Expect(string(desc.MediaType)).To(Equal(oci.CosignSimpleSigningMediaType))
+
+		rc, err := layer.Uncompressed()
+		g.Expect(err).ToNot(HaveOccurred())
+		payload, err := io.ReadAll(rc)
+		g.Expect(err).ToNot(HaveOccurred())
+		rc.Close()
+
+		var doc simpleSigning
+		g.Expect(json.Unmarshal(payload, &doc)).To(Succeed())
+		g.Expect(doc.Critical.Image.DockerManifestDigest).To(Equal(digest))
+
+		signature, err := base64.StdEncoding.DecodeString(desc.Annotations[oci.CosignSignatureAnnotation])
+		g.Expect(err).ToNot(HaveOccurred())
+		h := sha256.Sum256(payload)
+		g.Expect(ecdsa.VerifyASN1(&signer.key.PublicKey, h[:], signature)).To(BeTrue())
+
+		block, _ := pem.Decode([]byte(desc.Annotations[oci.CosignCertificateAnnotation]))
+		g.Expect(block).ToNot(BeNil())
+		g.Expect(block.Bytes).To(Equal(signer.cert))
+	}
+}
+
+func Test_Push_SignerFailure(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	url := fmt.Sprintf("%s/%s:%s", dockerReg, "test-push-signer-failure"+randStringRunes(5), "v0.0.1")
+	metadata := Metadata{
+		Source:   "github.com/fluxcd/flux2",
+		Revision: "rev",
+	}
+
+	digestURL, err := c.Push(ctx, url, "testdata/artifact", metadata, nil, WithPushSigner(failingSigner{}))
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("signing artifact failed: key unavailable"))
+	g.Expect(digestURL).ToNot(BeEmpty())
+
+	_, err = crane.Pull(digestURL)
+	g.Expect(err).ToNot(HaveOccurred())
+}
@@ -32,7 +32,7 @@ func (c *Client) Tag(ctx context.Context, url, tag string) (string, error) {
 	}
 
 	if err := crane.Tag(url, tag, c.optionsWithContext(ctx)...); err != nil {
-		return "", err
+		return "", wrapRegistryError(err)
 	}
 
 	dst := ref.Context().Tag(tag)
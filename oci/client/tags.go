@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// errStopWalk is used to stop the tags pagination.
+var errStopWalk = errors.New("stop walking tags")
+
+// TagsOption is a functional option for configuring Tags and WalkTags.
+type TagsOption func(o *tagsOptions)
+
+// tagsOptions holds the options for Tags and WalkTags.
+type tagsOptions struct {
+	// limit caps the number of tags returned, zero means no limit.
+	limit int
+
+	// pageSize is the number of tags requested per page.
+	pageSize int
+}
+
+// WithTagsLimit configures the maximum number of tags returned,
+// the remaining pages are not requested once the limit is reached.
+func WithTagsLimit(limit int) TagsOption {
+	return func(o *tagsOptions) {
+		o.limit = limit
+	}
+}
+
+// WithTagsPageSize configures the number of tags requested per page,
+// defaults to 1000. Registries may return smaller pages.
+func WithTagsPageSize(size int) TagsOption {
+	return func(o *tagsOptions) {
+		o.pageSize = size
+	}
+}
+
+// Tags returns the tags of the given OCI repository in the order returned
+// by the registry, following the pagination Link headers.
+func (c *Client) Tags(ctx context.Context, url string, opts ...TagsOption) ([]string, error) {
+	var tags []string
+	err := c.WalkTags(ctx, url, func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// WalkTags calls fn for each tag of the given OCI repository as the pages are
+// received from the registry, without holding the whole list in memory.
+// If fn returns an error, the walk stops and the error is returned.
+func (c *Client) WalkTags(ctx context.Context, url string, fn func(tag string) error, opts ...TagsOption) error {
+	o := &tagsOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	repo, err := name.NewRepository(url)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	walker := &tagsWalker{
+		base:  c.baseTransport(),
+		path:  fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+		limit: o.limit,
+		fn:    fn,
+	}
+
+	options := append(c.optionsWithContext(ctx), crane.WithTransport(walker))
+	if o.pageSize > 0 {
+		options = append(options, func(co *crane.Options) {
+			co.Remote = append(co.Remote, remote.WithPageSize(o.pageSize))
+		})
+	}
+
+	_, err = crane.ListTags(url, options...)
+	if walker.err != nil && walker.err != errStopWalk {
+		return walker.err
+	}
+	if err != nil {
+		return fmt.Errorf("listing tags failed: %w", wrapRegistryError(err))
+	}
+	return nil
+}
+
+// tagsWalker is an HTTP transport which passes the tags of each page to a
+// function and ends the pagination when the function fails or the limit is
+// reached. The tags are removed from the page, so that the caller doesn't
+// accumulate them.
+type tagsWalker struct {
+	base  http.RoundTripper
+	path  string
+	limit int
+	fn    func(tag string) error
+
+	count int
+	err   error
+}
+
+// tagsPage is the response body of the tags list API.
+type tagsPage struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func (w *tagsWalker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := w.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.HasSuffix(req.URL.Path, w.path) {
+		return resp, err
+	}
+
+	var page tagsPage
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("decoding tags page failed: %w", err)
+	}
+
+	for _, tag := range page.Tags {
+		if w.err != nil {
+			break
+		}
+		if w.limit > 0 && w.count >= w.limit {
+			w.err = errStopWalk
+			break
+		}
+		if err := w.fn(tag); err != nil {
+			w.err = err
+			break
+		}
+		w.count++
+	}
+	if w.err == nil && w.limit > 0 && w.count >= w.limit {
+		w.err = errStopWalk
+	}
+	if w.err != nil {
+		resp.Header.Del("Link")
+	}
+
+	body, err := json.Marshal(tagsPage{Name: page.Name, Tags: []string{}})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/gomega"
+)
+
+func TestTags(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := NewLocalClient()
+	repo := fmt.Sprintf("%s/%s", dockerReg, "test-tags"+randStringRunes(5))
+
+	tags := []string{"v0.0.1", "v0.0.2", "v0.0.3", "v0.0.4", "v0.0.5"}
+	img, err := random.Image(1024, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, tag := range tags {
+		g.Expect(crane.Push(img, repo+":"+tag, c.options...)).To(Succeed())
+	}
+
+	tests := []struct {
+		name     string
+		opts     []TagsOption
+		expected []string
+	}{
+		{
+			name:     "single page",
+			expected: tags,
+		},
+		{
+			name:     "multiple pages",
+			opts:     []TagsOption{WithTagsPageSize(2)},
+			expected: tags,
+		},
+		{
+			name:     "limit within page",
+			opts:     []TagsOption{WithTagsLimit(1)},
+			expected: tags[:1],
+		},
+		{
+			name:     "limit across pages",
+			opts:     []TagsOption{WithTagsPageSize(2), WithTagsLimit(3)},
+			expected: tags[:3],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := c.Tags(ctx, repo, tt.opts...)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.expected))
+		})
+	}
+
+	t.Run("walk stops on error", func(t *testing.T) {
+		g := NewWithT(t)
+		stop := errors.New("stop")
+		var walked []string
+		err := c.WalkTags(ctx, repo, func(tag string) error {
+			if len(walked) == 2 {
+				return stop
+			}
+			walked = append(walked, tag)
+			return nil
+		}, WithTagsPageSize(2))
+		g.Expect(err).To(MatchError(stop))
+		g.Expect(walked).To(Equal(tags[:2]))
+	})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// maxThrottleBurst is the maximum number of bytes transferred at once
+// when the bandwidth is limited.
+const maxThrottleBurst = 32 * 1024
+
+// WithBandwidthLimit configures the Client to cap the bandwidth of the registry
+// calls to the given number of bytes per second. The uploads and the downloads
+// are limited separately, the limit is shared by the concurrent transfers.
+func WithBandwidthLimit(bytesPerSecond int) ClientOption {
+	return func(c *Client) {
+		if bytesPerSecond <= 0 {
+			c.bandwidth = nil
+			return
+		}
+		burst := bytesPerSecond
+		if burst > maxThrottleBurst {
+			burst = maxThrottleBurst
+		}
+		c.bandwidth = &bandwidthLimiter{
+			upload:   rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+			download: rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+		}
+	}
+}
+
+// bandwidthLimiter holds the rate limiters for the uploads and downloads.
+type bandwidthLimiter struct {
+	upload   *rate.Limiter
+	download *rate.Limiter
+}
+
+// throttledTransport is an HTTP transport which rate limits the request
+// and response bodies.
+type throttledTransport struct {
+	base      http.RoundTripper
+	bandwidth *bandwidthLimiter
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = newThrottledReader(ctx, req.Body, t.bandwidth.upload)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return newThrottledReader(ctx, body, t.bandwidth.upload), nil
+			}
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = newThrottledReader(ctx, resp.Body, t.bandwidth.download)
+	return resp, nil
+}
+
+// throttledReader waits for the rate limiter after each read.
+type throttledReader struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func newThrottledReader(ctx context.Context, rc io.ReadCloser, limiter *rate.Limiter) *throttledReader {
+	return &throttledReader{ctx: ctx, rc: rc, limiter: limiter}
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *throttledReader) Close() error {
+	return r.rc.Close()
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestClient_WithBandwidthLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("a"), 32*1024)
+	var uploaded int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		uploaded = len(b)
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	c := NewLocalClient(WithBandwidthLimit(16 * 1024))
+	client := &http.Client{Transport: c.baseTransport()}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, srv.URL, bytes.NewReader(data))
+	g.Expect(err).ToNot(HaveOccurred())
+	resp, err := client.Do(req)
+	g.Expect(err).ToNot(HaveOccurred())
+	b, err := io.ReadAll(resp.Body)
+	g.Expect(err).ToNot(HaveOccurred())
+	resp.Body.Close()
+
+	g.Expect(uploaded).To(Equal(len(data)))
+	g.Expect(b).To(Equal(data))
+	// The first 16KiB of each direction are sent in a burst,
+	// the remaining 16KiB take a second in each direction.
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 1900*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = client.Do(req)
+	g.Expect(err).To(HaveOccurred())
+}
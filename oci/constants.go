@@ -49,9 +49,54 @@ const (
 	// the date and time on which the OCI artifact was built (RFC 3339).
 	CreatedAnnotation = "org.opencontainers.image.created"
 
+	// ProvenanceAnnotation is the annotation for specifying the SLSA
+	// provenance of an OCI artifact as a JSON encoded in-toto statement.
+	ProvenanceAnnotation = "io.fluxcd.provenance"
+
 	// OCIRepositoryPrefix is the prefix used for OCIRepository URLs.
 	OCIRepositoryPrefix = "oci://"
 
 	// UserAgent string used for OCI calls.
 	UserAgent = "flux/v2"
 )
+
+const (
+	// AgeEncryptedLayerMediaType is the media type of an artifact layer
+	// holding a gzip compressed tarball encrypted with age.
+	// Ref: https://age-encryption.org/v1
+	AgeEncryptedLayerMediaType = "application/vnd.oci.image.layer.v1.tar+gzip+age"
+
+	// HelmConfigMediaType is the media type of the config blob of a Helm chart,
+	// holding the chart metadata.
+	// Ref: https://helm.sh/docs/topics/registries/
+	HelmConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+
+	// HelmChartContentMediaType is the media type of the layer holding a Helm chart archive.
+	HelmChartContentMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+	// HelmChartProvenanceMediaType is the media type of the layer holding a Helm chart provenance file.
+	HelmChartProvenanceMediaType = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+)
+
+const (
+	// CosignSimpleSigningMediaType is the media type of the signature layer
+	// holding the signed payload in the simple signing format.
+	// Ref: https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md
+	CosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	// CosignSignatureAnnotation is the signature layer annotation holding
+	// the base64 encoded signature of the payload.
+	CosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// CosignCertificateAnnotation is the signature layer annotation holding
+	// the PEM encoded signing certificate.
+	CosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+
+	// CosignChainAnnotation is the signature layer annotation holding
+	// the PEM encoded certificate chain of the signing certificate.
+	CosignChainAnnotation = "dev.sigstore.cosign/chain"
+
+	// CosignSignatureTagSuffix is the suffix of the tag holding the signatures
+	// of an artifact, the tag is derived from the artifact digest.
+	CosignSignatureTagSuffix = ".sig"
+)
@@ -18,10 +18,13 @@ limitations under the License.
 package ssa
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/yaml"
 )
 
 // Action represents the action type performed by the reconciliation process.
@@ -33,11 +36,16 @@ const (
 	UnchangedAction  Action = "unchanged"
 	DeletedAction    Action = "deleted"
 	UnknownAction    Action = "unknown"
+
+	// SkippedAction is reported for the objects filtered out by the ignore annotation or the
+	// label selector, and for the objects whose kinds are not served by the API server,
+	// when ApplyAll is configured to skip the missing kinds.
+	SkippedAction Action = "skipped"
 )
 
 // ChangeSet holds the result of the reconciliation of an object collection.
 type ChangeSet struct {
-	Entries []ChangeSetEntry
+	Entries []ChangeSetEntry `json:"entries"`
 }
 
 // NewChangeSet returns a ChangeSet will an empty slice of entries.
@@ -63,6 +71,38 @@ func (c *ChangeSet) String() string {
 	return strings.TrimSuffix(b.String(), "\n")
 }
 
+// ToJSON returns the JSON representation of the ChangeSet, e.g. for audit logs.
+func (c *ChangeSet) ToJSON() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// ToYAML returns the YAML representation of the ChangeSet, with the same field names as ToJSON.
+func (c *ChangeSet) ToYAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// ParseChangeSet decodes a ChangeSet from its JSON or YAML representation.
+func ParseChangeSet(data []byte) (*ChangeSet, error) {
+	changeSet := NewChangeSet()
+	if err := yaml.Unmarshal(data, changeSet); err != nil {
+		return nil, fmt.Errorf("change set decode failed, error: %w", err)
+	}
+	if changeSet.Entries == nil {
+		changeSet.Entries = []ChangeSetEntry{}
+	}
+	return changeSet, nil
+}
+
+// setRevision sets the revision of the entries, if not empty.
+func (c *ChangeSet) setRevision(revision string) {
+	if c == nil || revision == "" {
+		return
+	}
+	for i := range c.Entries {
+		c.Entries[i].Revision = revision
+	}
+}
+
 func (c *ChangeSet) ToMap() map[string]string {
 	res := make(map[string]string, len(c.Entries))
 	for _, entry := range c.Entries {
@@ -79,6 +119,24 @@ func (c *ChangeSet) ToObjMetadataSet() object.ObjMetadataSet {
 	return res
 }
 
+// StaleEntries returns the entries of the previous ChangeSet whose objects are missing
+// from the current ChangeSet. No entries are returned if either ChangeSet is nil, as a nil
+// current ChangeSet is the result of a failed apply rather than an empty set of objects.
+func StaleEntries(previous, current *ChangeSet) []ChangeSetEntry {
+	if previous == nil || current == nil {
+		return nil
+	}
+
+	currentSet := current.ToObjMetadataSet()
+	var res []ChangeSetEntry
+	for _, entry := range previous.Entries {
+		if !currentSet.Contains(entry.ObjMetadata) {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
 // ChangeSetEntry defines the result of an action performed on an object.
 type ChangeSetEntry struct {
 	// ObjMetadata holds the unique identifier of this entry.
@@ -92,6 +150,63 @@ type ChangeSetEntry struct {
 
 	// Action represents the action type taken by the reconciler for this object.
 	Action string
+
+	// Revision is the source revision of the object, set from the apply or delete options.
+	Revision string
+}
+
+// changeSetEntryJSON is the serialized form of a ChangeSetEntry.
+type changeSetEntryJSON struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Subject   string `json:"subject"`
+	Action    string `json:"action"`
+	Revision  string `json:"revision,omitempty"`
+}
+
+// MarshalJSON encodes the entry with stable field names, e.g.
+// '{"group":"apps","version":"v1","kind":"Deployment","namespace":"default","name":"app",...}'.
+func (e ChangeSetEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(changeSetEntryJSON{
+		Group:     e.ObjMetadata.GroupKind.Group,
+		Version:   e.GroupVersion,
+		Kind:      e.ObjMetadata.GroupKind.Kind,
+		Namespace: e.ObjMetadata.Namespace,
+		Name:      e.ObjMetadata.Name,
+		Subject:   e.Subject,
+		Action:    e.Action,
+		Revision:  e.Revision,
+	})
+}
+
+// UnmarshalJSON decodes an entry encoded with MarshalJSON.
+func (e *ChangeSetEntry) UnmarshalJSON(data []byte) error {
+	var entry changeSetEntryJSON
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	if entry.Kind == "" || entry.Name == "" {
+		return fmt.Errorf("invalid change set entry, kind and name are required")
+	}
+
+	*e = ChangeSetEntry{
+		ObjMetadata: object.ObjMetadata{
+			Namespace: entry.Namespace,
+			Name:      entry.Name,
+			GroupKind: schema.GroupKind{Group: entry.Group, Kind: entry.Kind},
+		},
+		GroupVersion: entry.Version,
+		Subject:      entry.Subject,
+		Action:       entry.Action,
+		Revision:     entry.Revision,
+	}
+	if e.Subject == "" {
+		e.Subject = FmtObjMetadata(e.ObjMetadata)
+	}
+	return nil
 }
 
 func (e ChangeSetEntry) String() string {
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestStaleEntries(t *testing.T) {
+	newEntry := func(kind, name string) ChangeSetEntry {
+		id := object.ObjMetadata{
+			GroupKind: schema.GroupKind{Kind: kind},
+			Namespace: "default",
+			Name:      name,
+		}
+		return ChangeSetEntry{
+			ObjMetadata:  id,
+			GroupVersion: "v1",
+			Subject:      FmtObjMetadata(id),
+			Action:       string(CreatedAction),
+		}
+	}
+
+	previous := NewChangeSet()
+	previous.Add(newEntry("ConfigMap", "app"))
+	previous.Add(newEntry("ConfigMap", "db"))
+	previous.Add(newEntry("Secret", "db"))
+
+	current := NewChangeSet()
+	current.Add(newEntry("ConfigMap", "app"))
+	current.Add(newEntry("Service", "app"))
+
+	var stale []string
+	for _, entry := range StaleEntries(previous, current) {
+		stale = append(stale, entry.Subject)
+	}
+
+	expected := []string{"ConfigMap/default/db", "Secret/default/db"}
+	if diff := cmp.Diff(expected, stale); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	if entries := StaleEntries(nil, current); len(entries) != 0 {
+		t.Errorf("expected no stale entries, got %v", entries)
+	}
+
+	if entries := StaleEntries(previous, nil); len(entries) != 0 {
+		t.Errorf("expected no stale entries, got %v", entries)
+	}
+}
+
+func TestChangeSet_Serialization(t *testing.T) {
+	changeSet := NewChangeSet()
+	changeSet.Add(ChangeSetEntry{
+		ObjMetadata: object.ObjMetadata{
+			GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+			Namespace: "default",
+			Name:      "app",
+		},
+		GroupVersion: "v1",
+		Subject:      "Deployment/default/app",
+		Action:       string(ConfiguredAction),
+	})
+	changeSet.Add(ChangeSetEntry{
+		ObjMetadata: object.ObjMetadata{
+			GroupKind: schema.GroupKind{Kind: "Namespace"},
+			Name:      "apps",
+		},
+		GroupVersion: "v1",
+		Subject:      "Namespace/apps",
+		Action:       string(CreatedAction),
+	})
+	changeSet.setRevision("main@sha1:0123abcd")
+
+	data, err := changeSet.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"entries":[` +
+		`{"group":"apps","version":"v1","kind":"Deployment","namespace":"default","name":"app","subject":"Deployment/default/app","action":"configured","revision":"main@sha1:0123abcd"},` +
+		`{"group":"","version":"v1","kind":"Namespace","name":"apps","subject":"Namespace/apps","action":"created","revision":"main@sha1:0123abcd"}]}`
+	if diff := cmp.Diff(wantJSON, string(data)); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	parsed, err := ParseChangeSet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(changeSet, parsed); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	yamlData, err := changeSet.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = ParseChangeSet(yamlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(changeSet, parsed); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseChangeSet([]byte(`{"entries":[{"kind":"Deployment"}]}`)); err == nil {
+		t.Error("expected error for entry without name")
+	}
+
+	empty, err := ParseChangeSet([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(NewChangeSet(), empty); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LastAppliedConfigAnnotation holds the last configuration applied with a client-side
+// three-way merge patch, from which the fields removed from the desired state are computed.
+const LastAppliedConfigAnnotation = "ssa.fluxcd.io/last-applied-configuration"
+
+// SetClientSideApplyKinds configures the ResourceManager to apply the objects of the given kinds
+// with a client-side three-way merge patch instead of server-side apply, e.g. for the kinds
+// served by aggregated API servers which don't support server-side apply correctly.
+// Regardless of the kinds, the ResourceManager falls back to client-side apply once the
+// API server rejects a server-side apply request as an unsupported media type.
+func (m *ResourceManager) SetClientSideApplyKinds(kinds ...schema.GroupKind) {
+	m.clientSideApplyKinds = kinds
+}
+
+// useClientSideApply returns true if the given object is to be applied with a client-side merge patch.
+func (m *ResourceManager) useClientSideApply(object *unstructured.Unstructured) bool {
+	if m.serverSideApplyUnsupported != nil && atomic.LoadInt32(m.serverSideApplyUnsupported) == 1 {
+		return true
+	}
+	gk := object.GroupVersionKind().GroupKind()
+	for _, kind := range m.clientSideApplyKinds {
+		if kind == gk {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackToClientSideApply returns true if the given server-side apply error means
+// the API server doesn't support server-side apply, and disables it for the next requests.
+func (m *ResourceManager) fallbackToClientSideApply(err error) bool {
+	if !apierrors.IsUnsupportedMediaType(err) {
+		return false
+	}
+	if m.serverSideApplyUnsupported != nil && atomic.CompareAndSwapInt32(m.serverSideApplyUnsupported, 0, 1) {
+		m.logger().Info("server-side apply not supported, falling back to client-side apply", "error", err.Error())
+	}
+	return true
+}
+
+// clientSideApply creates the given object, or patches the in-cluster object with a three-way merge
+// patch computed from the last applied configuration, the desired and the in-cluster states.
+// The object is updated with the API server response.
+func (m *ResourceManager) clientSideApply(ctx context.Context, object *unstructured.Unstructured, dryRun bool) error {
+	if err := setLastAppliedConfiguration(object); err != nil {
+		return err
+	}
+
+	existingObject := &unstructured.Unstructured{}
+	existingObject.SetGroupVersionKind(object.GroupVersionKind())
+	err := m.client.Get(ctx, client.ObjectKeyFromObject(object), existingObject)
+	if apierrors.IsNotFound(err) {
+		opts := []client.CreateOption{client.FieldOwner(m.owner.Field)}
+		if dryRun {
+			opts = append(opts, client.DryRunAll)
+		}
+		return m.withRetry(ctx, func() error {
+			return m.client.Create(ctx, object, opts...)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	original := []byte(existingObject.GetAnnotations()[LastAppliedConfigAnnotation])
+	modified, err := object.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	current, err := existingObject.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	patch, err := m.threeWayMergePatch(object.GroupVersionKind(), original, modified, current)
+	if err != nil {
+		return fmt.Errorf("%s merge patch failed, error: %w", FmtUnstructured(object), err)
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(m.owner.Field)}
+	if dryRun {
+		opts = append(opts, client.DryRunAll)
+	}
+	return m.withRetry(ctx, func() error {
+		return m.client.Patch(ctx, object, patch, opts...)
+	})
+}
+
+// threeWayMergePatch returns a strategic merge patch for the kinds registered in the client scheme,
+// and a JSON merge patch for the other kinds, e.g. custom resources.
+func (m *ResourceManager) threeWayMergePatch(gvk schema.GroupVersionKind, original, modified, current []byte) (client.Patch, error) {
+	if typed, err := m.client.Scheme().New(gvk); err == nil {
+		meta, err := strategicpatch.NewPatchMetaFromStruct(typed)
+		if err != nil {
+			return nil, err
+		}
+		data, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, meta, true)
+		if err != nil {
+			return nil, err
+		}
+		return client.RawPatch(types.StrategicMergePatchType, data), nil
+	}
+
+	data, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+	if err != nil {
+		return nil, err
+	}
+	return client.RawPatch(types.MergePatchType, data), nil
+}
+
+// setLastAppliedConfiguration sets the LastAppliedConfigAnnotation to the JSON
+// representation of the object without the annotation.
+func setLastAppliedConfiguration(object *unstructured.Unstructured) error {
+	desired := object.DeepCopy()
+	annotations := desired.GetAnnotations()
+	delete(annotations, LastAppliedConfigAnnotation)
+	desired.SetAnnotations(annotations)
+
+	data, err := json.Marshal(desired.Object)
+	if err != nil {
+		return fmt.Errorf("%s last applied configuration encoding failed, error: %w", FmtUnstructured(object), err)
+	}
+
+	annotations = object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[LastAppliedConfigAnnotation] = string(data)
+	object.SetAnnotations(annotations)
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// noServerSideApplyClient rejects the server-side apply requests as an unsupported media type.
+type noServerSideApplyClient struct {
+	client.Client
+}
+
+func (c *noServerSideApplyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnsupportedMediaType,
+			Reason:  metav1.StatusReasonUnsupportedMediaType,
+			Message: "the body of the request was in an unknown format",
+		}}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestClientSideApply(t *testing.T) {
+	ctx := context.Background()
+	newConfigMap := func(data map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{"data": data}}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName("config")
+		return u
+	}
+	getData := func(t *testing.T, c client.Client) map[string]string {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "config"}, cm); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cm.Annotations[LastAppliedConfigAnnotation]; !ok {
+			t.Errorf("expected %s annotation to be set", LastAppliedConfigAnnotation)
+		}
+		return cm.Data
+	}
+
+	t.Run("applies configured kinds with a three-way merge patch", func(t *testing.T) {
+		kubeClient := fake.NewClientBuilder().Build()
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetClientSideApplyKinds(schema.GroupKind{Kind: "ConfigMap"})
+
+		if err := rm.apply(ctx, newConfigMap(map[string]interface{}{"a": "1", "b": "2"})); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]string{"a": "1", "b": "2"}, getData(t, kubeClient)); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		// a field set by another actor is kept, while the field removed from the desired state is deleted
+		cm := &corev1.ConfigMap{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "config"}, cm); err != nil {
+			t.Fatal(err)
+		}
+		cm.Data["c"] = "3"
+		if err := kubeClient.Update(ctx, cm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := rm.apply(ctx, newConfigMap(map[string]interface{}{"a": "10"})); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]string{"a": "10", "c": "3"}, getData(t, kubeClient)); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("falls back when server-side apply is not supported", func(t *testing.T) {
+		kubeClient := &noServerSideApplyClient{Client: fake.NewClientBuilder().Build()}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+		object := newConfigMap(map[string]interface{}{"a": "1"})
+		if rm.useClientSideApply(object) {
+			t.Fatal("expected server-side apply to be used by default")
+		}
+		if err := rm.apply(ctx, object); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]string{"a": "1"}, getData(t, kubeClient)); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+		if !rm.useClientSideApply(object) {
+			t.Error("expected client-side apply to be used after the fallback")
+		}
+	})
+}
+
+func TestThreeWayMergePatch(t *testing.T) {
+	rm := NewResourceManager(fake.NewClientBuilder().Build(), nil, Owner{Field: "manager", Group: "manager.io"})
+
+	tests := []struct {
+		name     string
+		gvk      schema.GroupVersionKind
+		wantType types.PatchType
+		wantData string
+	}{
+		{
+			name:     "native kind",
+			gvk:      schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			wantType: types.StrategicMergePatchType,
+			wantData: `{"data":{"a":"2","b":null}}`,
+		},
+		{
+			name:     "custom kind",
+			gvk:      schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Custom"},
+			wantType: types.MergePatchType,
+			wantData: `{"data":{"a":"2","b":null}}`,
+		},
+	}
+
+	original := []byte(`{"data":{"a":"1","b":"2"}}`)
+	modified := []byte(`{"data":{"a":"2"}}`)
+	current := []byte(`{"data":{"a":"1","b":"2","c":"3"}}`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := rm.threeWayMergePatch(tt.gvk, original, modified, current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patch.Type() != tt.wantType {
+				t.Errorf("expected patch type %s, got %s", tt.wantType, patch.Type())
+			}
+			data, err := patch.Data(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantData, string(data)); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
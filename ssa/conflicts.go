@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+// ConflictAction defines how the engine resolves the conflicts with the fields owned by other managers.
+type ConflictAction string
+
+const (
+	// ConflictForce takes the ownership of the conflicting fields, this is the default action.
+	ConflictForce ConflictAction = "force"
+
+	// ConflictYield leaves the fields owned by the conflicting manager out of the applied object,
+	// e.g. to not fight the HorizontalPodAutoscaler over the Deployment replicas.
+	ConflictYield ConflictAction = "yield"
+)
+
+// ConflictPolicy defines the action taken for the fields owned by other managers,
+// for the objects matching the group and kind.
+type ConflictPolicy struct {
+	// Group is the API group of the objects, an empty Group matches all groups.
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the objects, an empty Kind matches all kinds.
+	Kind string `json:"kind,omitempty"`
+
+	// Manager is the prefix of the conflicting field manager name,
+	// e.g. 'kubectl-client-side-apply', an empty Manager matches all managers.
+	Manager string `json:"manager,omitempty"`
+
+	// Action is the action taken for the fields owned by the matching managers.
+	Action ConflictAction `json:"action"`
+}
+
+// matches returns true if the policy applies to the given kind and field manager.
+func (p ConflictPolicy) matches(gk schema.GroupKind, manager string) bool {
+	return (p.Group == "" || p.Group == gk.Group) &&
+		(p.Kind == "" || p.Kind == gk.Kind) &&
+		strings.HasPrefix(manager, p.Manager)
+}
+
+// conflictAction returns the action of the first policy matching the given kind and
+// field manager, or ConflictForce if no policy matches.
+func conflictAction(policies []ConflictPolicy, gk schema.GroupKind, manager string) ConflictAction {
+	for _, policy := range policies {
+		if policy.matches(gk, manager) {
+			return policy.Action
+		}
+	}
+	return ConflictForce
+}
+
+// yieldConflicts returns a copy of the given object without the fields owned in-cluster
+// by the managers to which the conflict policies yield.
+func (m *ResourceManager) yieldConflicts(object, existingObject *unstructured.Unstructured, policies []ConflictPolicy) (*unstructured.Unstructured, error) {
+	if len(policies) == 0 || existingObject == nil {
+		return object, nil
+	}
+
+	gk := object.GroupVersionKind().GroupKind()
+	var result *unstructured.Unstructured
+	for _, entry := range existingObject.GetManagedFields() {
+		if entry.Manager == m.owner.Field || entry.FieldsV1 == nil {
+			continue
+		}
+		if conflictAction(policies, gk, entry.Manager) != ConflictYield {
+			continue
+		}
+
+		set, err := FieldsToSet(*entry.FieldsV1)
+		if err != nil {
+			return nil, fmt.Errorf("%s managedFields of '%s' parse failed, error: %w",
+				FmtUnstructured(existingObject), entry.Manager, err)
+		}
+
+		if result == nil {
+			result = object.DeepCopy()
+		}
+		set.Leaves().Iterate(func(path fieldpath.Path) {
+			if s := path.String(); s == ".metadata.name" || s == ".metadata.namespace" {
+				return
+			}
+			// The key fields identify the list items, the item is removed
+			// only if it's owned by the manager.
+			if isListKeyPath(path) {
+				path = path[:len(path)-1]
+				if !set.Has(path) {
+					return
+				}
+			}
+			content, _ := removeFieldPath(result.Object, path)
+			result.Object = content.(map[string]interface{})
+		})
+	}
+
+	if result == nil {
+		return object, nil
+	}
+	return result, nil
+}
+
+// isListKeyPath returns true if the path points to a key field of a list item.
+func isListKeyPath(path fieldpath.Path) bool {
+	if len(path) < 2 || path[len(path)-1].FieldName == nil || path[len(path)-2].Key == nil {
+		return false
+	}
+	for _, field := range *path[len(path)-2].Key {
+		if field.Name == *path[len(path)-1].FieldName {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFieldPath removes the value at the given path from the node,
+// it returns the updated node and true if the value was found.
+func removeFieldPath(node interface{}, path fieldpath.Path) (interface{}, bool) {
+	if len(path) == 0 {
+		return node, false
+	}
+
+	if name := path[0].FieldName; name != nil {
+		fields, ok := node.(map[string]interface{})
+		if !ok {
+			return node, false
+		}
+		child, ok := fields[*name]
+		if !ok {
+			return node, false
+		}
+		if len(path) == 1 {
+			delete(fields, *name)
+			return fields, true
+		}
+		updated, removed := removeFieldPath(child, path[1:])
+		fields[*name] = updated
+		return fields, removed
+	}
+
+	items, ok := node.([]interface{})
+	if !ok {
+		return node, false
+	}
+	i := indexOfPathElement(items, path[0])
+	if i < 0 {
+		return node, false
+	}
+	if len(path) == 1 {
+		return append(items[:i], items[i+1:]...), true
+	}
+	updated, removed := removeFieldPath(items[i], path[1:])
+	items[i] = updated
+	return items, removed
+}
+
+// indexOfPathElement returns the index of the list item matching the path element, or -1.
+func indexOfPathElement(items []interface{}, pe fieldpath.PathElement) int {
+	for i, item := range items {
+		switch {
+		case pe.Key != nil:
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			matched := true
+			for _, field := range *pe.Key {
+				v, ok := fields[field.Name]
+				if !ok || !value.Equals(field.Value, value.NewValueInterface(v)) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return i
+			}
+		case pe.Value != nil:
+			if value.Equals(*pe.Value, value.NewValueInterface(item)) {
+				return i
+			}
+		case pe.Index != nil:
+			if *pe.Index == i {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestYieldConflicts(t *testing.T) {
+	desired := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: default
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: podinfo
+        image: podinfo:6.2.0
+        ports:
+        - containerPort: 9898
+          protocol: TCP
+`
+	managedFields := []metav1.ManagedFieldsEntry{
+		{
+			Manager:   "manager",
+			Operation: metav1.ManagedFieldsOperationApply,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"podinfo\"}":{".":{},"f:image":{},"f:name":{}}}}}}}`)},
+		},
+		{
+			Manager:     "kube-controller-manager",
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			Subresource: "scale",
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:   "kubectl-edit",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"podinfo\"}":{"f:image":{},"f:name":{},"f:ports":{"k:{\"containerPort\":9898,\"protocol\":\"TCP\"}":{".":{},"f:containerPort":{},"f:protocol":{}}}}}}}}}`)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policies []ConflictPolicy
+		want     string
+	}{
+		{
+			name: "no policies",
+			want: desired,
+		},
+		{
+			name: "yield replicas to the autoscaler",
+			policies: []ConflictPolicy{
+				{Kind: "Deployment", Manager: "kube-controller-manager", Action: ConflictYield},
+			},
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: podinfo
+        image: podinfo:6.2.0
+        ports:
+        - containerPort: 9898
+          protocol: TCP
+`,
+		},
+		{
+			name: "yield to all managers except kubectl",
+			policies: []ConflictPolicy{
+				{Manager: "kubectl", Action: ConflictForce},
+				{Group: "apps", Kind: "Deployment", Action: ConflictYield},
+			},
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: podinfo
+        image: podinfo:6.2.0
+        ports:
+        - containerPort: 9898
+          protocol: TCP
+`,
+		},
+		{
+			name: "yield to kubectl",
+			policies: []ConflictPolicy{
+				{Manager: "kubectl", Action: ConflictYield},
+			},
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: default
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: podinfo
+        ports: []
+`,
+		},
+		{
+			name: "policy for another kind",
+			policies: []ConflictPolicy{
+				{Kind: "StatefulSet", Action: ConflictYield},
+			},
+			want: desired,
+		},
+	}
+
+	rm := &ResourceManager{owner: Owner{Field: "manager"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(desired), &object.Object); err != nil {
+				t.Fatal(err)
+			}
+			existingObject := object.DeepCopy()
+			existingObject.SetManagedFields(managedFields)
+
+			got, err := rm.yieldConflicts(object, existingObject, tt.policies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.want), &want.Object); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.Object, got.Object); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
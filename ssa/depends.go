@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// DependsOnAnnotation lists the objects which must be applied and ready before the annotated object,
+// as a comma separated list of '<kind>/<namespace>/<name>' for namespaced objects
+// and '<kind>/<name>' for cluster scoped objects.
+const DependsOnAnnotation = "ssa.fluxcd.io/depends-on"
+
+// dependencyRef identifies an object listed in the DependsOnAnnotation.
+type dependencyRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// parseDependsOn returns the dependencies listed in the DependsOnAnnotation of the given object.
+func parseDependsOn(object *unstructured.Unstructured) ([]dependencyRef, error) {
+	value := strings.TrimSpace(object.GetAnnotations()[DependsOnAnnotation])
+	if value == "" {
+		return nil, nil
+	}
+
+	var refs []dependencyRef
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, fmtSeparator)
+		switch len(parts) {
+		case 2:
+			refs = append(refs, dependencyRef{Kind: parts[0], Name: parts[1]})
+		case 3:
+			refs = append(refs, dependencyRef{Kind: parts[0], Namespace: parts[1], Name: parts[2]})
+		default:
+			return nil, fmt.Errorf("%s invalid %s annotation entry '%s', expected format is '<kind>/<namespace>/<name>'",
+				FmtUnstructured(object), DependsOnAnnotation, entry)
+		}
+	}
+	return refs, nil
+}
+
+// matches returns true if the given object metadata matches the dependency reference.
+func (r dependencyRef) matches(id object.ObjMetadata) bool {
+	return strings.EqualFold(r.Kind, id.GroupKind.Kind) && r.Namespace == id.Namespace && r.Name == id.Name
+}
+
+// SortByDependencies groups the given objects in waves based on the DependsOnAnnotation, where each
+// object is placed in a wave after the waves of its dependencies. The dependencies which are not in
+// the given set are assumed to be already applied and are ignored. It returns an error if the
+// dependencies are circular.
+func SortByDependencies(objects []*unstructured.Unstructured) ([][]*unstructured.Unstructured, error) {
+	sorted := make([]*unstructured.Unstructured, len(objects))
+	copy(sorted, objects)
+	sort.Sort(SortableUnstructureds(sorted))
+
+	ids := make([]object.ObjMetadata, len(sorted))
+	for i, obj := range sorted {
+		ids[i] = object.UnstructuredToObjMetadata(obj)
+	}
+
+	// dependencies holds the indexes of the dependencies of each object.
+	dependencies := make([][]int, len(sorted))
+	for i, obj := range sorted {
+		refs, err := parseDependsOn(obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			for j, id := range ids {
+				if i != j && ref.matches(id) {
+					dependencies[i] = append(dependencies[i], j)
+				}
+			}
+		}
+	}
+
+	waveOf := make([]int, len(sorted))
+	for i := range waveOf {
+		waveOf[i] = -1
+	}
+
+	var waves [][]*unstructured.Unstructured
+	placed := 0
+	for placed < len(sorted) {
+		var wave []int
+		for i := range sorted {
+			if waveOf[i] >= 0 {
+				continue
+			}
+			ready := true
+			for _, j := range dependencies[i] {
+				if waveOf[j] < 0 {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				wave = append(wave, i)
+			}
+		}
+
+		if len(wave) == 0 {
+			var cycle []string
+			for i, obj := range sorted {
+				if waveOf[i] < 0 {
+					cycle = append(cycle, FmtUnstructured(obj))
+				}
+			}
+			return nil, fmt.Errorf("circular dependencies detected between: [%s]", strings.Join(cycle, ", "))
+		}
+
+		objs := make([]*unstructured.Unstructured, 0, len(wave))
+		for _, i := range wave {
+			waveOf[i] = len(waves)
+			objs = append(objs, sorted[i])
+		}
+		waves = append(waves, objs)
+		placed += len(wave)
+	}
+
+	return waves, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSortByDependencies(t *testing.T) {
+	newObject := func(kind, namespace, name, dependsOn string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		u.SetNamespace(namespace)
+		u.SetName(name)
+		if dependsOn != "" {
+			u.SetAnnotations(map[string]string{DependsOnAnnotation: dependsOn})
+		}
+		return u
+	}
+
+	fmtWaves := func(waves [][]*unstructured.Unstructured) string {
+		var res []string
+		for _, wave := range waves {
+			res = append(res, "["+strings.ReplaceAll(FmtUnstructuredList(wave), "\n", " ")+"]")
+		}
+		return strings.Join(res, " ")
+	}
+
+	testCases := []struct {
+		name     string
+		objects  []*unstructured.Unstructured
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "no dependencies",
+			objects: []*unstructured.Unstructured{
+				newObject("Service", "default", "app", ""),
+				newObject("ConfigMap", "default", "app", ""),
+			},
+			expected: "[ConfigMap/default/app Service/default/app]",
+		},
+		{
+			name: "chain",
+			objects: []*unstructured.Unstructured{
+				newObject("Service", "default", "app", "Service/default/db"),
+				newObject("Service", "default", "db", "ConfigMap/default/db"),
+				newObject("ConfigMap", "default", "db", ""),
+				newObject("ConfigMap", "default", "app", ""),
+			},
+			expected: "[ConfigMap/default/app ConfigMap/default/db] [Service/default/db] [Service/default/app]",
+		},
+		{
+			name: "cluster scoped and missing dependencies",
+			objects: []*unstructured.Unstructured{
+				newObject("Service", "default", "app", "Namespace/default, Service/default/missing"),
+				newObject("Namespace", "", "default", ""),
+			},
+			expected: "[Namespace/default] [Service/default/app]",
+		},
+		{
+			name: "circular dependencies",
+			objects: []*unstructured.Unstructured{
+				newObject("Service", "default", "a", "Service/default/b"),
+				newObject("Service", "default", "b", "Service/default/a"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid annotation",
+			objects: []*unstructured.Unstructured{
+				newObject("Service", "default", "a", "Service"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			waves, err := SortByDependencies(tc.objects)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got waves %s", fmtWaves(waves))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmtWaves(waves); got != tc.expected {
+				t.Errorf("expected waves %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
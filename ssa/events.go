@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventReasons are the reasons of the events emitted for the actions.
+var eventReasons = map[Action]string{
+	CreatedAction:    "Created",
+	ConfiguredAction: "Configured",
+	DeletedAction:    "Deleted",
+}
+
+// SetEventRecorder configures the ResourceManager to emit Kubernetes events for the objects
+// created, configured and deleted. When the event object is not nil, the events are recorded on it,
+// e.g. on the custom resource which owns the applied objects, otherwise on each changed object.
+func (m *ResourceManager) SetEventRecorder(recorder record.EventRecorder, eventObject runtime.Object) {
+	m.eventRecorder = recorder
+	m.eventObject = eventObject
+}
+
+// recordEvent emits a Normal event describing the action performed on the given object,
+// the actor is the owner field manager, and the revision, if any, is set as annotation.
+func (m *ResourceManager) recordEvent(object *unstructured.Unstructured, action Action, revision string) {
+	reason, ok := eventReasons[action]
+	if m.eventRecorder == nil || !ok {
+		return
+	}
+
+	var involvedObject runtime.Object = object
+	if m.eventObject != nil {
+		involvedObject = m.eventObject
+	}
+
+	msg := fmt.Sprintf("%s %s by %s", FmtUnstructured(object), action, m.owner.Field)
+	if revision == "" {
+		m.eventRecorder.Event(involvedObject, corev1.EventTypeNormal, reason, msg)
+		return
+	}
+
+	annotations := map[string]string{
+		m.owner.Group + "/revision": revision,
+	}
+	m.eventRecorder.AnnotatedEventf(involvedObject, annotations, corev1.EventTypeNormal, reason,
+		"%s, revision %s", msg, revision)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordEvent(t *testing.T) {
+	newObject := func(apiVersion, kind, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+
+	configMap := newObject("v1", "ConfigMap", "test")
+	owner := newObject("kustomize.toolkit.fluxcd.io/v1beta2", "Kustomization", "apps")
+
+	tests := []struct {
+		name        string
+		eventObject *unstructured.Unstructured
+		action      Action
+		revision    string
+		want        []string
+	}{
+		{
+			name:   "created object",
+			action: CreatedAction,
+			want:   []string{"Normal Created ConfigMap/default/test created by manager involvedObject{kind=ConfigMap,apiVersion=v1}"},
+		},
+		{
+			name:     "configured object with revision",
+			action:   ConfiguredAction,
+			revision: "main@sha1:6e4d2a8",
+			want:     []string{"Normal Configured ConfigMap/default/test configured by manager, revision main@sha1:6e4d2a8 involvedObject{kind=ConfigMap,apiVersion=v1}"},
+		},
+		{
+			name:        "deleted object recorded on owner",
+			eventObject: owner,
+			action:      DeletedAction,
+			want:        []string{"Normal Deleted ConfigMap/default/test deleted by manager involvedObject{kind=Kustomization,apiVersion=kustomize.toolkit.fluxcd.io/v1beta2}"},
+		},
+		{
+			name:   "unchanged object",
+			action: UnchangedAction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			recorder.IncludeObject = true
+
+			rm := &ResourceManager{owner: Owner{Field: "manager", Group: "manager.io"}}
+			if tt.eventObject != nil {
+				rm.SetEventRecorder(recorder, tt.eventObject)
+			} else {
+				rm.SetEventRecorder(recorder, nil)
+			}
+			rm.recordEvent(configMap, tt.action, tt.revision)
+			close(recorder.Events)
+
+			var got []string
+			for e := range recorder.Events {
+				got = append(got, e)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
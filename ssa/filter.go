@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultIgnoreAnnotation is the annotation which exempts an object from apply and delete
+// when set to 'true', e.g. 'ssa.fluxcd.io/ignore: "true"'. The check is enabled by setting
+// the IgnoreAnnotation of the apply and delete options.
+const DefaultIgnoreAnnotation = "ssa.fluxcd.io/ignore"
+
+// HelmHookAnnotation is the annotation which marks the objects rendered from a Helm chart
+// as hooks, run by Helm at specific points of a release lifecycle.
+const HelmHookAnnotation = "helm.sh/hook"
+
+// filterObjects splits the given objects into the objects to reconcile and the objects
+// to skip, which carry the ignore annotation set to 'true' or don't match the label selector.
+// An empty ignore annotation or label selector disables the matching filter.
+func filterObjects(objects []*unstructured.Unstructured, ignoreAnnotation, labelSelector string) (
+	[]*unstructured.Unstructured,
+	[]*unstructured.Unstructured,
+	error,
+) {
+	selector := labels.Everything()
+	if labelSelector != "" {
+		var err error
+		selector, err = labels.Parse(labelSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid label selector '%s', error: %w", labelSelector, err)
+		}
+	}
+
+	var kept, skipped []*unstructured.Unstructured
+	for _, object := range objects {
+		if isIgnored(object, ignoreAnnotation) || !selector.Matches(labels.Set(object.GetLabels())) {
+			skipped = append(skipped, object)
+			continue
+		}
+		kept = append(kept, object)
+	}
+	return kept, skipped, nil
+}
+
+// isIgnored returns true if the given object has the ignore annotation set to 'true'.
+func isIgnored(object *unstructured.Unstructured, ignoreAnnotation string) bool {
+	if ignoreAnnotation == "" {
+		return false
+	}
+	return strings.EqualFold(object.GetAnnotations()[ignoreAnnotation], "true")
+}
+
+// IsHelmHook returns true if the given object has the Helm hook annotation set.
+func IsHelmHook(object *unstructured.Unstructured) bool {
+	_, ok := object.GetAnnotations()[HelmHookAnnotation]
+	return ok
+}
+
+// SplitHelmHooks splits the given objects into the regular objects and the Helm hooks,
+// so that the manifests rendered from a chart can be applied without the hooks.
+func SplitHelmHooks(objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, []*unstructured.Unstructured) {
+	var kept, hooks []*unstructured.Unstructured
+	for _, object := range objects {
+		if IsHelmHook(object) {
+			hooks = append(hooks, object)
+			continue
+		}
+		kept = append(kept, object)
+	}
+	return kept, hooks
+}
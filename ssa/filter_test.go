@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFilterObjects(t *testing.T) {
+	newObject := func(name string, labels, annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName(name)
+		u.SetLabels(labels)
+		u.SetAnnotations(annotations)
+		return u
+	}
+
+	objects := []*unstructured.Unstructured{
+		newObject("managed", map[string]string{"app": "podinfo"}, nil),
+		newObject("ignored", map[string]string{"app": "podinfo"}, map[string]string{DefaultIgnoreAnnotation: "true"}),
+		newObject("not-ignored", map[string]string{"app": "podinfo"}, map[string]string{DefaultIgnoreAnnotation: "false"}),
+		newObject("unlabeled", nil, nil),
+	}
+
+	tests := []struct {
+		name             string
+		ignoreAnnotation string
+		labelSelector    string
+		wantKept         []string
+		wantSkipped      []string
+		wantErr          bool
+	}{
+		{
+			name:     "no filters",
+			wantKept: []string{"managed", "ignored", "not-ignored", "unlabeled"},
+		},
+		{
+			name:             "ignore annotation",
+			ignoreAnnotation: DefaultIgnoreAnnotation,
+			wantKept:         []string{"managed", "not-ignored", "unlabeled"},
+			wantSkipped:      []string{"ignored"},
+		},
+		{
+			name:          "label selector",
+			labelSelector: "app=podinfo",
+			wantKept:      []string{"managed", "ignored", "not-ignored"},
+			wantSkipped:   []string{"unlabeled"},
+		},
+		{
+			name:             "ignore annotation and label selector",
+			ignoreAnnotation: DefaultIgnoreAnnotation,
+			labelSelector:    "app in (podinfo)",
+			wantKept:         []string{"managed", "not-ignored"},
+			wantSkipped:      []string{"ignored", "unlabeled"},
+		},
+		{
+			name:          "invalid label selector",
+			labelSelector: "app in podinfo",
+			wantErr:       true,
+		},
+	}
+
+	names := func(objects []*unstructured.Unstructured) []string {
+		var result []string
+		for _, object := range objects {
+			result = append(result, object.GetName())
+		}
+		return result
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped, err := filterObjects(objects, tt.ignoreAnnotation, tt.labelSelector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterObjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.wantKept, names(kept)); diff != "" {
+				t.Errorf("Mismatch from expected kept objects (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantSkipped, names(skipped)); diff != "" {
+				t.Errorf("Mismatch from expected skipped objects (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplitHelmHooks(t *testing.T) {
+	objects, err := ReadObjects(strings.NewReader(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: test
+  namespace: default
+  annotations:
+    helm.sh/hook: test
+    helm.sh/hook-delete-policy: hook-succeeded
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: test
+        image: busybox
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  annotations:
+    helm.sh/resource-policy: keep
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, hooks := SplitHelmHooks(objects)
+
+	var keptNames, hookNames []string
+	for _, object := range kept {
+		keptNames = append(keptNames, object.GetName())
+	}
+	for _, object := range hooks {
+		hookNames = append(hookNames, object.GetName())
+	}
+
+	if diff := cmp.Diff([]string{"config", "app"}, keptNames); diff != "" {
+		t.Errorf("Mismatch from expected kept objects (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"test"}, hookNames); diff != "" {
+		t.Errorf("Mismatch from expected hooks (-want +got):\n%s", diff)
+	}
+}
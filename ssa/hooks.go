@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PreApplyFunc is invoked before an object is applied, with the object to apply, the server-side
+// dry-run result and the planned action. The object can be mutated, the mutations are applied but
+// are not reflected in the dry-run result. Returning an error fails the apply of the object.
+type PreApplyFunc func(ctx context.Context, object, dryRunObject *unstructured.Unstructured, action Action) error
+
+// PostApplyFunc is invoked after an object is reconciled, with the resulting ChangeSet entry,
+// and the error if the object failed to apply.
+type PostApplyFunc func(ctx context.Context, object *unstructured.Unstructured, entry ChangeSetEntry, err error)
+
+// SetApplyHooks registers the callbacks invoked by Apply and ApplyAll for each object, e.g. to
+// implement custom mutations, policy checks or progress reporting. The pre-apply hook is invoked only
+// for the objects which are new or have drifted, before the in-cluster object is modified, including the
+// metadata cleanup. The post-apply hook is invoked for all the objects.
+// When ApplyAll runs concurrently, the hooks are invoked concurrently. A nil hook is not invoked.
+func (m *ResourceManager) SetApplyHooks(preApply PreApplyFunc, postApply PostApplyFunc) {
+	m.preApplyHook = preApply
+	m.postApplyHook = postApply
+}
+
+// preApply invokes the pre-apply hook, if set.
+func (m *ResourceManager) preApply(ctx context.Context, object, dryRunObject *unstructured.Unstructured, action Action) error {
+	if m.preApplyHook == nil {
+		return nil
+	}
+	if err := m.preApplyHook(ctx, object, dryRunObject, action); err != nil {
+		return fmt.Errorf("%s pre-apply hook failed, error: %w", FmtUnstructured(object), err)
+	}
+	return nil
+}
+
+// postApply invokes the post-apply hook, if set. A nil entry is reported with the UnknownAction.
+func (m *ResourceManager) postApply(ctx context.Context, object *unstructured.Unstructured, entry *ChangeSetEntry, err error) {
+	if m.postApplyHook == nil {
+		return
+	}
+	if entry == nil {
+		entry = m.changeSetEntry(object, UnknownAction)
+	}
+	m.postApplyHook(ctx, object, *entry, err)
+}
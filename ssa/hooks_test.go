@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyHooks(t *testing.T) {
+	ctx := context.Background()
+	newConfigMap := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"data": map[string]interface{}{"key": "value"},
+		}}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+	newManager := func() (*ResourceManager, client.Client) {
+		kubeClient := fake.NewClientBuilder().Build()
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetClientSideApplyKinds(schema.GroupKind{Kind: "ConfigMap"})
+		return rm, kubeClient
+	}
+
+	t.Run("invokes the hooks for each object", func(t *testing.T) {
+		rm, kubeClient := newManager()
+
+		var mu sync.Mutex
+		actions := make(map[string]Action)
+		rm.SetApplyHooks(
+			func(ctx context.Context, object, dryRunObject *unstructured.Unstructured, action Action) error {
+				if dryRunObject == nil {
+					t.Errorf("expected the dry-run result of %s", FmtUnstructured(object))
+				}
+				object.SetLabels(map[string]string{"hook": string(action)})
+				return nil
+			},
+			func(ctx context.Context, object *unstructured.Unstructured, entry ChangeSetEntry, err error) {
+				if err != nil {
+					t.Errorf("unexpected error for %s: %v", entry.Subject, err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				actions[object.GetName()] = Action(entry.Action)
+			},
+		)
+
+		opts := DefaultApplyOptions()
+		opts.Concurrency = 2
+		if _, err := rm.ApplyAll(ctx, []*unstructured.Unstructured{newConfigMap("first"), newConfigMap("second")}, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]Action{"first": CreatedAction, "second": CreatedAction}
+		if diff := cmp.Diff(want, actions); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		cm := &corev1.ConfigMap{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "first"}, cm); err != nil {
+			t.Fatal(err)
+		}
+		if cm.Labels["hook"] != string(CreatedAction) {
+			t.Errorf("expected the pre-apply mutation to be applied, got labels %v", cm.Labels)
+		}
+	})
+
+	t.Run("fails the apply on pre-apply errors", func(t *testing.T) {
+		rm, kubeClient := newManager()
+
+		var postErr error
+		rm.SetApplyHooks(
+			func(ctx context.Context, object, dryRunObject *unstructured.Unstructured, action Action) error {
+				return errors.New("denied by policy")
+			},
+			func(ctx context.Context, object *unstructured.Unstructured, entry ChangeSetEntry, err error) {
+				postErr = err
+				if entry.Action != string(UnknownAction) {
+					t.Errorf("expected action %s, got %s", UnknownAction, entry.Action)
+				}
+			},
+		)
+
+		_, err := rm.Apply(ctx, newConfigMap("first"), DefaultApplyOptions())
+		if err == nil || postErr == nil {
+			t.Fatalf("expected the hook error to be returned and reported, got %v and %v", err, postErr)
+		}
+
+		cm := &corev1.ConfigMap{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "first"}, cm); err == nil {
+			t.Error("expected the object not to be applied")
+		}
+	})
+	t.Run("does not clean up metadata on pre-apply errors", func(t *testing.T) {
+		rm, kubeClient := newManager()
+		rm.SetApplyHooks(
+			func(ctx context.Context, object, dryRunObject *unstructured.Unstructured, action Action) error {
+				return errors.New("denied by policy")
+			},
+			nil,
+		)
+
+		existing := newConfigMap("first")
+		existing.SetAnnotations(map[string]string{"example.com/stale": "true"})
+		if err := kubeClient.Create(ctx, existing); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := DefaultApplyOptions()
+		opts.Cleanup.Annotations = []string{"example.com/stale"}
+		if _, err := rm.Apply(ctx, newConfigMap("first"), opts); err == nil {
+			t.Error("expected Apply to fail")
+		}
+		if _, err := rm.ApplyAll(ctx, []*unstructured.Unstructured{newConfigMap("first")}, opts); err == nil {
+			t.Error("expected ApplyAll to fail")
+		}
+
+		cm := &corev1.ConfigMap{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "first"}, cm); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cm.Annotations["example.com/stale"]; !ok {
+			t.Errorf("expected the in-cluster object to be left untouched, got annotations %v", cm.Annotations)
+		}
+	})
+}
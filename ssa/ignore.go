@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// IgnoreFieldsAnnotation holds the comma separated JSON pointers of the fields managed
+// outside of the ResourceManager, e.g. 'ssa.fluxcd.io/ignore-fields: /spec/replicas'.
+const IgnoreFieldsAnnotation = "ssa.fluxcd.io/ignore-fields"
+
+// IgnoreRule defines the fields of the matching objects which are managed by other
+// controllers, e.g. the replicas of a Deployment scaled by a HorizontalPodAutoscaler.
+// The ignored fields keep their in-cluster values on apply and are not reported as drift.
+type IgnoreRule struct {
+	// Paths holds the JSON pointers of the ignored fields, e.g. '/spec/replicas', the '/' and '~'
+	// characters of the keys are escaped as '~1' and '~0', e.g. '/metadata/annotations/example.com~1injected'.
+	// A '*' path segment matches any map key or array index, e.g. '/spec/template/spec/containers/*/image'.
+	Paths []string `json:"paths"`
+
+	// Group is the API group of the matching objects, an empty Group matches the core group
+	// when Kind is set, and all groups otherwise.
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the matching objects, an empty Kind matches all kinds.
+	Kind string `json:"kind,omitempty"`
+}
+
+// ScaledReplicasIgnoreRules ignores the replicas of the workloads, for the
+// object sets where the replicas are managed by HorizontalPodAutoscalers.
+var ScaledReplicasIgnoreRules = []IgnoreRule{
+	{Group: "apps", Kind: "Deployment", Paths: []string{"/spec/replicas"}},
+	{Group: "apps", Kind: "StatefulSet", Paths: []string{"/spec/replicas"}},
+	{Group: "apps", Kind: "ReplicaSet", Paths: []string{"/spec/replicas"}},
+}
+
+// matches returns true if the rule applies to the given object.
+func (r IgnoreRule) matches(object *unstructured.Unstructured) bool {
+	if r.Kind == "" {
+		return true
+	}
+	gvk := object.GroupVersionKind()
+	return r.Kind == gvk.Kind && r.Group == gvk.Group
+}
+
+// ignoredPaths returns the ignored paths of the given object, from the
+// matching rules and the object IgnoreFieldsAnnotation.
+func ignoredPaths(object *unstructured.Unstructured, rules []IgnoreRule) []string {
+	var paths []string
+	for _, rule := range rules {
+		if rule.matches(object) {
+			paths = append(paths, rule.Paths...)
+		}
+	}
+	for _, path := range strings.Split(object.GetAnnotations()[IgnoreFieldsAnnotation], ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// SetIgnoreRules configures the fields ignored by Apply, ApplyAll, Diff and DiffAll for all the objects,
+// in addition to the options IgnoreRules and the objects IgnoreFieldsAnnotation.
+// The rules paths are JSON pointers as defined in RFC 6901, e.g. '/metadata/annotations/example.com~1injected'.
+func (m *ResourceManager) SetIgnoreRules(rules ...IgnoreRule) {
+	m.ignoreRules = rules
+}
+
+// ignoreFields returns a copy of the given object in which the fields ignored by the
+// ResourceManager rules, the given rules and the object annotation keep their in-cluster values.
+func (m *ResourceManager) ignoreFields(object, existingObject *unstructured.Unstructured, rules []IgnoreRule) *unstructured.Unstructured {
+	if len(m.ignoreRules) > 0 {
+		rules = append(append([]IgnoreRule{}, m.ignoreRules...), rules...)
+	}
+	return ignoreFields(object, existingObject, rules)
+}
+
+// ignoreFields returns a copy of the given object in which the ignored fields are set
+// to the values of the existing object. The ignored fields missing from the existing
+// object keep their desired values, e.g. the initial replicas of a new Deployment.
+func ignoreFields(object, existingObject *unstructured.Unstructured, rules []IgnoreRule) *unstructured.Unstructured {
+	paths := ignoredPaths(object, rules)
+	if len(paths) == 0 || existingObject == nil || existingObject.GetResourceVersion() == "" {
+		return object
+	}
+
+	result := object.DeepCopy()
+	for _, pattern := range paths {
+		segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		for _, path := range matchPaths(existingObject.Object, nil, segments) {
+			value, _ := getPath(existingObject.Object, path)
+			setIgnoredPath(result.Object, existingObject.Object, path, runtime.DeepCopyJSONValue(value))
+		}
+	}
+	return result
+}
+
+// setIgnoredPath sets the value at the given path, the missing parent maps are created
+// when the matching parents of the existing object are maps, e.g. the annotations.
+func setIgnoredPath(object, existingObject map[string]interface{}, path []string, value interface{}) {
+	var node interface{} = object
+	for i, k := range path[:len(path)-1] {
+		fields, ok := node.(map[string]interface{})
+		if !ok {
+			break
+		}
+		child, ok := fields[k]
+		if !ok {
+			existing, _ := getPath(existingObject, path[:i+1])
+			if _, ok := existing.(map[string]interface{}); !ok {
+				return
+			}
+			child = map[string]interface{}{}
+			fields[k] = child
+		}
+		node = child
+	}
+	setPath(object, path, value)
+}
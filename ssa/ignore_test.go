@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIgnoreFields(t *testing.T) {
+	newDeployment := func(replicas int64, image string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "podinfo",
+				"namespace": "default",
+			},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "app", "image": image},
+						},
+					},
+				},
+			},
+		}}
+		return u
+	}
+	existing := newDeployment(5, "podinfo:6.0.0")
+	existing.SetResourceVersion("1")
+
+	tests := []struct {
+		name        string
+		object      *unstructured.Unstructured
+		existing    *unstructured.Unstructured
+		rules       []IgnoreRule
+		annotations map[string]string
+		want        *unstructured.Unstructured
+	}{
+		{
+			name:     "ignores the replicas with the built-in rules",
+			object:   newDeployment(1, "podinfo:6.1.0"),
+			existing: existing,
+			rules:    ScaledReplicasIgnoreRules,
+			want:     newDeployment(5, "podinfo:6.1.0"),
+		},
+		{
+			name:     "ignores the wildcard paths",
+			object:   newDeployment(1, "podinfo:6.1.0"),
+			existing: existing,
+			rules:    []IgnoreRule{{Paths: []string{"/spec/template/spec/containers/*/image"}}},
+			want:     newDeployment(1, "podinfo:6.0.0"),
+		},
+		{
+			name:     "skips the rules of other groups",
+			object:   newDeployment(1, "podinfo:6.1.0"),
+			existing: existing,
+			rules:    []IgnoreRule{{Kind: "Deployment", Paths: []string{"/spec/replicas"}}},
+			want:     newDeployment(1, "podinfo:6.1.0"),
+		},
+		{
+			name:        "ignores the annotation paths",
+			object:      newDeployment(1, "podinfo:6.1.0"),
+			existing:    existing,
+			annotations: map[string]string{IgnoreFieldsAnnotation: "/spec/replicas, /spec/template/spec/containers/0/image"},
+			want:        newDeployment(5, "podinfo:6.0.0"),
+		},
+		{
+			name:     "keeps the desired values of new objects",
+			object:   newDeployment(1, "podinfo:6.1.0"),
+			existing: newDeployment(1, "podinfo:6.1.0"),
+			rules:    ScaledReplicasIgnoreRules,
+			want:     newDeployment(1, "podinfo:6.1.0"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.annotations != nil {
+				tt.object.SetAnnotations(tt.annotations)
+				tt.want.SetAnnotations(tt.annotations)
+			}
+			got := ignoreFields(tt.object, tt.existing, tt.rules)
+			if diff := cmp.Diff(tt.want.Object, got.Object); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIgnoreFields_EscapedPaths(t *testing.T) {
+	newConfigMap := func(annotations map[string]string, data map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{"data": data}}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName("config")
+		if annotations != nil {
+			u.SetAnnotations(annotations)
+		}
+		return u
+	}
+
+	existing := newConfigMap(map[string]string{"example.com/injected": "true"}, map[string]interface{}{"key": "in-cluster"})
+	existing.SetResourceVersion("1")
+
+	rm := NewResourceManager(nil, nil, Owner{Field: "manager", Group: "manager.io"})
+	rm.SetIgnoreRules(IgnoreRule{Paths: []string{"/metadata/annotations/example.com~1injected"}})
+
+	got := rm.ignoreFields(newConfigMap(nil, map[string]interface{}{"key": "desired"}), existing,
+		[]IgnoreRule{{Kind: "ConfigMap", Paths: []string{"/data/key"}}})
+
+	want := newConfigMap(map[string]string{"example.com/injected": "true"}, map[string]interface{}{"key": "in-cluster"})
+	if diff := cmp.Diff(want.Object, got.Object); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Impersonation holds the identity under which the Kubernetes API requests are performed,
+// either a user with its groups, or a service account.
+type Impersonation struct {
+	// UserName is the name of the impersonated user.
+	UserName string `json:"userName,omitempty"`
+
+	// Groups are the groups of the impersonated user or service account.
+	Groups []string `json:"groups,omitempty"`
+
+	// ServiceAccountName is the name of the impersonated service account.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ServiceAccountNamespace is the namespace of the impersonated service account.
+	ServiceAccountNamespace string `json:"serviceAccountNamespace,omitempty"`
+}
+
+// ImpersonationConfig returns the client-go impersonation config for the identity.
+// A service account is impersonated as 'system:serviceaccount:<namespace>:<name>'
+// along with the service account groups.
+func (i Impersonation) ImpersonationConfig() (rest.ImpersonationConfig, error) {
+	if i.ServiceAccountName == "" {
+		if i.UserName == "" {
+			return rest.ImpersonationConfig{}, fmt.Errorf("impersonation requires a user name or a service account name")
+		}
+		return rest.ImpersonationConfig{
+			UserName: i.UserName,
+			Groups:   i.Groups,
+		}, nil
+	}
+
+	if i.UserName != "" {
+		return rest.ImpersonationConfig{}, fmt.Errorf("impersonation of user '%s' and service account '%s' are mutually exclusive",
+			i.UserName, i.ServiceAccountName)
+	}
+	if i.ServiceAccountNamespace == "" {
+		return rest.ImpersonationConfig{}, fmt.Errorf("impersonation of service account '%s' requires a namespace",
+			i.ServiceAccountName)
+	}
+
+	groups := []string{
+		"system:serviceaccounts",
+		"system:serviceaccounts:" + i.ServiceAccountNamespace,
+	}
+	return rest.ImpersonationConfig{
+		UserName: fmt.Sprintf("system:serviceaccount:%s:%s", i.ServiceAccountNamespace, i.ServiceAccountName),
+		Groups:   append(groups, i.Groups...),
+	}, nil
+}
+
+// Impersonate returns a ResourceManager which performs the requests, including the health checks,
+// under the given identity, so that objects are reconciled with the identity's own RBAC permissions.
+// The Kubernetes client is created from the given config, with the scheme and RESTMapper of the
+// ResourceManager client, while the other settings, e.g. the owner and status readers, are kept.
+// The impersonating ResourceManager shares the client-side apply fallback state, since it targets the same cluster.
+func (m *ResourceManager) Impersonate(config *rest.Config, impersonation Impersonation) (*ResourceManager, error) {
+	impersonationConfig, err := impersonation.ImpersonationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := rest.CopyConfig(config)
+	cfg.Impersonate = impersonationConfig
+
+	kubeClient, err := client.New(cfg, client.Options{
+		Scheme: m.client.Scheme(),
+		Mapper: m.client.RESTMapper(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client impersonating '%s', error: %w", impersonationConfig.UserName, err)
+	}
+
+	rm := *m
+	rm.client = kubeClient
+	rm.poller = polling.NewStatusPoller(kubeClient, kubeClient.RESTMapper(), m.pollerOptions)
+	return &rm, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestImpersonationConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		impersonation Impersonation
+		want          rest.ImpersonationConfig
+		wantErr       bool
+	}{
+		{
+			name:          "user with groups",
+			impersonation: Impersonation{UserName: "tenant", Groups: []string{"tenants"}},
+			want:          rest.ImpersonationConfig{UserName: "tenant", Groups: []string{"tenants"}},
+		},
+		{
+			name:          "service account",
+			impersonation: Impersonation{ServiceAccountName: "reconciler", ServiceAccountNamespace: "tenant"},
+			want: rest.ImpersonationConfig{
+				UserName: "system:serviceaccount:tenant:reconciler",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:tenant"},
+			},
+		},
+		{
+			name:          "service account with groups",
+			impersonation: Impersonation{ServiceAccountName: "reconciler", ServiceAccountNamespace: "tenant", Groups: []string{"tenants"}},
+			want: rest.ImpersonationConfig{
+				UserName: "system:serviceaccount:tenant:reconciler",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:tenant", "tenants"},
+			},
+		},
+		{
+			name:          "service account without namespace",
+			impersonation: Impersonation{ServiceAccountName: "reconciler"},
+			wantErr:       true,
+		},
+		{
+			name:          "user and service account",
+			impersonation: Impersonation{UserName: "tenant", ServiceAccountName: "reconciler", ServiceAccountNamespace: "tenant"},
+			wantErr:       true,
+		},
+		{
+			name:          "groups without user",
+			impersonation: Impersonation{Groups: []string{"tenants"}},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.impersonation.ImpersonationConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImpersonationConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImpersonate_SharesApplyFallback(t *testing.T) {
+	rm := NewResourceManager(fake.NewClientBuilder().Build(), nil, Owner{Field: "manager", Group: "manager.io"})
+	unsupported := apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "PATCH",
+		schema.GroupResource{Resource: "configmaps"}, "test", "", 0, false)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rm.fallbackToClientSideApply(unsupported)
+	}()
+	impersonated, err := rm.Impersonate(&rest.Config{Host: "https://127.0.0.1:6443"}, Impersonation{UserName: "tenant"})
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind("ConfigMap")
+	if !impersonated.useClientSideApply(object) {
+		t.Error("expected the impersonating manager to share the client-side apply fallback")
+	}
+}
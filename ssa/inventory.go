@@ -0,0 +1,337 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// InventoryShardsAnnotation holds the number of objects the inventory is sharded across.
+	InventoryShardsAnnotation = "ssa.fluxcd.io/inventory-shards"
+
+	// inventoryKey is the data key holding the compressed inventory entries.
+	inventoryKey = "inventory"
+
+	// inventoryFieldOwner is the field manager of the inventory objects.
+	inventoryFieldOwner = "ssa-inventory"
+
+	// maxInventoryShardSize is the maximum size in bytes of the compressed entries stored in an object,
+	// it leaves room for the base64 encoding and the metadata within the 1MB object size limit.
+	maxInventoryShardSize = 700 * 1024
+)
+
+// Inventory stores the ChangeSet of the last apply, so that the objects removed from the
+// source can be garbage collected and the drift detection can be performed on the applied set.
+type Inventory interface {
+	// Read returns the stored ChangeSet, or an empty ChangeSet if the inventory doesn't exist.
+	Read(ctx context.Context) (*ChangeSet, error)
+
+	// Write stores the given ChangeSet, replacing the previous one.
+	Write(ctx context.Context, changeSet *ChangeSet) error
+
+	// Delete removes the inventory from the cluster.
+	Delete(ctx context.Context) error
+}
+
+// NewConfigMapInventory returns an Inventory stored in the binary data of the ConfigMap with
+// the given name, sharded across the ConfigMaps '<name>-1', '<name>-2', etc. when over 1MB.
+func NewConfigMapInventory(kubeClient client.Client, namespace, name string) Inventory {
+	return &objectInventory{
+		client:    kubeClient,
+		gvk:       schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		dataField: "binaryData",
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// NewSecretInventory returns an Inventory stored in the data of the Secret with the given
+// name, sharded across the Secrets '<name>-1', '<name>-2', etc. when over 1MB.
+func NewSecretInventory(kubeClient client.Client, namespace, name string) Inventory {
+	return &objectInventory{
+		client:    kubeClient,
+		gvk:       schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		dataField: "data",
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// inventoryEntry is the stored representation of a ChangeSetEntry.
+type inventoryEntry struct {
+	// ID is the object ID in the cli-utils format '<namespace>_<name>_<group>_<kind>'.
+	ID string `json:"id"`
+
+	// Version is the API version of the object.
+	Version string `json:"v"`
+
+	// Action is the action performed on the object.
+	Action string `json:"a,omitempty"`
+}
+
+// objectInventory stores the inventory in the data of ConfigMaps or Secrets.
+type objectInventory struct {
+	client    client.Client
+	gvk       schema.GroupVersionKind
+	dataField string
+	namespace string
+	name      string
+}
+
+// Read returns the ChangeSet stored in the inventory objects.
+func (i *objectInventory) Read(ctx context.Context) (*ChangeSet, error) {
+	primary, err := i.get(ctx, i.name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return NewChangeSet(), nil
+		}
+		return nil, err
+	}
+
+	shards, err := i.shardCount(primary)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for n := 0; n < shards; n++ {
+		obj := primary
+		if n > 0 {
+			if obj, err = i.get(ctx, i.shardName(n)); err != nil {
+				return nil, fmt.Errorf("%s inventory shard read failed, error: %w", FmtUnstructured(primary), err)
+			}
+		}
+		shard, err := i.shardData(obj)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, shard...)
+	}
+
+	return decodeInventory(data)
+}
+
+// Write stores the given ChangeSet in the inventory objects, and removes the shards
+// which are no longer needed.
+func (i *objectInventory) Write(ctx context.Context, changeSet *ChangeSet) error {
+	data, err := encodeInventory(changeSet)
+	if err != nil {
+		return err
+	}
+
+	var previousShards int
+	if primary, err := i.get(ctx, i.name); err == nil {
+		previousShards, _ = i.shardCount(primary)
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	shards := splitInventory(data, maxInventoryShardSize)
+	// Write the shards in reverse order, so that the primary object references
+	// only shards that have already been written.
+	for n := len(shards) - 1; n >= 0; n-- {
+		obj := i.newObject(i.shardName(n))
+		if n == 0 {
+			obj.SetAnnotations(map[string]string{InventoryShardsAnnotation: strconv.Itoa(len(shards))})
+		}
+		obj.Object[i.dataField] = map[string]interface{}{
+			inventoryKey: base64.StdEncoding.EncodeToString(shards[n]),
+		}
+
+		if err := i.client.Patch(ctx, obj, client.Apply, client.ForceOwnership, client.FieldOwner(inventoryFieldOwner)); err != nil {
+			return fmt.Errorf("%s inventory write failed, error: %w", FmtUnstructured(obj), err)
+		}
+	}
+
+	for n := len(shards); n < previousShards; n++ {
+		if err := i.deleteObject(ctx, i.shardName(n)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the inventory objects.
+func (i *objectInventory) Delete(ctx context.Context) error {
+	primary, err := i.get(ctx, i.name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	shards, err := i.shardCount(primary)
+	if err != nil {
+		return err
+	}
+
+	for n := shards - 1; n >= 0; n-- {
+		if err := i.deleteObject(ctx, i.shardName(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *objectInventory) newObject(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(i.gvk)
+	obj.SetNamespace(i.namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func (i *objectInventory) get(ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	obj := i.newObject(name)
+	if err := i.client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (i *objectInventory) deleteObject(ctx context.Context, name string) error {
+	obj := i.newObject(name)
+	if err := i.client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("%s inventory delete failed, error: %w", FmtUnstructured(obj), err)
+	}
+	return nil
+}
+
+// shardName returns the name of the nth inventory object.
+func (i *objectInventory) shardName(n int) string {
+	if n == 0 {
+		return i.name
+	}
+	return fmt.Sprintf("%s-%d", i.name, n)
+}
+
+// shardCount returns the number of shards recorded on the primary inventory object.
+func (i *objectInventory) shardCount(primary *unstructured.Unstructured) (int, error) {
+	value, ok := primary.GetAnnotations()[InventoryShardsAnnotation]
+	if !ok {
+		return 1, nil
+	}
+	shards, err := strconv.Atoi(value)
+	if err != nil || shards < 1 {
+		return 0, fmt.Errorf("%s invalid %s annotation '%s'", FmtUnstructured(primary), InventoryShardsAnnotation, value)
+	}
+	return shards, nil
+}
+
+// shardData returns the compressed entries stored in the given inventory object.
+func (i *objectInventory) shardData(obj *unstructured.Unstructured) ([]byte, error) {
+	value, _, err := unstructured.NestedString(obj.Object, i.dataField, inventoryKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s inventory read failed, error: %w", FmtUnstructured(obj), err)
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s inventory decode failed, error: %w", FmtUnstructured(obj), err)
+	}
+	return data, nil
+}
+
+// encodeInventory returns the gzip compressed JSON list of the given ChangeSet entries.
+func encodeInventory(changeSet *ChangeSet) ([]byte, error) {
+	entries := make([]inventoryEntry, 0, len(changeSet.Entries))
+	for _, entry := range changeSet.Entries {
+		entries = append(entries, inventoryEntry{
+			ID:      entry.ObjMetadata.String(),
+			Version: entry.GroupVersion,
+			Action:  entry.Action,
+		})
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeInventory returns the ChangeSet of the given compressed entries.
+func decodeInventory(data []byte) (*ChangeSet, error) {
+	changeSet := NewChangeSet()
+	if len(data) == 0 {
+		return changeSet, nil
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("inventory decompression failed, error: %w", err)
+	}
+	defer gr.Close()
+
+	raw, err := io.ReadAll(gr)
+	if err != nil {
+		return nil, fmt.Errorf("inventory decompression failed, error: %w", err)
+	}
+
+	var entries []inventoryEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("inventory decode failed, error: %w", err)
+	}
+
+	for _, entry := range entries {
+		id, err := object.ParseObjMetadata(entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("inventory decode failed, error: %w", err)
+		}
+		changeSet.Add(ChangeSetEntry{
+			ObjMetadata:  id,
+			GroupVersion: entry.Version,
+			Subject:      FmtObjMetadata(id),
+			Action:       entry.Action,
+		})
+	}
+	return changeSet, nil
+}
+
+// splitInventory splits the given data in shards of at most the given size.
+func splitInventory(data []byte, size int) [][]byte {
+	shards := [][]byte{}
+	for len(data) > size {
+		shards = append(shards, data[:size])
+		data = data[size:]
+	}
+	return append(shards, data)
+}
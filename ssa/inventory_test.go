@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func newInventoryChangeSet(names ...string) *ChangeSet {
+	changeSet := NewChangeSet()
+	for _, name := range names {
+		id := object.ObjMetadata{
+			GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+			Namespace: "default",
+			Name:      name,
+		}
+		changeSet.Add(ChangeSetEntry{
+			ObjMetadata:  id,
+			GroupVersion: "v1",
+			Subject:      FmtObjMetadata(id),
+			Action:       string(ConfiguredAction),
+		})
+	}
+	return changeSet
+}
+
+func TestInventoryEncoding(t *testing.T) {
+	changeSet := newInventoryChangeSet("app", "db")
+
+	data, err := encodeInventory(changeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeInventory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(changeSet, decoded); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	empty, err := decodeInventory(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Entries) != 0 {
+		t.Errorf("expected empty ChangeSet, got %v", empty.Entries)
+	}
+}
+
+func TestSplitInventory(t *testing.T) {
+	data := []byte("0123456789")
+
+	shards := splitInventory(data, 4)
+	if len(shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(shards))
+	}
+	if joined := bytes.Join(shards, nil); !bytes.Equal(joined, data) {
+		t.Errorf("expected %s, got %s", data, joined)
+	}
+
+	if shards := splitInventory(nil, 4); len(shards) != 1 {
+		t.Errorf("expected a single empty shard, got %d", len(shards))
+	}
+}
+
+func TestInventory(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for name, newInventory := range map[string]func(name string) Inventory{
+		"ConfigMap": func(name string) Inventory { return NewConfigMapInventory(manager.client, "default", name) },
+		"Secret":    func(name string) Inventory { return NewSecretInventory(manager.client, "default", name) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			inventory := newInventory(generateName("inventory"))
+
+			changeSet, err := inventory.Read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changeSet.Entries) != 0 {
+				t.Errorf("expected empty inventory, got %v", changeSet.Entries)
+			}
+
+			expected := newInventoryChangeSet("app", "db")
+			if err := inventory.Write(ctx, expected); err != nil {
+				t.Fatal(err)
+			}
+
+			changeSet, err = inventory.Read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, changeSet); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+
+			if err := inventory.Delete(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			changeSet, err = inventory.Read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changeSet.Entries) != 0 {
+				t.Errorf("expected empty inventory after delete, got %v", changeSet.Entries)
+			}
+		})
+	}
+}
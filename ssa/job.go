@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// JobStrategy defines how the Jobs, which can't be updated once started, are re-applied.
+type JobStrategy string
+
+const (
+	// JobStrategyFail surfaces the immutable field errors of the changed Jobs.
+	JobStrategyFail JobStrategy = "fail"
+
+	// JobStrategySkip leaves the existing Jobs untouched, even if their spec differs.
+	JobStrategySkip JobStrategy = "skip-if-exists"
+
+	// JobStrategyReplace deletes the existing Jobs whose spec differs, waits for their
+	// termination and recreates them.
+	JobStrategyReplace JobStrategy = "replace"
+)
+
+// JobStrategyAnnotation overrides the ApplyOptions JobStrategy for the annotated Job,
+// e.g. 'ssa.fluxcd.io/job-strategy: replace'.
+const JobStrategyAnnotation = "ssa.fluxcd.io/job-strategy"
+
+// jobReplaceAnnotations holds the annotations commonly used by the GitOps tools
+// to signal that an object must be replaced, and the matching values.
+var jobReplaceAnnotations = map[string]string{
+	"kustomize.toolkit.fluxcd.io/force": "enabled",
+	"argocd.argoproj.io/sync-options":   "Replace=true",
+}
+
+// IsJob checks if the given object is a Kubernetes Job.
+func IsJob(object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+	return gvk.Group == "batch" && gvk.Kind == "Job"
+}
+
+// jobStrategy returns the strategy of the given Job, based on its annotations
+// and on the default strategy.
+func jobStrategy(object *unstructured.Unstructured, defaultStrategy JobStrategy) JobStrategy {
+	annotations := object.GetAnnotations()
+	switch strategy := JobStrategy(strings.ToLower(annotations[JobStrategyAnnotation])); strategy {
+	case JobStrategyFail, JobStrategySkip, JobStrategyReplace:
+		return strategy
+	}
+
+	for key, val := range jobReplaceAnnotations {
+		if strings.Contains(annotations[key], val) {
+			return JobStrategyReplace
+		}
+	}
+
+	if defaultStrategy == "" {
+		return JobStrategyFail
+	}
+	return defaultStrategy
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestJobStrategy(t *testing.T) {
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		defaultStrategy JobStrategy
+		expected        JobStrategy
+	}{
+		{
+			name:     "defaults to fail",
+			expected: JobStrategyFail,
+		},
+		{
+			name:            "default strategy",
+			defaultStrategy: JobStrategySkip,
+			expected:        JobStrategySkip,
+		},
+		{
+			name:            "annotation overrides default",
+			annotations:     map[string]string{JobStrategyAnnotation: "replace"},
+			defaultStrategy: JobStrategySkip,
+			expected:        JobStrategyReplace,
+		},
+		{
+			name:        "Flux force annotation",
+			annotations: map[string]string{"kustomize.toolkit.fluxcd.io/force": "enabled"},
+			expected:    JobStrategyReplace,
+		},
+		{
+			name:        "Argo CD replace sync option",
+			annotations: map[string]string{"argocd.argoproj.io/sync-options": "Prune=false,Replace=true"},
+			expected:    JobStrategyReplace,
+		},
+		{
+			name:            "invalid annotation",
+			annotations:     map[string]string{JobStrategyAnnotation: "recreate"},
+			defaultStrategy: JobStrategySkip,
+			expected:        JobStrategySkip,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job := &unstructured.Unstructured{}
+			job.SetAPIVersion("batch/v1")
+			job.SetKind("Job")
+			job.SetName("test")
+			job.SetAnnotations(tc.annotations)
+
+			if !IsJob(job) {
+				t.Fatal("expected object to be a Job")
+			}
+			if got := jobStrategy(job, tc.defaultStrategy); got != tc.expected {
+				t.Errorf("expected strategy %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
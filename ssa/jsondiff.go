@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// JSONPatchOperation is a field change as specified by RFC 6902
+// https://www.rfc-editor.org/rfc/rfc6902
+type JSONPatchOperation struct {
+	// Operation is the type of change, one of 'add', 'remove' or 'replace'.
+	Operation string `json:"op"`
+
+	// Path is the JSON pointer of the changed field, e.g. '/spec/replicas'.
+	Path string `json:"path"`
+
+	// Value is the new value of the field, empty for 'remove' operations.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// diffIgnorePaths holds the paths of the fields set by the API server
+// which are not reported as changes.
+var diffIgnorePaths = []string{
+	"/metadata/managedFields",
+	"/metadata/resourceVersion",
+	"/metadata/generation",
+	"/metadata/creationTimestamp",
+	"/metadata/uid",
+	"/status",
+}
+
+// jsonPatchDiff returns the operations which transform the existing object into the desired one.
+// The maps are compared by key, the arrays of the same length by index, and the
+// arrays of different lengths are replaced as a whole.
+func jsonPatchDiff(existing, desired map[string]interface{}) []JSONPatchOperation {
+	var ops []JSONPatchOperation
+	diffMaps("", existing, desired, &ops)
+	return ops
+}
+
+func diffValues(path string, existing, desired interface{}, ops *[]JSONPatchOperation) {
+	if isIgnoredPath(path) {
+		return
+	}
+
+	existingMap, existingIsMap := existing.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if existingIsMap && desiredIsMap {
+		diffMaps(path, existingMap, desiredMap, ops)
+		return
+	}
+
+	existingSlice, existingIsSlice := existing.([]interface{})
+	desiredSlice, desiredIsSlice := desired.([]interface{})
+	if existingIsSlice && desiredIsSlice && len(existingSlice) == len(desiredSlice) {
+		for i := range existingSlice {
+			diffValues(fmt.Sprintf("%s/%d", path, i), existingSlice[i], desiredSlice[i], ops)
+		}
+		return
+	}
+
+	if !apiequality.Semantic.DeepEqual(existing, desired) {
+		*ops = append(*ops, JSONPatchOperation{Operation: "replace", Path: path, Value: desired})
+	}
+}
+
+func diffMaps(path string, existing, desired map[string]interface{}, ops *[]JSONPatchOperation) {
+	keys := make([]string, 0, len(existing)+len(desired))
+	for k := range existing {
+		keys = append(keys, k)
+	}
+	for k := range desired {
+		if _, ok := existing[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := path + "/" + escapeJSONPointer(k)
+		existingVal, inExisting := existing[k]
+		desiredVal, inDesired := desired[k]
+		switch {
+		case !inDesired:
+			if !isIgnoredPath(p) {
+				*ops = append(*ops, JSONPatchOperation{Operation: "remove", Path: p})
+			}
+		case !inExisting:
+			if !isIgnoredPath(p) {
+				*ops = append(*ops, JSONPatchOperation{Operation: "add", Path: p, Value: desiredVal})
+			}
+		default:
+			diffValues(p, existingVal, desiredVal, ops)
+		}
+	}
+}
+
+func isIgnoredPath(path string) bool {
+	for _, ignore := range diffIgnorePaths {
+		if path == ignore {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeJSONPointer escapes the given key as a JSON pointer reference token.
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
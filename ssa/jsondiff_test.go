@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONPatchDiff(t *testing.T) {
+	existing := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "app",
+			"resourceVersion": "1",
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/name": "app",
+				"tier":                   "backend",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80)},
+			},
+			"args": []interface{}{"--verbose"},
+		},
+		"status": map[string]interface{}{
+			"replicas": int64(1),
+		},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "app",
+			"resourceVersion": "2",
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/name": "app",
+				"team":                   "payments",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(8080)},
+			},
+			"args": []interface{}{"--verbose", "--debug"},
+		},
+		"status": map[string]interface{}{
+			"replicas": int64(2),
+		},
+	}
+
+	expected := []JSONPatchOperation{
+		{Operation: "add", Path: "/metadata/labels/team", Value: "payments"},
+		{Operation: "remove", Path: "/metadata/labels/tier"},
+		{Operation: "replace", Path: "/spec/args", Value: []interface{}{"--verbose", "--debug"}},
+		{Operation: "replace", Path: "/spec/ports/0/port", Value: int64(8080)},
+		{Operation: "replace", Path: "/spec/replicas", Value: int64(2)},
+	}
+
+	if diff := cmp.Diff(expected, jsonPatchDiff(existing, desired)); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	if ops := jsonPatchDiff(existing, existing); len(ops) != 0 {
+		t.Errorf("expected no operations, got %v", ops)
+	}
+}
+
+func TestEscapeJSONPointer(t *testing.T) {
+	if got := escapeJSONPointer("app.kubernetes.io/name~x"); got != "app.kubernetes.io~1name~0x" {
+		t.Errorf("unexpected escaped key %s", got)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// ManagedFields holds the fields owned by a field manager of an in-cluster object.
+type ManagedFields struct {
+	// Manager is the name of the field manager, e.g. 'kubectl-client-side-apply'.
+	Manager string `json:"manager"`
+
+	// Operation is the type of operation performed by the manager, can be 'Update' or 'Apply'.
+	Operation metav1.ManagedFieldsOperationType `json:"operation"`
+
+	// Subresource is the subresource written by the manager, e.g. 'status'.
+	Subresource string `json:"subresource,omitempty"`
+
+	// Fields holds the paths of the owned fields, e.g. '.spec.replicas'
+	// or '.spec.template.spec.containers[name="app"].image'.
+	Fields []string `json:"fields"`
+}
+
+// GetManagedFields returns the fields owned by each field manager of the given object,
+// the fields are listed in the order of the object metadata.managedFields.
+func GetManagedFields(object *unstructured.Unstructured) ([]ManagedFields, error) {
+	var result []ManagedFields
+	for _, entry := range object.GetManagedFields() {
+		managed := ManagedFields{
+			Manager:     entry.Manager,
+			Operation:   entry.Operation,
+			Subresource: entry.Subresource,
+			Fields:      []string{},
+		}
+		if entry.FieldsV1 != nil {
+			set, err := FieldsToSet(*entry.FieldsV1)
+			if err != nil {
+				return nil, fmt.Errorf("%s managedFields of '%s' parse failed, error: %w",
+					FmtUnstructured(object), entry.Manager, err)
+			}
+			set.Leaves().Iterate(func(path fieldpath.Path) {
+				managed.Fields = append(managed.Fields, path.String())
+			})
+			sort.Strings(managed.Fields)
+		}
+		result = append(result, managed)
+	}
+	return result, nil
+}
+
+// TakeOwnershipOptions contains options for the ownership transfer requests.
+type TakeOwnershipOptions struct {
+	// Managers defines the field managers from which the fields are transferred,
+	// e.g. 'kubectl-client-side-apply' with an 'Update' operation or 'helm' with an 'Update' operation.
+	Managers []FieldManager `json:"managers"`
+
+	// Fields defines the paths of the transferred fields, e.g. '.spec.replicas',
+	// the fields nested under the paths are transferred too.
+	// An empty Fields list means all the fields of the matching managers are transferred.
+	Fields []string `json:"fields,omitempty"`
+
+	// DryRun configures the engine to perform a server-side dry-run of the ownership transfer.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// TakeOwnership transfers the fields owned by the matching managers to the ResourceManager field owner,
+// with an apply operation, e.g. to migrate the objects managed by kubectl or Helm. The transferred fields
+// are removed from the previous managers, the managers left without fields are removed from the object.
+func (m *ResourceManager) TakeOwnership(ctx context.Context, object *unstructured.Unstructured, opts TakeOwnershipOptions) (*ChangeSetEntry, error) {
+	existingObject := object.DeepCopy()
+	if err := m.client.Get(ctx, client.ObjectKeyFromObject(object), existingObject); err != nil {
+		if apierrors.IsNotFound(err) {
+			return m.changeSetEntry(object, UnchangedAction), nil
+		}
+		return m.changeSetEntry(object, UnknownAction),
+			fmt.Errorf("%s query failed, error: %w", FmtUnstructured(object), err)
+	}
+
+	patches, err := patchTakeOwnership(existingObject, opts.Managers, opts.Fields, m.owner.Field)
+	if err != nil {
+		return m.changeSetEntry(object, UnknownAction),
+			fmt.Errorf("%s ownership transfer failed, error: %w", FmtUnstructured(object), err)
+	}
+	if len(patches) == 0 {
+		return m.changeSetEntry(existingObject, UnchangedAction), nil
+	}
+
+	rawPatch, err := json.Marshal(patches)
+	if err != nil {
+		return m.changeSetEntry(object, UnknownAction), err
+	}
+
+	patchOpts := []client.PatchOption{client.FieldOwner(m.owner.Field)}
+	if opts.DryRun {
+		patchOpts = append(patchOpts, client.DryRunAll)
+	}
+	if err := m.withRetry(ctx, func() error {
+		return m.client.Patch(ctx, existingObject, client.RawPatch(types.JSONPatchType, rawPatch), patchOpts...)
+	}); err != nil {
+		return m.changeSetEntry(object, UnknownAction),
+			fmt.Errorf("%s ownership transfer failed, error: %w", FmtUnstructured(object), err)
+	}
+
+	m.logger().Info("object ownership transferred", "object", FmtUnstructured(object), "dryRun", opts.DryRun)
+	return m.changeSetEntry(existingObject, ConfiguredAction), nil
+}
+
+// patchTakeOwnership returns a jsonPatch array for moving the given fields from the managers with matching
+// prefix and operation type to the specified manager name with an apply operation.
+func patchTakeOwnership(object *unstructured.Unstructured, managers []FieldManager, fields []string, name string) ([]jsonPatch, error) {
+	objEntries := object.GetManagedFields()
+
+	owner := metav1.ManagedFieldsEntry{
+		Manager:    name,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: object.GetAPIVersion(),
+	}
+	ownerSet := fieldpath.NewSet()
+	for _, entry := range objEntries {
+		if entry.Manager == name && entry.Operation == metav1.ManagedFieldsOperationApply && entry.Subresource == "" {
+			owner = entry
+			if entry.FieldsV1 != nil {
+				set, err := FieldsToSet(*entry.FieldsV1)
+				if err != nil {
+					return nil, fmt.Errorf("unable to parse managed fields of '%s': '%w'", entry.Manager, err)
+				}
+				ownerSet = &set
+			}
+		}
+	}
+
+	var patches []jsonPatch
+	entries := make([]metav1.ManagedFieldsEntry, 0, len(objEntries)+1)
+	edited := false
+	for _, entry := range objEntries {
+		if entry.Manager == owner.Manager && entry.Operation == owner.Operation && entry.Subresource == "" {
+			continue
+		}
+		if entry.FieldsV1 == nil || !matchesFieldManager(entry, managers) {
+			entries = append(entries, entry)
+			continue
+		}
+
+		set, err := FieldsToSet(*entry.FieldsV1)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse managed fields of '%s': '%w'", entry.Manager, err)
+		}
+
+		moved := fieldpath.NewSet()
+		set.Iterate(func(path fieldpath.Path) {
+			if hasFieldPrefix(path.String(), fields) {
+				moved.Insert(path)
+			}
+		})
+		if moved.Empty() {
+			entries = append(entries, entry)
+			continue
+		}
+
+		edited = true
+		ownerSet = ownerSet.Union(moved)
+		remaining := set.Difference(moved)
+		if remaining.Empty() {
+			continue
+		}
+		remainingFields, err := SetToFields(*remaining)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert managed set to field: %w", err)
+		}
+		entry.FieldsV1 = &remainingFields
+		entries = append(entries, entry)
+	}
+
+	if !edited {
+		return nil, nil
+	}
+
+	ownerFields, err := SetToFields(*ownerSet)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert managed set to field: %w", err)
+	}
+	owner.FieldsV1 = &ownerFields
+	owner.FieldsType = "FieldsV1"
+	entries = append(entries, owner)
+	return append(patches, newPatchReplace(managedFieldsPath, entries)), nil
+}
+
+// matchesFieldManager returns true if the entry matches any of the managers prefix and operation type.
+func matchesFieldManager(entry metav1.ManagedFieldsEntry, managers []FieldManager) bool {
+	for _, manager := range managers {
+		if strings.HasPrefix(entry.Manager, manager.Name) &&
+			entry.Operation == manager.OperationType &&
+			entry.Subresource == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFieldPrefix returns true if the path equals or is nested under any of
+// the prefixes, an empty prefixes list matches all paths.
+func hasFieldPrefix(path string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newManagedObject(entries ...metav1.ManagedFieldsEntry) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("apps/v1")
+	u.SetKind("Deployment")
+	u.SetName("podinfo")
+	u.SetNamespace("default")
+	u.SetManagedFields(entries)
+	return u
+}
+
+func newManagedFieldsEntry(manager string, op metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{
+		Manager:    manager,
+		Operation:  op,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func TestGetManagedFields(t *testing.T) {
+	object := newManagedObject(
+		newManagedFieldsEntry("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate,
+			`{"f:metadata":{"f:labels":{"f:app":{}}},"f:spec":{"f:replicas":{}}}`),
+		newManagedFieldsEntry("kube-controller-manager", metav1.ManagedFieldsOperationUpdate,
+			`{"f:status":{"f:conditions":{"k:{\"type\":\"Available\"}":{".":{},"f:status":{}}}}}`),
+	)
+
+	got, err := GetManagedFields(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ManagedFields{
+		{
+			Manager:   "kubectl-client-side-apply",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Fields:    []string{".metadata.labels.app", ".spec.replicas"},
+		},
+		{
+			Manager:   "kube-controller-manager",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Fields:    []string{`.status.conditions[type="Available"].status`},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
+
+func TestPatchTakeOwnership(t *testing.T) {
+	kubectl := []FieldManager{{Name: "kubectl", OperationType: metav1.ManagedFieldsOperationUpdate}}
+
+	tests := []struct {
+		name   string
+		object *unstructured.Unstructured
+		fields []string
+		want   []ManagedFields
+	}{
+		{
+			name: "transfers all fields",
+			object: newManagedObject(
+				newManagedFieldsEntry("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate,
+					`{"f:metadata":{"f:labels":{"f:app":{}}},"f:spec":{"f:replicas":{}}}`),
+			),
+			want: []ManagedFields{
+				{
+					Manager:   "test-owner",
+					Operation: metav1.ManagedFieldsOperationApply,
+					Fields:    []string{".metadata.labels.app", ".spec.replicas"},
+				},
+			},
+		},
+		{
+			name: "transfers the selected fields",
+			object: newManagedObject(
+				newManagedFieldsEntry("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate,
+					`{"f:metadata":{"f:labels":{"f:app":{}}},"f:spec":{"f:replicas":{}}}`),
+			),
+			fields: []string{".spec"},
+			want: []ManagedFields{
+				{
+					Manager:   "kubectl-client-side-apply",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					Fields:    []string{".metadata.labels.app"},
+				},
+				{
+					Manager:   "test-owner",
+					Operation: metav1.ManagedFieldsOperationApply,
+					Fields:    []string{".spec.replicas"},
+				},
+			},
+		},
+		{
+			name: "merges with the owner fields",
+			object: newManagedObject(
+				newManagedFieldsEntry("test-owner", metav1.ManagedFieldsOperationApply,
+					`{"f:metadata":{"f:labels":{"f:app":{}}}}`),
+				newManagedFieldsEntry("kubectl-edit", metav1.ManagedFieldsOperationUpdate,
+					`{"f:spec":{"f:replicas":{}}}`),
+				newManagedFieldsEntry("helm", metav1.ManagedFieldsOperationUpdate,
+					`{"f:spec":{"f:paused":{}}}`),
+			),
+			want: []ManagedFields{
+				{
+					Manager:   "helm",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					Fields:    []string{".spec.paused"},
+				},
+				{
+					Manager:   "test-owner",
+					Operation: metav1.ManagedFieldsOperationApply,
+					Fields:    []string{".metadata.labels.app", ".spec.replicas"},
+				},
+			},
+		},
+		{
+			name: "skips the objects without matching fields",
+			object: newManagedObject(
+				newManagedFieldsEntry("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate,
+					`{"f:metadata":{"f:labels":{"f:app":{}}}}`),
+			),
+			fields: []string{".spec"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches, err := patchTakeOwnership(tt.object, kubectl, tt.fields, "test-owner")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(patches) != 0 {
+					t.Fatalf("expected no patches, got %v", patches)
+				}
+				return
+			}
+			if len(patches) != 1 {
+				t.Fatalf("expected one patch, got %v", patches)
+			}
+
+			tt.object.SetManagedFields(patches[0].Value)
+			got, err := GetManagedFields(tt.object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -18,8 +18,14 @@ limitations under the License.
 package ssa
 
 import (
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -29,14 +35,57 @@ type ResourceManager struct {
 	client client.Client
 	poller *polling.StatusPoller
 	owner  Owner
+
+	// statusReaders are the custom status readers of the poller.
+	statusReaders []engine.StatusReader
+
+	// pollerOptions are the options of the poller built with the custom status readers.
+	pollerOptions polling.Options
+
+	// eventRecorder emits the events of the apply and delete actions, if not nil.
+	eventRecorder record.EventRecorder
+
+	// eventObject is the object on which the events are recorded, if not nil.
+	eventObject runtime.Object
+
+	// metricsRecorder receives the operations measurements, if not nil.
+	metricsRecorder MetricsRecorder
+
+	// log receives the debug information of the operations, discarded if unset.
+	log logr.Logger
+
+	// less orders the objects of ApplyAll, DeleteAll and DiffAll, defaults to the ReconcileOrder.
+	less LessFunc
+
+	// retryBackoff is the backoff of the apply and delete requests failing with transient errors.
+	retryBackoff wait.Backoff
+
+	// clientSideApplyKinds are the kinds applied with a client-side merge patch.
+	clientSideApplyKinds []schema.GroupKind
+
+	// serverSideApplyUnsupported is set to 1 when the API server rejects the server-side apply requests,
+	// it's shared by the copies of the ResourceManager which target the same cluster.
+	serverSideApplyUnsupported *int32
+
+	// ignoreRules are the fields ignored on apply and diff for all the objects.
+	ignoreRules []IgnoreRule
+
+	// preApplyHook is invoked before each object is applied, if not nil.
+	preApplyHook PreApplyFunc
+
+	// postApplyHook is invoked after each object is reconciled by Apply and ApplyAll, if not nil.
+	postApplyHook PostApplyFunc
 }
 
 // NewResourceManager creates a ResourceManager for the given Kubernetes client.
 func NewResourceManager(client client.Client, poller *polling.StatusPoller, owner Owner) *ResourceManager {
 	return &ResourceManager{
-		client: client,
-		poller: poller,
-		owner:  owner,
+		client:       client,
+		poller:       poller,
+		owner:        owner,
+		retryBackoff: DefaultRetryBackoff,
+
+		serverSideApplyUnsupported: new(int32),
 	}
 }
 
@@ -45,6 +94,43 @@ func (m *ResourceManager) Client() client.Client {
 	return m.client
 }
 
+// WithLogger returns a copy of the ResourceManager which logs the debug information of the operations,
+// e.g. the objects dry-run results, the actions performed and their duration, at verbosity level 1.
+// The copy shares the client-side apply fallback state with the ResourceManager.
+func (m *ResourceManager) WithLogger(log logr.Logger) *ResourceManager {
+	rm := *m
+	rm.log = log
+	return &rm
+}
+
+// logger returns the logger of the ResourceManager, or a logger which discards all messages.
+func (m *ResourceManager) logger() logr.Logger {
+	if m.log.GetSink() == nil {
+		return logr.Discard()
+	}
+	return m.log.V(1)
+}
+
+// SetSortFunc overrides the order in which ApplyAll, DiffAll and DeleteAll reconcile the objects,
+// e.g. with a custom KindOrder LessFunc. DeleteAll reconciles the objects in the reverse order.
+// A nil less function restores the default ReconcileOrder.
+func (m *ResourceManager) SetSortFunc(less LessFunc) {
+	m.less = less
+}
+
+// sortObjects sorts the given objects in the reconcile order, or in the reverse order if reverse is true.
+func (m *ResourceManager) sortObjects(objects []*unstructured.Unstructured, reverse bool) {
+	less := m.less
+	if less == nil {
+		less = ReconcileOrder.LessFunc()
+	}
+	if reverse {
+		SortObjects(objects, func(i, j *unstructured.Unstructured) bool { return less(j, i) })
+		return
+	}
+	SortObjects(objects, less)
+}
+
 // SetOwnerLabels adds the ownership labels to the given objects.
 // The ownership labels are in the format:
 //
@@ -21,11 +21,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -34,16 +39,66 @@ type ApplyOptions struct {
 	// Force configures the engine to recreate objects that contain immutable field changes.
 	Force bool `json:"force"`
 
+	// RecreateImmutable configures the engine to delete the objects that fail to apply due to
+	// immutable field changes, e.g. a Service clusterIP or a StatefulSet volumeClaimTemplates,
+	// wait for their termination and recreate them.
+	RecreateImmutable bool `json:"recreateImmutable"`
+
+	// IgnoreAnnotation is the annotation which exempts the objects from ApplyAll when set to 'true'.
+	// An empty IgnoreAnnotation disables the check, set it to DefaultIgnoreAnnotation to enable it.
+	IgnoreAnnotation string `json:"ignoreAnnotation,omitempty"`
+
+	// LabelSelector filters the objects applied by ApplyAll, e.g. 'app.kubernetes.io/part-of=podinfo'.
+	// An empty LabelSelector means all objects are applied.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// SkipHelmHooks exempts the objects with the 'helm.sh/hook' annotation from ApplyAll,
+	// e.g. the test and upgrade Jobs rendered from a Helm chart.
+	SkipHelmHooks bool `json:"skipHelmHooks,omitempty"`
+
+	// Revision is the source revision of the objects, e.g. a Git commit SHA,
+	// included in the Kubernetes events when an event recorder is set, and in the ChangeSet entries.
+	Revision string `json:"revision,omitempty"`
+
+	// JobStrategy defines how the existing Jobs are re-applied, defaults to JobStrategyFail.
+	// The strategy can be overridden per Job with the JobStrategyAnnotation.
+	JobStrategy JobStrategy `json:"jobStrategy,omitempty"`
+
+	// IgnoreRules defines the fields managed by other controllers, which keep their in-cluster values,
+	// in addition to the fields listed in the objects IgnoreFieldsAnnotation.
+	IgnoreRules []IgnoreRule `json:"ignoreRules,omitempty"`
+
+	// ConflictPolicies defines per kind and per field manager how the conflicts with the fields
+	// owned by other managers are resolved, the first matching policy wins.
+	// The ownership of the fields not matching any policy is forced.
+	ConflictPolicies []ConflictPolicy `json:"conflictPolicies,omitempty"`
+
 	// Exclusions determines which in-cluster objects are skipped from apply
 	// based on the specified key-value pairs.
 	// A nil Exclusions map means all objects are applied
 	// regardless of their metadata labels and annotations.
 	Exclusions map[string]string `json:"exclusions"`
 
+	// Concurrency is the number of concurrent dry-run and apply requests performed by ApplyAll
+	// for the objects of the same stage, defaults to one request at a time.
+	Concurrency int `json:"concurrency,omitempty"`
+
 	// WaitTimeout defines after which interval should the engine give up on waiting for
 	// cluster scoped resources to become ready.
 	WaitTimeout time.Duration `json:"waitTimeout"`
 
+	// KindMatchTimeout defines for how long the objects whose kinds are not known to the RESTMapper,
+	// e.g. the custom resources of the CRDs applied in the same set, are retried before failing.
+	// The zero value fails Apply and ApplyAll as soon as a kind is not matched, set it to e.g. 30 seconds
+	// to wait for the unknown kinds to be served. ApplyAllStaged defaults it to the WaitTimeout.
+	KindMatchTimeout time.Duration `json:"kindMatchTimeout,omitempty"`
+
+	// SkipMissingKinds configures ApplyAll to skip the objects whose kinds are not served, e.g. the custom
+	// resources of optional CRDs which are not installed. The objects whose kinds are declared by the CRDs
+	// of the same set are skipped only if their kinds are still not served after the KindMatchTimeout.
+	// The skipped objects are reported in the ChangeSet with the SkippedAction.
+	SkipMissingKinds bool `json:"skipMissingKinds,omitempty"`
+
 	// Cleanup defines which in-cluster metadata entries are to be removed before applying objects.
 	Cleanup ApplyCleanupOptions `json:"cleanup"`
 }
@@ -59,11 +114,63 @@ type ApplyCleanupOptions struct {
 	// FieldManagers defines which `metadata.managedFields` managers should be removed from in-cluster objects.
 	FieldManagers []FieldManager `json:"fieldManagers,omitempty"`
 
+	// KubectlMigration claims the fields managed by 'kubectl apply' and removes the
+	// 'kubectl.kubernetes.io/last-applied-configuration' annotation from in-cluster objects,
+	// in addition to the Annotations and FieldManagers entries.
+	// The migration is disabled by default, set it to true to take over the objects
+	// previously applied with 'kubectl apply' in client-side mode.
+	KubectlMigration bool `json:"kubectlMigration,omitempty"`
+
 	// Exclusions determines which in-cluster objects are skipped from cleanup
 	// based on the specified key-value pairs.
 	Exclusions map[string]string `json:"exclusions"`
 }
 
+// kubectlFieldManagers holds the managers of the fields set with 'kubectl apply' in client-side mode.
+var kubectlFieldManagers = []FieldManager{
+	{
+		Name:          "kubectl-client-side-apply",
+		OperationType: metav1.ManagedFieldsOperationUpdate,
+	},
+	{
+		Name:          "before-first-apply",
+		OperationType: metav1.ManagedFieldsOperationUpdate,
+	},
+}
+
+// withKubectlMigration returns a copy of the cleanup options with the kubectl
+// client-side apply annotation and field managers added if KubectlMigration is set.
+func (o ApplyCleanupOptions) withKubectlMigration() ApplyCleanupOptions {
+	if !o.KubectlMigration {
+		return o
+	}
+
+	annotations := make([]string, 0, len(o.Annotations)+1)
+	annotations = append(annotations, o.Annotations...)
+	if !containsItemString(annotations, corev1.LastAppliedConfigAnnotation) {
+		annotations = append(annotations, corev1.LastAppliedConfigAnnotation)
+	}
+	o.Annotations = annotations
+
+	managers := make([]FieldManager, 0, len(o.FieldManagers)+len(kubectlFieldManagers))
+	managers = append(managers, o.FieldManagers...)
+	for _, manager := range kubectlFieldManagers {
+		found := false
+		for _, m := range managers {
+			if m == manager {
+				found = true
+				break
+			}
+		}
+		if !found {
+			managers = append(managers, manager)
+		}
+	}
+	o.FieldManagers = managers
+
+	return o
+}
+
 // DefaultApplyOptions returns the default apply options where force apply is disabled.
 func DefaultApplyOptions() ApplyOptions {
 	return ApplyOptions{
@@ -76,7 +183,15 @@ func DefaultApplyOptions() ApplyOptions {
 // Apply performs a server-side apply of the given object if the matching in-cluster object is different or if it doesn't exist.
 // Drift detection is performed by comparing the server-side dry-run result with the existing object.
 // When immutable field changes are detected, the object is recreated if 'force' is set to 'true'.
+// When the object is a Namespace being deleted, its termination is awaited before recreating it.
 func (m *ResourceManager) Apply(ctx context.Context, object *unstructured.Unstructured, opts ApplyOptions) (*ChangeSetEntry, error) {
+	entry, err := m.applyObject(ctx, object, opts)
+	m.postApply(ctx, object, entry, err)
+	return entry, err
+}
+
+// applyObject performs the dry-run and the apply of the given object for Apply.
+func (m *ResourceManager) applyObject(ctx context.Context, object *unstructured.Unstructured, opts ApplyOptions) (*ChangeSetEntry, error) {
 	existingObject := object.DeepCopy()
 	_ = m.client.Get(ctx, client.ObjectKeyFromObject(object), existingObject)
 
@@ -84,27 +199,52 @@ func (m *ResourceManager) Apply(ctx context.Context, object *unstructured.Unstru
 		return m.changeSetEntry(object, UnchangedAction), nil
 	}
 
+	if m.skipJob(object, existingObject, opts) {
+		return m.changeSetEntry(object, UnchangedAction), nil
+	}
+
+	if isTerminatingNamespace(existingObject) {
+		if err := m.waitForNamespaceTermination(existingObject, opts); err != nil {
+			return nil, err
+		}
+		return m.applyObject(ctx, object, opts)
+	}
+
+	object = m.ignoreFields(object, existingObject, opts.IgnoreRules)
+	object, err := m.yieldConflicts(object, existingObject, opts.ConflictPolicies)
+	if err != nil {
+		return nil, err
+	}
+
 	dryRunObject := object.DeepCopy()
 	if err := m.dryRunApply(ctx, dryRunObject); err != nil {
-		if opts.Force && IsImmutableError(err) {
-			if err := m.client.Delete(ctx, existingObject); err != nil {
-				return nil, fmt.Errorf("%s immutable field detected, failed to delete object, error: %w",
-					FmtUnstructured(dryRunObject), err)
+		if m.shouldRecreate(object, err, opts) {
+			if err := m.deleteImmutable(ctx, object, existingObject, opts); err != nil {
+				return nil, err
+			}
+			return m.applyObject(ctx, object, opts)
+		}
+
+		if opts.KindMatchTimeout > 0 && IsNoMatchError(err) {
+			if err := m.waitForKindMatch(ctx, []*unstructured.Unstructured{object}, opts); err != nil {
+				return nil, err
 			}
-			return m.Apply(ctx, object, opts)
+			opts.KindMatchTimeout = 0
+			return m.applyObject(ctx, object, opts)
 		}
 
 		return nil, m.validationError(dryRunObject, err)
 	}
 
-	patched, err := m.cleanupMetadata(ctx, object, existingObject, opts.Cleanup)
+	cleanup, err := m.planMetadataCleanup(object, existingObject, opts.Cleanup)
 	if err != nil {
 		return nil, fmt.Errorf("%s metadata.managedFields cleanup failed, error: %w",
 			FmtUnstructured(existingObject), err)
 	}
 
 	// do not apply objects that have not drifted to avoid bumping the resource version
-	if !patched && !m.hasDrifted(existingObject, dryRunObject) {
+	if cleanup == nil && !m.hasDrifted(existingObject, dryRunObject) {
+		m.logger().Info("object unchanged", "object", FmtUnstructured(object))
 		return m.changeSetEntry(object, UnchangedAction), nil
 	}
 
@@ -119,88 +259,327 @@ func (m *ResourceManager) Apply(ctx context.Context, object *unstructured.Unstru
 		appliedObject.SetAnnotations(annotations)
 	}
 
-	if err := m.apply(ctx, appliedObject); err != nil {
-		return nil, fmt.Errorf("%s apply failed, error: %w", FmtUnstructured(appliedObject), err)
+	action := ConfiguredAction
+	if dryRunObject.GetResourceVersion() == "" {
+		action = CreatedAction
+	}
+	if err := m.preApply(ctx, appliedObject, dryRunObject, action); err != nil {
+		return nil, err
 	}
 
-	if dryRunObject.GetResourceVersion() == "" {
-		return m.changeSetEntry(appliedObject, CreatedAction), nil
+	// the in-cluster object is modified only after the pre-apply hook allowed the apply
+	if err := m.cleanupMetadata(ctx, cleanup); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if err := m.apply(ctx, appliedObject); err != nil {
+		return nil, fmt.Errorf("%s apply failed, error: %w", FmtUnstructured(appliedObject), err)
 	}
 
-	return m.changeSetEntry(appliedObject, ConfiguredAction), nil
+	m.logger().Info("object applied", "object", FmtUnstructured(appliedObject),
+		"action", action, "duration", time.Since(start))
+	m.recordEvent(appliedObject, action, opts.Revision)
+	return m.changeSetEntry(appliedObject, action), nil
 }
 
 // ApplyAll performs a server-side dry-run of the given objects, and based on the diff result,
 // it applies the objects that are new or modified.
+// When the objects have dependencies declared with the DependsOnAnnotation, they are applied
+// in waves, and each wave must become ready before the next one is applied.
+// The objects with the ignore annotation, or not matching the label selector, are skipped
+// and reported in the ChangeSet with the SkippedAction.
 func (m *ResourceManager) ApplyAll(ctx context.Context, objects []*unstructured.Unstructured, opts ApplyOptions) (*ChangeSet, error) {
-	sort.Sort(SortableUnstructureds(objects))
+	start := time.Now()
+	changeSet, err := m.applyAllWaves(ctx, objects, opts)
+	changeSet.setRevision(opts.Revision)
+	m.recordOperation(ApplyOperation, start, changeSet, err)
+	m.logger().Info("apply completed", "objects", len(objects),
+		"duration", time.Since(start), "error", err)
+	return changeSet, err
+}
+
+// applyAllWaves applies the given objects in waves ordered by their dependencies.
+func (m *ResourceManager) applyAllWaves(ctx context.Context, objects []*unstructured.Unstructured, opts ApplyOptions) (*ChangeSet, error) {
+	objects, skipped, err := filterObjects(objects, opts.IgnoreAnnotation, opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SkipHelmHooks {
+		var hooks []*unstructured.Unstructured
+		objects, hooks = SplitHelmHooks(objects)
+		skipped = append(skipped, hooks...)
+	}
+
 	changeSet := NewChangeSet()
-	var toApply []*unstructured.Unstructured
-	for _, object := range objects {
-		existingObject := object.DeepCopy()
-		_ = m.client.Get(ctx, client.ObjectKeyFromObject(object), existingObject)
+	for _, object := range skipped {
+		changeSet.Add(*m.changeSetEntry(object, SkippedAction))
+	}
 
-		if existingObject != nil && AnyInMetadata(existingObject, opts.Exclusions) {
-			changeSet.Add(*m.changeSetEntry(existingObject, UnchangedAction))
-			continue
+	waves, err := SortByDependencies(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	// the kinds of the CRDs in the set, for which the objects are not skipped until their kinds are matched
+	declared := make(map[schema.GroupVersionKind]bool)
+	for _, gvk := range servedKinds(objects) {
+		declared[gvk] = true
+	}
+
+	for i, wave := range waves {
+		cs, err := m.applyAll(ctx, wave, opts, declared)
+		if err != nil {
+			return nil, err
+		}
+		changeSet.Append(cs.Entries)
+
+		if i < len(waves)-1 {
+			wave = withoutSkipped(wave, cs)
+			m.logger().Info("waiting for dependencies", "wave", i+1, "objects", len(wave))
+			if err := m.Wait(wave, WaitOptions{Interval: 2 * time.Second, Timeout: opts.WaitTimeout}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return changeSet, nil
+}
+
+// withoutSkipped returns the objects which are not reported as skipped in the given ChangeSet.
+func withoutSkipped(objects []*unstructured.Unstructured, changeSet *ChangeSet) []*unstructured.Unstructured {
+	skipped := make(map[object.ObjMetadata]bool)
+	for _, entry := range changeSet.Entries {
+		if entry.Action == string(SkippedAction) {
+			skipped[entry.ObjMetadata] = true
 		}
+	}
+	if len(skipped) == 0 {
+		return objects
+	}
+
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if !skipped[object.UnstructuredToObjMetadata(obj)] {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// applyAll performs a server-side dry-run of the given objects, and based on the diff result,
+// it applies the objects that are new or modified, regardless of their dependencies.
+// The dry-run and apply requests are performed concurrently, up to the options Concurrency.
+func (m *ResourceManager) applyAll(ctx context.Context, objects []*unstructured.Unstructured,
+	opts ApplyOptions, declared map[schema.GroupVersionKind]bool) (*ChangeSet, error) {
+	m.sortObjects(objects, false)
 
-		dryRunObject := object.DeepCopy()
-		if err := m.dryRunApply(ctx, dryRunObject); err != nil {
-			if opts.Force && IsImmutableError(err) {
-				if err := m.client.Delete(ctx, existingObject); err != nil {
-					return nil, fmt.Errorf("%s immutable field detected, failed to delete object, error: %w",
-						FmtUnstructured(dryRunObject), err)
+	plans := make([]applyPlan, len(objects))
+	runConcurrently(opts.Concurrency, len(objects), func(i int) {
+		plans[i] = m.planApply(ctx, objects[i], opts)
+	})
+
+	changeSet := NewChangeSet()
+	var toApply []applyPlan
+	var unmatched []*unstructured.Unstructured
+	recreated := false
+	for i, plan := range plans {
+		if plan.err != nil {
+			if IsNoMatchError(plan.err) {
+				// the objects of kinds not declared by the set CRDs are skipped without waiting
+				if opts.SkipMissingKinds && (opts.KindMatchTimeout == 0 || !declared[objects[i].GroupVersionKind()]) {
+					m.logger().Info("object skipped, kind not found", "object", FmtUnstructured(objects[i]))
+					changeSet.Add(*m.changeSetEntry(objects[i], SkippedAction))
+					continue
 				}
-				return m.ApplyAll(ctx, objects, opts)
+				if opts.KindMatchTimeout > 0 {
+					unmatched = append(unmatched, objects[i])
+					continue
+				}
+			}
+			m.recordFailure(objects[i].GroupVersionKind().GroupKind(), ApplyOperation)
+			m.postApply(ctx, objects[i], nil, plan.err)
+			return nil, plan.err
+		}
+		if plan.recreated {
+			recreated = true
+			continue
+		}
+		changeSet.Add(*plan.entry)
+		if plan.object != nil {
+			toApply = append(toApply, plan)
+		}
+	}
+
+	// the objects with immutable field changes have been deleted, start over
+	if recreated {
+		return m.applyAll(ctx, objects, opts, declared)
+	}
+
+	for i, plan := range plans {
+		if plan.err == nil && plan.object == nil {
+			m.postApply(ctx, objects[i], plan.entry, nil)
+		}
+	}
+
+	errs := make([]error, len(toApply))
+	runConcurrently(opts.Concurrency, len(toApply), func(i int) {
+		appliedObject := toApply[i].object.DeepCopy()
+		action := Action(toApply[i].entry.Action)
+		defer func() {
+			entry := toApply[i].entry
+			if errs[i] != nil {
+				entry = nil
 			}
+			m.postApply(ctx, appliedObject, entry, errs[i])
+		}()
 
-			return nil, m.validationError(dryRunObject, err)
+		if err := m.preApply(ctx, appliedObject, toApply[i].dryRunObject, action); err != nil {
+			errs[i] = err
+			return
 		}
 
-		patched, err := m.cleanupMetadata(ctx, object, existingObject, opts.Cleanup)
+		if err := m.cleanupMetadata(ctx, toApply[i].cleanup); err != nil {
+			errs[i] = err
+			return
+		}
+
+		start := time.Now()
+		if err := m.apply(ctx, appliedObject); err != nil {
+			errs[i] = fmt.Errorf("%s apply failed, error: %w", FmtUnstructured(appliedObject), err)
+			return
+		}
+		m.logger().Info("object applied", "object", FmtUnstructured(appliedObject),
+			"action", action, "duration", time.Since(start))
+		m.recordEvent(appliedObject, action, opts.Revision)
+	})
+	for i, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("%s metadata.managedFields cleanup failed, error: %w",
-				FmtUnstructured(existingObject), err)
+			m.recordFailure(toApply[i].object.GroupVersionKind().GroupKind(), ApplyOperation)
+			return nil, err
 		}
+	}
 
-		// Merge Workload Identity annotation with source controlled version
-		if existingObject.GetAnnotations()["iam.gke.io/gcp-service-account"] != "" {
-			annotations := dryRunObject.GetAnnotations()
-			if len(annotations) == 0 {
-				annotations = make(map[string]string)
+	// the objects whose kinds were not served, e.g. the custom resources
+	// of the CRDs applied above, are applied once their kinds are matched,
+	// or skipped after the timeout if SkipMissingKinds is set
+	if len(unmatched) > 0 {
+		if err := m.waitForKindMatch(ctx, unmatched, opts); err != nil && !opts.SkipMissingKinds {
+			for _, object := range unmatched {
+				m.recordFailure(object.GroupVersionKind().GroupKind(), ApplyOperation)
 			}
-			annotations["iam.gke.io/gcp-service-account"] = existingObject.GetAnnotations()["iam.gke.io/gcp-service-account"]
-			dryRunObject.SetAnnotations(annotations)
-			object.SetAnnotations(annotations)
+			return nil, err
+		}
+		opts.KindMatchTimeout = 0
+		cs, err := m.applyAll(ctx, unmatched, opts, declared)
+		if err != nil {
+			return nil, err
 		}
+		changeSet.Append(cs.Entries)
+	}
+
+	return changeSet, nil
+}
+
+// applyPlan is the result of the server-side dry-run of an object.
+type applyPlan struct {
+	// entry is the change set entry of the object.
+	entry *ChangeSetEntry
+
+	// object is the object to apply, nil if the object has not drifted.
+	object *unstructured.Unstructured
+
+	// dryRunObject is the server-side dry-run result of the object to apply.
+	dryRunObject *unstructured.Unstructured
+
+	// cleanup is the metadata cleanup performed before applying the object, nil if not needed.
+	cleanup *metadataCleanup
+
+	// recreated is true if the object was deleted due to immutable field changes.
+	recreated bool
+
+	err error
+}
+
+// planApply performs a server-side dry-run of the given object, and based on the diff result,
+// it determines whether the object should be applied.
+func (m *ResourceManager) planApply(ctx context.Context, object *unstructured.Unstructured, opts ApplyOptions) applyPlan {
+	existingObject := object.DeepCopy()
+	_ = m.client.Get(ctx, client.ObjectKeyFromObject(object), existingObject)
+
+	if existingObject != nil && AnyInMetadata(existingObject, opts.Exclusions) {
+		return applyPlan{entry: m.changeSetEntry(existingObject, UnchangedAction)}
+	}
+
+	if m.skipJob(object, existingObject, opts) {
+		return applyPlan{entry: m.changeSetEntry(existingObject, UnchangedAction)}
+	}
+
+	if isTerminatingNamespace(existingObject) {
+		if err := m.waitForNamespaceTermination(existingObject, opts); err != nil {
+			return applyPlan{err: err}
+		}
+		return m.planApply(ctx, object, opts)
+	}
 
-		if patched || m.hasDrifted(existingObject, dryRunObject) {
-			toApply = append(toApply, object)
-			if dryRunObject.GetResourceVersion() == "" {
-				changeSet.Add(*m.changeSetEntry(dryRunObject, CreatedAction))
-			} else {
-				changeSet.Add(*m.changeSetEntry(dryRunObject, ConfiguredAction))
+	object = m.ignoreFields(object, existingObject, opts.IgnoreRules)
+	object, err := m.yieldConflicts(object, existingObject, opts.ConflictPolicies)
+	if err != nil {
+		return applyPlan{err: err}
+	}
+
+	dryRunObject := object.DeepCopy()
+	if err := m.dryRunApply(ctx, dryRunObject); err != nil {
+		if m.shouldRecreate(object, err, opts) {
+			if err := m.deleteImmutable(ctx, object, existingObject, opts); err != nil {
+				return applyPlan{err: err}
 			}
-		} else {
-			changeSet.Add(*m.changeSetEntry(dryRunObject, UnchangedAction))
+			return applyPlan{recreated: true}
 		}
+
+		return applyPlan{err: m.validationError(dryRunObject, err)}
 	}
 
-	for _, object := range toApply {
-		appliedObject := object.DeepCopy()
-		if err := m.apply(ctx, appliedObject); err != nil {
-			return nil, fmt.Errorf("%s apply failed, error: %w", FmtUnstructured(appliedObject), err)
+	cleanup, err := m.planMetadataCleanup(object, existingObject, opts.Cleanup)
+	if err != nil {
+		return applyPlan{err: fmt.Errorf("%s metadata.managedFields cleanup failed, error: %w",
+			FmtUnstructured(existingObject), err)}
+	}
+
+	// Merge Workload Identity annotation with source controlled version
+	if existingObject.GetAnnotations()["iam.gke.io/gcp-service-account"] != "" {
+		annotations := dryRunObject.GetAnnotations()
+		if len(annotations) == 0 {
+			annotations = make(map[string]string)
 		}
+		annotations["iam.gke.io/gcp-service-account"] = existingObject.GetAnnotations()["iam.gke.io/gcp-service-account"]
+		dryRunObject.SetAnnotations(annotations)
+		object.SetAnnotations(annotations)
 	}
 
-	return changeSet, nil
+	if cleanup == nil && !m.hasDrifted(existingObject, dryRunObject) {
+		m.logger().Info("object unchanged", "object", FmtUnstructured(object))
+		return applyPlan{entry: m.changeSetEntry(dryRunObject, UnchangedAction)}
+	}
+
+	plan := applyPlan{object: object, dryRunObject: dryRunObject, cleanup: cleanup}
+	if dryRunObject.GetResourceVersion() == "" {
+		plan.entry = m.changeSetEntry(dryRunObject, CreatedAction)
+	} else {
+		plan.entry = m.changeSetEntry(dryRunObject, ConfiguredAction)
+	}
+	return plan
 }
 
 // ApplyAllStaged extracts the CRDs and Namespaces, applies them with ApplyAll,
 // waits for CRDs and Namespaces to become ready, then is applies all the other objects.
+// The CRDs are ready when Established and their kinds are served by the API discovery,
+// the custom resources failing with no kind match errors are retried until the KindMatchTimeout,
+// or until the WaitTimeout if the KindMatchTimeout is zero.
 // This function should be used when the given objects have a mix of custom resource definition and custom resources,
 // or a mix of namespace definitions with namespaced objects.
+// The dependencies declared with the DependsOnAnnotation are honored within each stage.
 func (m *ResourceManager) ApplyAllStaged(ctx context.Context, objects []*unstructured.Unstructured, opts ApplyOptions) (*ChangeSet, error) {
 	changeSet := NewChangeSet()
 
@@ -225,8 +604,31 @@ func (m *ResourceManager) ApplyAllStaged(ctx context.Context, objects []*unstruc
 		}
 		changeSet.Append(cs.Entries)
 
-		if err := m.Wait(stageOne, WaitOptions{2 * time.Second, opts.WaitTimeout}); err != nil {
-			return nil, err
+		// the skipped objects may not exist in the cluster, hence they are not waited for
+		applied := withoutSkipped(stageOne, cs)
+		waitOpts := WaitOptions{Interval: 2 * time.Second, Timeout: opts.WaitTimeout}
+		if len(applied) > 0 {
+			if err := m.Wait(applied, waitOpts); err != nil {
+				return nil, err
+			}
+		}
+
+		if kinds := servedKinds(applied); len(kinds) > 0 {
+			if err := m.waitForKinds(ctx, kinds, waitOpts); err != nil {
+				return nil, err
+			}
+
+			// the custom resources are retried by ApplyAll until their kinds are matched
+			if opts.KindMatchTimeout == 0 {
+				opts.KindMatchTimeout = opts.WaitTimeout
+			}
+			cs, err := m.ApplyAll(ctx, stageTwo, opts)
+			if err != nil {
+				return nil, err
+			}
+			changeSet.Append(cs.Entries)
+
+			return changeSet, nil
 		}
 	}
 
@@ -239,35 +641,191 @@ func (m *ResourceManager) ApplyAllStaged(ctx context.Context, objects []*unstruc
 	return changeSet, nil
 }
 
+// servedKinds returns the GroupVersionKinds served by the given CRDs.
+func servedKinds(objects []*unstructured.Unstructured) []schema.GroupVersionKind {
+	var kinds []schema.GroupVersionKind
+	for _, object := range objects {
+		if object.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+
+		group, _, _ := unstructured.NestedString(object.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(object.Object, "spec", "names", "kind")
+		versions, _, _ := unstructured.NestedSlice(object.Object, "spec", "versions")
+		for _, v := range versions {
+			version, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := version["name"].(string)
+			if served, _ := version["served"].(bool); served && name != "" {
+				kinds = append(kinds, schema.GroupVersionKind{Group: group, Version: name, Kind: kind})
+			}
+		}
+	}
+	return kinds
+}
+
+// waitForKinds waits for the discovery to include the given kinds,
+// the RESTMapper is reset between polls if it supports it.
+func (m *ResourceManager) waitForKinds(ctx context.Context, kinds []schema.GroupVersionKind, opts WaitOptions) error {
+	mapper := m.client.RESTMapper()
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, opts.Interval, opts.Timeout, func(ctx context.Context) (bool, error) {
+		for _, gvk := range kinds {
+			if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+				if !IsNoMatchError(err) {
+					return false, err
+				}
+				lastErr = err
+				if resettable, ok := mapper.(meta.ResettableRESTMapper); ok {
+					resettable.Reset()
+				}
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout && lastErr != nil {
+		return fmt.Errorf("timeout waiting for custom resource definitions to be served, error: %w", lastErr)
+	}
+	return err
+}
+
+// kindMatchInterval is the interval at which the RESTMapper is reset and queried
+// while waiting for the kinds of the applied objects to be served.
+const kindMatchInterval = time.Second
+
+// waitForKindMatch waits up to the options KindMatchTimeout for the kinds
+// of the given objects to be served and known to the RESTMapper.
+func (m *ResourceManager) waitForKindMatch(ctx context.Context, objects []*unstructured.Unstructured, opts ApplyOptions) error {
+	seen := make(map[schema.GroupVersionKind]bool)
+	var kinds []schema.GroupVersionKind
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+		if !seen[gvk] {
+			seen[gvk] = true
+			kinds = append(kinds, gvk)
+		}
+	}
+
+	m.logger().Info("waiting for kinds to be served", "kinds", kinds)
+	return m.waitForKinds(ctx, kinds, WaitOptions{
+		Interval: kindMatchInterval,
+		Timeout:  opts.KindMatchTimeout,
+	})
+}
+
+// shouldRecreate returns true if the object which failed the dry-run apply with the given error
+// has to be recreated based on the force, recreate and Job strategy options.
+func (m *ResourceManager) shouldRecreate(object *unstructured.Unstructured, err error, opts ApplyOptions) bool {
+	if opts.Force && IsImmutableError(err) {
+		return true
+	}
+	return IsImmutableFieldError(err) && (opts.RecreateImmutable || m.replaceJob(object, opts))
+}
+
+// deleteImmutable deletes the existing object before recreating it, and waits for its
+// termination if the recreate option or the Job replace strategy is set.
+func (m *ResourceManager) deleteImmutable(ctx context.Context, object, existingObject *unstructured.Unstructured, opts ApplyOptions) error {
+	m.logger().Info("recreating object with immutable field changes", "object", FmtUnstructured(existingObject))
+	if err := m.client.Delete(ctx, existingObject, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return fmt.Errorf("%s immutable field detected, failed to delete object, error: %w",
+			FmtUnstructured(existingObject), err)
+	}
+
+	if opts.RecreateImmutable || m.replaceJob(object, opts) {
+		if err := m.WaitForTermination([]*unstructured.Unstructured{existingObject},
+			WaitOptions{Interval: time.Second, Timeout: opts.WaitTimeout}); err != nil {
+			return fmt.Errorf("%s immutable field detected, failed to wait for object termination, error: %w",
+				FmtUnstructured(existingObject), err)
+		}
+	}
+
+	return nil
+}
+
+// skipJob returns true if the given object is an existing Job with the skip strategy.
+func (m *ResourceManager) skipJob(object, existingObject *unstructured.Unstructured, opts ApplyOptions) bool {
+	return IsJob(object) && existingObject.GetResourceVersion() != "" &&
+		jobStrategy(object, opts.JobStrategy) == JobStrategySkip
+}
+
+// isTerminatingNamespace returns true if the given in-cluster object is a Namespace being deleted.
+func isTerminatingNamespace(existingObject *unstructured.Unstructured) bool {
+	return existingObject != nil && existingObject.GetKind() == "Namespace" &&
+		existingObject.GetDeletionTimestamp() != nil
+}
+
+// waitForNamespaceTermination waits for the given Namespace to be fully deleted, so that it can be
+// recreated along with its contents, instead of failing to create objects in a terminating namespace.
+func (m *ResourceManager) waitForNamespaceTermination(namespace *unstructured.Unstructured, opts ApplyOptions) error {
+	m.logger().Info("waiting for namespace termination", "object", FmtUnstructured(namespace))
+	return m.WaitForTermination([]*unstructured.Unstructured{namespace},
+		WaitOptions{Interval: 2 * time.Second, Timeout: opts.WaitTimeout})
+}
+
+// replaceJob returns true if the given object is a Job with the replace strategy.
+func (m *ResourceManager) replaceJob(object *unstructured.Unstructured, opts ApplyOptions) bool {
+	return IsJob(object) && jobStrategy(object, opts.JobStrategy) == JobStrategyReplace
+}
+
 func (m *ResourceManager) dryRunApply(ctx context.Context, object *unstructured.Unstructured) error {
+	if m.useClientSideApply(object) {
+		return m.clientSideApply(ctx, object, true)
+	}
+
 	opts := []client.PatchOption{
 		client.DryRunAll,
 		client.ForceOwnership,
 		client.FieldOwner(m.owner.Field),
 	}
-	return m.client.Patch(ctx, object, client.Apply, opts...)
+	err := m.withRetry(ctx, func() error {
+		return m.client.Patch(ctx, object, client.Apply, opts...)
+	})
+	if m.fallbackToClientSideApply(err) {
+		return m.clientSideApply(ctx, object, true)
+	}
+	return err
 }
 
 func (m *ResourceManager) apply(ctx context.Context, object *unstructured.Unstructured) error {
+	if m.useClientSideApply(object) {
+		return m.clientSideApply(ctx, object, false)
+	}
+
 	opts := []client.PatchOption{
 		client.ForceOwnership,
 		client.FieldOwner(m.owner.Field),
 	}
-	return m.client.Patch(ctx, object, client.Apply, opts...)
+	err := m.withRetry(ctx, func() error {
+		return m.client.Patch(ctx, object, client.Apply, opts...)
+	})
+	if m.fallbackToClientSideApply(err) {
+		return m.clientSideApply(ctx, object, false)
+	}
+	return err
 }
 
-// cleanupMetadata performs an HTTP PATCH request to remove entries from metadata annotations, labels and managedFields.
-func (m *ResourceManager) cleanupMetadata(ctx context.Context,
-	desiredObject *unstructured.Unstructured,
+// metadataCleanup is the JSON patch which removes entries from the metadata of an in-cluster object.
+type metadataCleanup struct {
+	object *unstructured.Unstructured
+	patch  client.Patch
+}
+
+// planMetadataCleanup returns the patch to remove entries from metadata annotations, labels and managedFields,
+// or nil if the in-cluster object has no entries to remove.
+func (m *ResourceManager) planMetadataCleanup(desiredObject *unstructured.Unstructured,
 	object *unstructured.Unstructured,
-	opts ApplyCleanupOptions) (bool, error) {
+	opts ApplyCleanupOptions) (*metadataCleanup, error) {
 	if AnyInMetadata(desiredObject, opts.Exclusions) || AnyInMetadata(object, opts.Exclusions) {
-		return false, nil
+		return nil, nil
 	}
 
 	if object == nil {
-		return false, nil
+		return nil, nil
 	}
+	opts = opts.withKubectlMigration()
 	existingObject := object.DeepCopy()
 	var patches []jsonPatch
 
@@ -282,21 +840,32 @@ func (m *ResourceManager) cleanupMetadata(ctx context.Context,
 	if len(opts.FieldManagers) > 0 {
 		managedFieldPatch, err := patchReplaceFieldsManagers(existingObject, opts.FieldManagers, m.owner.Field)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		patches = append(patches, managedFieldPatch...)
 	}
 
 	// no patching is needed exit early
 	if len(patches) == 0 {
-		return false, nil
+		return nil, nil
 	}
 
 	rawPatch, err := json.Marshal(patches)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	patch := client.RawPatch(types.JSONPatchType, rawPatch)
+	return &metadataCleanup{object: existingObject, patch: client.RawPatch(types.JSONPatchType, rawPatch)}, nil
+}
 
-	return true, m.client.Patch(ctx, existingObject, patch, client.FieldOwner(m.owner.Field))
+// cleanupMetadata performs an HTTP PATCH request to remove entries from metadata annotations, labels and managedFields.
+func (m *ResourceManager) cleanupMetadata(ctx context.Context, cleanup *metadataCleanup) error {
+	if cleanup == nil {
+		return nil
+	}
+	if err := m.withRetry(ctx, func() error {
+		return m.client.Patch(ctx, cleanup.object, cleanup.patch, client.FieldOwner(m.owner.Field))
+	}); err != nil {
+		return fmt.Errorf("%s metadata.managedFields cleanup failed, error: %w", FmtUnstructured(cleanup.object), err)
+	}
+	return nil
 }
@@ -22,14 +22,18 @@ import (
 	"encoding/base64"
 	"fmt"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestApply(t *testing.T) {
@@ -273,6 +277,205 @@ func TestApply_Force(t *testing.T) {
 	})
 }
 
+func TestApply_RecreateImmutable(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("recreate")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretName, secret := getFirstObject(objects, "Secret", id)
+
+	if _, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("recreates immutable secret", func(t *testing.T) {
+		err = unstructured.SetNestedField(secret.Object, "val-recreate", "stringData", "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opts := DefaultApplyOptions()
+		opts.RecreateImmutable = true
+		opts.WaitTimeout = timeout
+
+		changeSet, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range changeSet.Entries {
+			if entry.Subject == secretName {
+				if diff := cmp.Diff(string(CreatedAction), entry.Action); diff != "" {
+					t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+				}
+				break
+			}
+		}
+	})
+}
+
+func TestApply_JobStrategy(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("job")
+	objects, err := readManifest("testdata/test10.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobName, job := getFirstObject(objects, "Job", id)
+
+	if _, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// update the job pod template
+	containers, _, err := unstructured.NestedSlice(job.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers[0].(map[string]interface{})["command"] = []interface{}{"./podinfo", "--help"}
+	if err := unstructured.SetNestedSlice(job.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("fails to apply changed job", func(t *testing.T) {
+		if _, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err == nil {
+			t.Fatal("Expected error got none")
+		}
+	})
+
+	t.Run("skips changed job", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.JobStrategy = JobStrategySkip
+
+		changeSet, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(string(UnchangedAction), changeSet.ToMap()[jobName]); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("replaces changed job", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.JobStrategy = JobStrategyReplace
+		opts.WaitTimeout = timeout
+
+		changeSet, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(string(CreatedAction), changeSet.ToMap()[jobName]); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestApplyAll_Concurrency(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("concurrency")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		configMap := &unstructured.Unstructured{}
+		configMap.SetAPIVersion("v1")
+		configMap.SetKind("ConfigMap")
+		configMap.SetNamespace(id)
+		configMap.SetName(fmt.Sprintf("%s-%d", id, i))
+		if err := unstructured.SetNestedField(configMap.Object, "value", "data", "key"); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, configMap)
+	}
+
+	opts := DefaultApplyOptions()
+	opts.Concurrency = 4
+
+	t.Run("creates objects concurrently", func(t *testing.T) {
+		changeSet, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range changeSet.Entries {
+			if diff := cmp.Diff(string(CreatedAction), entry.Action); diff != "" {
+				t.Errorf("Mismatch from expected value for %s (-want +got):\n%s", entry.Subject, diff)
+			}
+		}
+	})
+
+	t.Run("preserves the objects order", func(t *testing.T) {
+		changeSet, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sequential, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(sequential.Entries, changeSet.Entries); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestApply_TerminatingNamespace(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("terminating")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ns := getFirstObject(objects, "Namespace", id)
+
+	if _, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// envtest runs without the namespace controller, the namespace stays in the terminating phase
+	if err := manager.client.Delete(ctx, ns.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("waits for namespace termination", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.WaitTimeout = 3 * time.Second
+
+		_, err := manager.ApplyAllStaged(ctx, objects, opts)
+		if err == nil {
+			t.Fatal("Expected error got none")
+		}
+
+		if !strings.Contains(err.Error(), "termination timeout") {
+			t.Errorf("Expected termination timeout error, got %s", err)
+		}
+	})
+}
+
 func TestApply_SetNativeKindsDefaults(t *testing.T) {
 	timeout := 10 * time.Second
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -539,6 +742,81 @@ func TestApply_Cleanup(t *testing.T) {
 	})
 }
 
+func TestApply_KubectlMigration(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("kubectl")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, configMap := getFirstObject(objects, "ConfigMap", id)
+	if err := SetNativeKindsDefaults(objects); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("creates objects as kubectl", func(t *testing.T) {
+		for _, object := range objects {
+			obj := object.DeepCopy()
+			obj.SetAnnotations(map[string]string{corev1.LastAppliedConfigAnnotation: "test"})
+			if err := manager.client.Create(ctx, obj, client.FieldOwner("kubectl-client-side-apply")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("migrates kubectl client-side apply", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.Cleanup.KubectlMigration = true
+		if _, err := manager.ApplyAll(ctx, objects, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		obj := configMap.DeepCopy()
+		if err := manager.Client().Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := obj.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; ok {
+			t.Errorf("%s annotation not removed", corev1.LastAppliedConfigAnnotation)
+		}
+
+		for _, entry := range obj.GetManagedFields() {
+			if entry.Manager == "kubectl-client-side-apply" {
+				t.Errorf("kubectl-client-side-apply manager not replaced")
+			}
+		}
+	})
+}
+
+func TestApplyCleanupOptions_WithKubectlMigration(t *testing.T) {
+	opts := ApplyCleanupOptions{
+		Annotations:   []string{"example.com/annotation"},
+		FieldManagers: []FieldManager{kubectlFieldManagers[0]},
+	}
+
+	if diff := cmp.Diff(opts, opts.withKubectlMigration()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	opts.KubectlMigration = true
+	got := opts.withKubectlMigration()
+
+	wantAnnotations := []string{"example.com/annotation", corev1.LastAppliedConfigAnnotation}
+	if diff := cmp.Diff(wantAnnotations, got.Annotations); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(kubectlFieldManagers, got.FieldManagers); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+	if len(opts.Annotations) != 1 {
+		t.Errorf("expected the original options to be unchanged, got %v", opts.Annotations)
+	}
+}
+
 func TestApply_CleanupRemovals(t *testing.T) {
 	timeout := 10 * time.Second
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -752,3 +1030,231 @@ func TestApply_Cleanup_Exclusions(t *testing.T) {
 		}
 	})
 }
+
+func TestServedKinds(t *testing.T) {
+	objects, err := readManifest("testdata/test5.yaml", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, crd := getFirstObject(objects, "CustomResourceDefinition", "clustertests.testing.fluxcd.io")
+	if err := unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"name": "v1", "served": true},
+		map[string]interface{}{"name": "v1beta1", "served": false},
+	}, "spec", "versions"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []schema.GroupVersionKind{
+		{Group: "testing.fluxcd.io", Version: "v1", Kind: "ClusterTest"},
+	}
+	if diff := cmp.Diff(want, servedKinds(objects)); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
+
+// delayedRESTMapper maps the given kind only after being reset the given number of times.
+type delayedRESTMapper struct {
+	meta.RESTMapper
+	resets int
+	delay  int
+	gvk    schema.GroupVersionKind
+}
+
+func (m *delayedRESTMapper) Reset() {
+	m.resets++
+	if m.resets == m.delay {
+		m.RESTMapper.(*meta.DefaultRESTMapper).Add(m.gvk, meta.RESTScopeNamespace)
+	}
+}
+
+func TestWaitForKindMatch(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Custom"}
+	newObject := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+	objects := []*unstructured.Unstructured{newObject("first"), newObject("second")}
+
+	t.Run("waits for the kind to be served", func(t *testing.T) {
+		mapper := &delayedRESTMapper{RESTMapper: meta.NewDefaultRESTMapper(nil), delay: 1, gvk: gvk}
+		rm := NewResourceManager(fake.NewClientBuilder().WithRESTMapper(mapper).Build(), nil, Owner{Field: "manager", Group: "manager.io"})
+
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 5 * time.Second
+		if err := rm.waitForKindMatch(context.Background(), objects, opts); err != nil {
+			t.Fatal(err)
+		}
+		if mapper.resets != 1 {
+			t.Errorf("expected the mapper to be reset once, got %d", mapper.resets)
+		}
+	})
+
+	t.Run("fails after the timeout", func(t *testing.T) {
+		mapper := &delayedRESTMapper{RESTMapper: meta.NewDefaultRESTMapper(nil), delay: -1, gvk: gvk}
+		rm := NewResourceManager(fake.NewClientBuilder().WithRESTMapper(mapper).Build(), nil, Owner{Field: "manager", Group: "manager.io"})
+
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 100 * time.Millisecond
+		err := rm.waitForKindMatch(context.Background(), objects, opts)
+		if !IsNoMatchError(err) {
+			t.Fatalf("expected no match error, got %v", err)
+		}
+	})
+}
+
+// mappedClient fails the patch requests of the kinds unknown to its RESTMapper.
+type mappedClient struct {
+	client.Client
+}
+
+func (c *mappedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if _, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestApplyAll_SkipMissingKinds(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Custom"}
+	newObject := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+	objects := []*unstructured.Unstructured{newObject("first"), newObject("second")}
+
+	newManager := func() *ResourceManager {
+		mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+		kubeClient := &mappedClient{Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build()}
+		return NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+	}
+
+	t.Run("fails on missing kinds", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 0
+		_, err := newManager().ApplyAll(context.Background(), objects, opts)
+		if !IsNoMatchError(err) {
+			t.Fatalf("expected no match error, got %v", err)
+		}
+	})
+
+	t.Run("skips missing kinds", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 0
+		opts.SkipMissingKinds = true
+		changeSet, err := newManager().ApplyAll(context.Background(), objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range changeSet.Entries {
+			if entry.Action != string(SkippedAction) {
+				t.Errorf("expected %s to be skipped, got %s", entry.Subject, entry.Action)
+			}
+		}
+		if len(changeSet.Entries) != len(objects) {
+			t.Errorf("expected %d entries, got %d", len(objects), len(changeSet.Entries))
+		}
+	})
+
+	t.Run("skips undeclared kinds without waiting", func(t *testing.T) {
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 30 * time.Second
+		opts.SkipMissingKinds = true
+		start := time.Now()
+		changeSet, err := newManager().ApplyAll(context.Background(), objects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed >= opts.KindMatchTimeout {
+			t.Errorf("expected the objects to be skipped without waiting, took %s", elapsed)
+		}
+		if len(changeSet.Entries) != len(objects) || changeSet.Entries[0].Action != string(SkippedAction) {
+			t.Errorf("expected the objects to be skipped, got %s", changeSet)
+		}
+	})
+
+	t.Run("skips declared kinds after the timeout", func(t *testing.T) {
+		crdGVK := schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+		mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+		mapper.Add(crdGVK, meta.RESTScopeRoot)
+		kubeClient := &mappedClient{Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build()}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetClientSideApplyKinds(crdGVK.GroupKind())
+
+		crd := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"group":    gvk.Group,
+				"names":    map[string]interface{}{"kind": gvk.Kind},
+				"versions": []interface{}{map[string]interface{}{"name": gvk.Version, "served": true}},
+			},
+		}}
+		crd.SetGroupVersionKind(crdGVK)
+		crd.SetName("customs.example.com")
+
+		opts := DefaultApplyOptions()
+		opts.KindMatchTimeout = 300 * time.Millisecond
+		opts.SkipMissingKinds = true
+		start := time.Now()
+		changeSet, err := rm.ApplyAll(context.Background(), append([]*unstructured.Unstructured{crd}, objects...), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < opts.KindMatchTimeout {
+			t.Errorf("expected the declared kinds to be awaited, took %s", elapsed)
+		}
+
+		actions := make(map[string]string)
+		for _, entry := range changeSet.Entries {
+			actions[entry.Subject] = entry.Action
+		}
+		want := map[string]string{
+			"CustomResourceDefinition/customs.example.com": string(CreatedAction),
+			"Custom/default/first":                         string(SkippedAction),
+			"Custom/default/second":                        string(SkippedAction),
+		}
+		if diff := cmp.Diff(want, actions); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestApplyAllStaged_SkipsIgnoredDefinitions(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+	rm.SetClientSideApplyKinds(schema.GroupKind{Kind: "ConfigMap"})
+
+	namespace := &unstructured.Unstructured{}
+	namespace.SetAPIVersion("v1")
+	namespace.SetKind("Namespace")
+	namespace.SetName("ignored")
+	namespace.SetAnnotations(map[string]string{DefaultIgnoreAnnotation: "true"})
+
+	configMap := &unstructured.Unstructured{}
+	configMap.SetAPIVersion("v1")
+	configMap.SetKind("ConfigMap")
+	configMap.SetNamespace("default")
+	configMap.SetName("test")
+
+	opts := DefaultApplyOptions()
+	opts.IgnoreAnnotation = DefaultIgnoreAnnotation
+	opts.WaitTimeout = time.Second
+	changeSet, err := rm.ApplyAllStaged(context.Background(), []*unstructured.Unstructured{namespace, configMap}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"Namespace/ignored":      string(SkippedAction),
+		"ConfigMap/default/test": string(CreatedAction),
+	}
+	if diff := cmp.Diff(expected, changeSet.ToMap()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
@@ -20,12 +20,15 @@ package ssa
 import (
 	"context"
 	"fmt"
-	"sort"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -35,6 +38,14 @@ type DeleteOptions struct {
 	// performed.
 	PropagationPolicy metav1.DeletionPropagation
 
+	// KindPropagationPolicies overrides the PropagationPolicy for the objects of the given kinds,
+	// e.g. to delete in the foreground the objects with finalizer-heavy children.
+	KindPropagationPolicies map[schema.GroupKind]metav1.DeletionPropagation
+
+	// GracePeriodSeconds overrides the termination grace period of the deleted objects,
+	// a nil GracePeriodSeconds means the objects default grace period is used.
+	GracePeriodSeconds *int64
+
 	// Inclusions determines which in-cluster objects are subject to deletion
 	// based on the specified key-value pairs.
 	// A nil Inclusions map means all objects are subject to deletion
@@ -46,6 +57,22 @@ type DeleteOptions struct {
 	// A nil Exclusions map means all objects are subject to deletion
 	// irregardless of their metadata labels and annotations.
 	Exclusions map[string]string
+
+	// IgnoreAnnotation is the annotation which exempts the objects from DeleteAll when set to 'true'.
+	// An empty IgnoreAnnotation disables the check, set it to DefaultIgnoreAnnotation to enable it.
+	IgnoreAnnotation string
+
+	// LabelSelector filters the objects deleted by DeleteAll, e.g. 'app.kubernetes.io/part-of=podinfo'.
+	// An empty LabelSelector means all objects are subject to deletion.
+	LabelSelector string
+
+	// Revision is the source revision of the objects, e.g. a Git commit SHA,
+	// included in the Kubernetes events when an event recorder is set, and in the ChangeSet entries.
+	Revision string
+
+	// DryRun configures the engine to perform a server-side dry-run of the deletion,
+	// the objects are reported as deleted but are kept in-cluster.
+	DryRun bool
 }
 
 // DefaultDeleteOptions returns the default delete options where the propagation
@@ -58,6 +85,14 @@ func DefaultDeleteOptions() DeleteOptions {
 	}
 }
 
+// propagationPolicy returns the propagation policy for the objects of the given kind.
+func (o DeleteOptions) propagationPolicy(gk schema.GroupKind) metav1.DeletionPropagation {
+	if policy, ok := o.KindPropagationPolicies[gk]; ok {
+		return policy
+	}
+	return o.PropagationPolicy
+}
+
 // Delete deletes the given object (not found errors are ignored).
 func (m *ResourceManager) Delete(ctx context.Context, object *unstructured.Unstructured, opts DeleteOptions) (*ChangeSetEntry, error) {
 
@@ -85,18 +120,54 @@ func (m *ResourceManager) Delete(ctx context.Context, object *unstructured.Unstr
 		return m.changeSetEntry(object, UnchangedAction), nil
 	}
 
-	if err := m.client.Delete(ctx, existingObject, client.PropagationPolicy(opts.PropagationPolicy)); err != nil {
+	deleteOpts := []client.DeleteOption{client.PropagationPolicy(opts.propagationPolicy(object.GroupVersionKind().GroupKind()))}
+	if opts.GracePeriodSeconds != nil {
+		deleteOpts = append(deleteOpts, client.GracePeriodSeconds(*opts.GracePeriodSeconds))
+	}
+	if opts.DryRun {
+		deleteOpts = append(deleteOpts, client.DryRunAll)
+	}
+
+	if err := m.withRetry(ctx, func() error {
+		return m.client.Delete(ctx, existingObject, deleteOpts...)
+	}); err != nil {
 		return m.changeSetEntry(object, UnknownAction),
 			fmt.Errorf("%s delete failed, error: %w", FmtUnstructured(object), err)
 	}
 
+	m.logger().Info("object deleted", "object", FmtUnstructured(object), "dryRun", opts.DryRun)
+	if !opts.DryRun {
+		m.recordEvent(existingObject, DeletedAction, opts.Revision)
+	}
+
 	return m.changeSetEntry(object, DeletedAction), nil
 }
 
 // DeleteAll deletes the given set of objects (not found errors are ignored).
+// The objects with the ignore annotation, or not matching the label selector, are skipped
+// and reported in the ChangeSet with the SkippedAction.
 func (m *ResourceManager) DeleteAll(ctx context.Context, objects []*unstructured.Unstructured, opts DeleteOptions) (*ChangeSet, error) {
-	sort.Sort(sort.Reverse(SortableUnstructureds(objects)))
+	start := time.Now()
+	changeSet, err := m.deleteAll(ctx, objects, opts)
+	changeSet.setRevision(opts.Revision)
+	m.recordOperation(DeleteOperation, start, changeSet, err)
+	m.logger().Info("delete completed", "objects", len(objects),
+		"duration", time.Since(start), "error", err)
+	return changeSet, err
+}
+
+// deleteAll deletes the given objects in the reverse apply order.
+func (m *ResourceManager) deleteAll(ctx context.Context, objects []*unstructured.Unstructured, opts DeleteOptions) (*ChangeSet, error) {
+	objects, skipped, err := filterObjects(objects, opts.IgnoreAnnotation, opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	m.sortObjects(objects, true)
 	changeSet := NewChangeSet()
+	for _, object := range skipped {
+		changeSet.Add(*m.changeSetEntry(object, SkippedAction))
+	}
 
 	var errors string
 	for _, object := range objects {
@@ -115,3 +186,146 @@ func (m *ResourceManager) DeleteAll(ctx context.Context, objects []*unstructured
 
 	return changeSet, nil
 }
+
+// GarbageCollect deletes the objects of the previous ChangeSet which are missing from the current one,
+// e.g. the objects removed from the source since the previous apply.
+// The deletion honors the inclusions, exclusions, ignore annotation, label selector and dry-run delete options,
+// which are matched against the in-cluster objects. The objects whose kind is no longer served by the
+// API server are reported as deleted. An error is returned if the current ChangeSet is nil, e.g. when
+// the apply which produced it failed, to prevent the deletion of every object of the previous ChangeSet.
+func (m *ResourceManager) GarbageCollect(ctx context.Context, previous, current *ChangeSet, opts DeleteOptions) (*ChangeSet, error) {
+	if current == nil {
+		return nil, fmt.Errorf("garbage collection requires the current change set")
+	}
+
+	var objects []*unstructured.Unstructured
+	gone := NewChangeSet()
+	for _, entry := range StaleEntries(previous, current) {
+		gvk := schema.GroupVersionKind{
+			Group:   entry.ObjMetadata.GroupKind.Group,
+			Version: entry.GroupVersion,
+			Kind:    entry.ObjMetadata.GroupKind.Kind,
+		}
+
+		u := &unstructured.Unstructured{}
+		u.SetNamespace(entry.ObjMetadata.Namespace)
+		u.SetName(entry.ObjMetadata.Name)
+
+		if gvk.Version == "" {
+			mapping, err := m.client.RESTMapper().RESTMapping(entry.ObjMetadata.GroupKind)
+			if IsNoMatchError(err) {
+				u.SetGroupVersionKind(gvk)
+				gone.Add(*m.changeSetEntry(u, DeletedAction))
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s version lookup failed, error: %w", entry.Subject, err)
+			}
+			gvk.Version = mapping.GroupVersionKind.Version
+		}
+		u.SetGroupVersionKind(gvk)
+
+		// the ignore annotation and the label selector are matched against the in-cluster metadata
+		err := m.client.Get(ctx, client.ObjectKeyFromObject(u), u)
+		if IsNoMatchError(err) {
+			gone.Add(*m.changeSetEntry(u, DeletedAction))
+			continue
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s query failed, error: %w", entry.Subject, err)
+		}
+		objects = append(objects, u)
+	}
+	gone.setRevision(opts.Revision)
+
+	if len(objects) == 0 {
+		return gone, nil
+	}
+
+	changeSet, err := m.DeleteAll(ctx, objects, opts)
+	if changeSet != nil {
+		changeSet.Append(gone.Entries)
+	}
+	return changeSet, err
+}
+
+// OwnerScope restricts the discovery of the objects labeled with the owner labels.
+type OwnerScope struct {
+	// Kinds are the kinds of the objects to discover.
+	Kinds []schema.GroupVersionKind
+
+	// Namespaces are the namespaces in which the namespaced objects are discovered,
+	// an empty list means the objects are discovered across all namespaces.
+	Namespaces []string
+}
+
+// ListOwned returns the in-cluster objects of the scope kinds labeled with the owner labels of the given name
+// and namespace, see SetOwnerLabels. The cluster-scoped kinds are listed regardless of the scope namespaces.
+func (m *ResourceManager) ListOwned(ctx context.Context, name, namespace string, scope OwnerScope) ([]*unstructured.Unstructured, error) {
+	namespaces := scope.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, gvk := range scope.Kinds {
+		mapping, err := m.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s mapping lookup failed, error: %w", gvk.GroupVersion(), gvk.Kind, err)
+		}
+
+		listNamespaces := namespaces
+		if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+			listNamespaces = []string{metav1.NamespaceAll}
+		}
+
+		for _, ns := range listNamespaces {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			if err := m.client.List(ctx, list,
+				client.InNamespace(ns),
+				client.MatchingLabels(m.GetOwnerLabels(name, namespace))); err != nil {
+				return nil, fmt.Errorf("%s/%s list failed, error: %w", gvk.GroupVersion(), gvk.Kind, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// GarbageCollectOwned deletes the in-cluster objects labeled with the owner labels of the given name and namespace
+// which are missing from the current ChangeSet. The objects are discovered with ListOwned, it should be used when
+// no record of the previously applied objects exists, otherwise GarbageCollect is more efficient.
+func (m *ResourceManager) GarbageCollectOwned(ctx context.Context, name, namespace string,
+	current *ChangeSet, scope OwnerScope, opts DeleteOptions) (*ChangeSet, error) {
+	owned, err := m.ListOwned(ctx, name, namespace, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[object.ObjMetadata]bool)
+	if current != nil {
+		for _, entry := range current.Entries {
+			seen[entry.ObjMetadata] = true
+		}
+	}
+
+	var stale []*unstructured.Unstructured
+	for _, obj := range owned {
+		id := object.UnstructuredToObjMetadata(obj)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stale = append(stale, obj)
+	}
+
+	if len(stale) == 0 {
+		return NewChangeSet(), nil
+	}
+
+	return m.DeleteAll(ctx, stale, opts)
+}
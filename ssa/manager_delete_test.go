@@ -24,8 +24,16 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestDelete(t *testing.T) {
@@ -96,7 +104,7 @@ func TestDelete(t *testing.T) {
 			t.Error(err)
 		}
 
-		if err := manager.WaitForTermination(objects, WaitOptions{time.Second, 5 * time.Second}); err != nil {
+		if err := manager.WaitForTermination(objects, WaitOptions{Interval: time.Second, Timeout: 5 * time.Second}); err != nil {
 			// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/880
 			if !strings.Contains(err.Error(), "Namespace/") {
 				t.Error(err)
@@ -161,3 +169,263 @@ func TestDelete_Exclusions(t *testing.T) {
 		}
 	})
 }
+
+func TestGarbageCollect(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("gc")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, configMap := getFirstObject(objects, "ConfigMap", id)
+	current := NewChangeSet()
+	for _, entry := range previous.Entries {
+		if entry.Subject != FmtUnstructured(configMap) {
+			current.Add(entry)
+		}
+	}
+
+	t.Run("dry-run keeps stale objects", func(t *testing.T) {
+		opts := DefaultDeleteOptions()
+		opts.DryRun = true
+
+		changeSet, err := manager.GarbageCollect(ctx, previous, current, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{FmtUnstructured(configMap): string(DeletedAction)}
+		if diff := cmp.Diff(expected, changeSet.ToMap()); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		configMapClone := configMap.DeepCopy()
+		if err := manager.client.Get(ctx, client.ObjectKeyFromObject(configMapClone), configMapClone); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("deletes stale objects", func(t *testing.T) {
+		changeSet, err := manager.GarbageCollect(ctx, previous, current, DefaultDeleteOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{FmtUnstructured(configMap): string(DeletedAction)}
+		if diff := cmp.Diff(expected, changeSet.ToMap()); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		configMapClone := configMap.DeepCopy()
+		err = manager.client.Get(ctx, client.ObjectKeyFromObject(configMapClone), configMapClone)
+		if !apierrors.IsNotFound(err) {
+			t.Error(err)
+		}
+	})
+}
+
+// deleteOptionsRecorder records the options of the delete requests.
+type deleteOptionsRecorder struct {
+	client.Client
+	options map[string]client.DeleteOptions
+}
+
+func (c *deleteOptionsRecorder) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	deleteOpts := client.DeleteOptions{}
+	deleteOpts.ApplyOptions(opts)
+	c.options[obj.GetName()] = deleteOpts
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestGarbageCollect_InClusterFilters(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "ignored",
+			Namespace:   "default",
+			Annotations: map[string]string{DefaultIgnoreAnnotation: "true"},
+		}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default"}},
+	).Build()
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+	previous := NewChangeSet()
+	for _, name := range []string{"ignored", "stale"} {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName(name)
+		previous.Add(*rm.changeSetEntry(u, CreatedAction))
+	}
+
+	opts := DefaultDeleteOptions()
+	opts.IgnoreAnnotation = DefaultIgnoreAnnotation
+	changeSet, err := rm.GarbageCollect(ctx, previous, NewChangeSet(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"ConfigMap/default/ignored": string(SkippedAction),
+		"ConfigMap/default/stale":   string(DeletedAction),
+	}
+	if diff := cmp.Diff(expected, changeSet.ToMap()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "ignored"}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the ignored object to survive the garbage collection, got %v", err)
+	}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "stale"}, &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the stale object to be deleted, got %v", err)
+	}
+}
+
+func TestGarbageCollect_UnservedKinds(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default"}},
+	).Build()
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+	previous := NewChangeSet()
+	for _, apiVersion := range []string{"v1", "testing.fluxcd.io/v1", "testing.fluxcd.io/"} {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind("ConfigMap")
+		if apiVersion != "v1" {
+			u.SetKind("Widget")
+		}
+		u.SetNamespace("default")
+		u.SetName("stale")
+		previous.Add(*rm.changeSetEntry(u, CreatedAction))
+	}
+
+	if _, err := rm.GarbageCollect(ctx, previous, nil, DefaultDeleteOptions()); err == nil {
+		t.Error("expected an error for a nil current change set")
+	}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "stale"}, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("expected the object to survive the garbage collection, got %v", err)
+	}
+
+	changeSet, err := rm.GarbageCollect(ctx, previous, NewChangeSet(), DefaultDeleteOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changeSet.Entries) != len(previous.Entries) {
+		t.Fatalf("expected %d entries, got %v", len(previous.Entries), changeSet.Entries)
+	}
+	for _, entry := range changeSet.Entries {
+		if entry.Action != string(DeletedAction) {
+			t.Errorf("expected %s to be deleted, got %s", entry.Subject, entry.Action)
+		}
+	}
+}
+
+func TestDelete_PropagationPolicies(t *testing.T) {
+	kubeClient := &deleteOptionsRecorder{
+		Client: fake.NewClientBuilder().WithObjects(
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+		).Build(),
+		options: make(map[string]client.DeleteOptions),
+	}
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+	newObject := func(apiVersion, kind, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetNamespace("default")
+		u.SetName(name)
+		return u
+	}
+	objects := []*unstructured.Unstructured{
+		newObject("v1", "ConfigMap", "config"),
+		newObject("apps/v1", "Deployment", "app"),
+	}
+
+	var gracePeriod int64 = 5
+	opts := DefaultDeleteOptions()
+	opts.KindPropagationPolicies = map[schema.GroupKind]metav1.DeletionPropagation{
+		{Group: "apps", Kind: "Deployment"}: metav1.DeletePropagationForeground,
+	}
+	opts.GracePeriodSeconds = &gracePeriod
+
+	if _, err := rm.DeleteAll(context.Background(), objects, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	background := metav1.DeletePropagationBackground
+	foreground := metav1.DeletePropagationForeground
+	want := map[string]client.DeleteOptions{
+		"config": {PropagationPolicy: &background, GracePeriodSeconds: &gracePeriod},
+		"app":    {PropagationPolicy: &foreground, GracePeriodSeconds: &gracePeriod},
+	}
+	if diff := cmp.Diff(want, kubeClient.options); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
+
+func TestGarbageCollectOwned(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	rm := NewResourceManager(nil, nil, Owner{Field: "manager", Group: "manager.io"})
+	ownerLabels := rm.GetOwnerLabels("app", "flux-system")
+
+	kubeClient := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "stale", Labels: ownerLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "current", Namespace: "default", Labels: ownerLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default", Labels: ownerLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "other", Labels: ownerLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unowned", Namespace: "default"}},
+	).Build()
+	rm = NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+	current := NewChangeSet()
+	current.Add(ChangeSetEntry{
+		ObjMetadata: object.ObjMetadata{GroupKind: schema.GroupKind{Kind: "ConfigMap"}, Namespace: "default", Name: "current"},
+		Action:      string(UnchangedAction),
+	})
+
+	scope := OwnerScope{
+		Kinds: []schema.GroupVersionKind{
+			corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+			corev1.SchemeGroupVersion.WithKind("Namespace"),
+		},
+		Namespaces: []string{"default"},
+	}
+
+	changeSet, err := rm.GarbageCollectOwned(context.Background(), "app", "flux-system", current, scope, DefaultDeleteOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"ConfigMap/default/stale": string(DeletedAction),
+		"Namespace/stale":         string(DeletedAction),
+	}
+	if diff := cmp.Diff(want, changeSet.ToMap()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	remaining := &corev1.ConfigMapList{}
+	if err := kubeClient.List(context.Background(), remaining); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining.Items) != 3 {
+		t.Errorf("expected 3 config maps to be kept, got %d", len(remaining.Items))
+	}
+}
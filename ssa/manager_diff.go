@@ -26,12 +26,13 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	defaultMask = "*****"
-	diffMask    = "******"
+	defaultMask = "***"
+	diffMask    = "***-changed"
 )
 
 // DiffOptions contains options for server-side dry-run apply requests.
@@ -41,17 +42,31 @@ type DiffOptions struct {
 	// A nil Exclusions map means all objects are applied
 	// regardless of their metadata labels and annotations.
 	Exclusions map[string]string `json:"exclusions"`
+
+	// MaskPaths holds the JSON pointers of the fields whose values are masked in the diff result,
+	// in addition to the Secrets data, e.g. '/spec/values/password'. A '*' path segment matches
+	// any map key or array index, e.g. '/spec/env/*/value'.
+	MaskPaths []string `json:"maskPaths,omitempty"`
+
+	// IgnoreRules defines the fields managed by other controllers, which are not reported as drift,
+	// in addition to the fields listed in the objects IgnoreFieldsAnnotation.
+	IgnoreRules []IgnoreRule `json:"ignoreRules,omitempty"`
+
+	// Concurrency is the number of concurrent dry-run requests performed by DiffAll.
+	Concurrency int `json:"concurrency,omitempty"`
 }
 
 // DefaultDiffOptions returns the default dry-run apply options.
 func DefaultDiffOptions() DiffOptions {
 	return DiffOptions{
-		Exclusions: nil,
+		Exclusions:  nil,
+		Concurrency: 4,
 	}
 }
 
 // Diff performs a server-side apply dry-un and returns the live and merged objects if drift is detected.
-// If the diff contains Kubernetes Secrets, the data values are masked.
+// If the diff contains Kubernetes Secrets, or fields matching the mask paths, the values are replaced
+// with '***', and with '***-changed' in the merged object when the values differ.
 func (m *ResourceManager) Diff(ctx context.Context, object *unstructured.Unstructured, opts DiffOptions) (
 	*ChangeSetEntry,
 	*unstructured.Unstructured,
@@ -65,7 +80,7 @@ func (m *ResourceManager) Diff(ctx context.Context, object *unstructured.Unstruc
 		return m.changeSetEntry(existingObject, UnchangedAction), nil, nil, nil
 	}
 
-	dryRunObject := object.DeepCopy()
+	dryRunObject := m.ignoreFields(object, existingObject, opts.IgnoreRules).DeepCopy()
 	if err := m.dryRunApply(ctx, dryRunObject); err != nil {
 		return nil, nil, nil, m.validationError(dryRunObject, err)
 	}
@@ -89,12 +104,119 @@ func (m *ResourceManager) Diff(ctx context.Context, object *unstructured.Unstruc
 			dryRunObject, existingObject = d, ex
 		}
 
+		maskPaths(existingObject.Object, dryRunObject.Object, opts.MaskPaths)
+
 		return cse, existingObject, dryRunObject, nil
 	}
 
 	return m.changeSetEntry(dryRunObject, UnchangedAction), nil, nil, nil
 }
 
+// ObjectDiff is the machine-readable result of a server-side apply dry-run.
+type ObjectDiff struct {
+	// GroupVersionKind is the API group, version and kind of the object.
+	GroupVersionKind schema.GroupVersionKind `json:"groupVersionKind"`
+
+	// Namespace is the namespace of the object, empty for cluster scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+
+	// Action is the action the apply would perform, one of 'created', 'configured' or 'unchanged'.
+	Action Action `json:"action"`
+
+	// Operations holds the field changes of the configured objects, from the in-cluster object
+	// to the dry-run result. The metadata fields set by the API server and the status are ignored.
+	Operations []JSONPatchOperation `json:"operations,omitempty"`
+
+	// Error is the dry-run failure reported by DiffAll, the action is 'unknown' when set.
+	Error string `json:"error,omitempty"`
+}
+
+// DiffReport holds the aggregated result of the server-side apply dry-run of an object set.
+type DiffReport struct {
+	// Created is the number of objects that would be created.
+	Created int `json:"created"`
+
+	// Configured is the number of objects that would be configured.
+	Configured int `json:"configured"`
+
+	// Unchanged is the number of objects that are up-to-date.
+	Unchanged int `json:"unchanged"`
+
+	// Failed is the number of objects that failed the dry-run.
+	Failed int `json:"failed"`
+
+	// Objects holds the diff of each object, in the apply order.
+	Objects []ObjectDiff `json:"objects"`
+}
+
+// StructuredDiff performs a server-side apply dry-run and returns the field changes if drift is detected.
+// The values of the Kubernetes Secrets and of the fields matching the mask paths are masked.
+func (m *ResourceManager) StructuredDiff(ctx context.Context, object *unstructured.Unstructured, opts DiffOptions) (*ObjectDiff, error) {
+	entry, existingObject, mergedObject, err := m.Diff(ctx, object, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ObjectDiff{
+		GroupVersionKind: object.GroupVersionKind(),
+		Namespace:        object.GetNamespace(),
+		Name:             object.GetName(),
+		Action:           Action(entry.Action),
+	}
+	if existingObject != nil && mergedObject != nil {
+		existing, merged := existingObject.DeepCopy(), mergedObject.DeepCopy()
+		normalizeObject(existing)
+		normalizeObject(merged)
+		diff.Operations = jsonPatchDiff(existing.Object, merged.Object)
+	}
+
+	return diff, nil
+}
+
+// DiffAll performs a server-side apply dry-run of the given objects concurrently, and returns a report
+// of the changes an apply would make. The dry-run failures are reported per object instead of
+// stopping the diff. The number of concurrent requests is set by the diff options Concurrency.
+func (m *ResourceManager) DiffAll(ctx context.Context, objects []*unstructured.Unstructured, opts DiffOptions) *DiffReport {
+	sorted := make([]*unstructured.Unstructured, len(objects))
+	copy(sorted, objects)
+	m.sortObjects(sorted, false)
+
+	diffs := make([]ObjectDiff, len(sorted))
+	runConcurrently(opts.Concurrency, len(sorted), func(i int) {
+		object := sorted[i]
+		objDiff, err := m.StructuredDiff(ctx, object, opts)
+		if err != nil {
+			objDiff = &ObjectDiff{
+				GroupVersionKind: object.GroupVersionKind(),
+				Namespace:        object.GetNamespace(),
+				Name:             object.GetName(),
+				Action:           UnknownAction,
+				Error:            err.Error(),
+			}
+		}
+		diffs[i] = *objDiff
+	})
+
+	report := &DiffReport{Objects: diffs}
+	for _, objDiff := range diffs {
+		switch {
+		case objDiff.Error != "":
+			report.Failed++
+		case objDiff.Action == CreatedAction:
+			report.Created++
+		case objDiff.Action == ConfiguredAction:
+			report.Configured++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	return report
+}
+
 // sanitizeDriftedSecrets masks the data values of the given secret objects
 func (m *ResourceManager) sanitizeDriftedSecrets(existingObject, dryRunObject *unstructured.Unstructured) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
 	dryRunData, foundDryRun, err := getNestedMap(dryRunObject)
@@ -167,11 +289,13 @@ func hasObjectDrifted(existingObject, dryRunObject *unstructured.Unstructured) b
 	return !apiequality.Semantic.DeepEqual(dryRunObj.Object, existingObj.Object)
 }
 
-// prepareObjectForDiff removes the metadata and status fields from the given object
+// prepareObjectForDiff removes the metadata and status fields from the given object,
+// and normalizes the remaining fields.
 func prepareObjectForDiff(object *unstructured.Unstructured) *unstructured.Unstructured {
 	deepCopy := object.DeepCopy()
 	unstructured.RemoveNestedField(deepCopy.Object, "metadata")
 	unstructured.RemoveNestedField(deepCopy.Object, "status")
+	normalizeObject(deepCopy)
 	if err := fixHorizontalPodAutoscaler(deepCopy); err != nil {
 		return object
 	}
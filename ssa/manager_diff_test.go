@@ -392,3 +392,116 @@ func getKeys(m map[string]interface{}) []string {
 
 	return keys
 }
+
+func TestStructuredDiff(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("structured")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, configMap := getFirstObject(objects, "ConfigMap", id)
+
+	if _, err = manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("generates no operations for unchanged object", func(t *testing.T) {
+		objDiff, err := manager.StructuredDiff(ctx, configMap, DefaultDiffOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(UnchangedAction, objDiff.Action); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		if len(objDiff.Operations) != 0 {
+			t.Errorf("expected no operations, got %v", objDiff.Operations)
+		}
+	})
+
+	t.Run("generates operations for changed object", func(t *testing.T) {
+		newVal := "structured-diff-test"
+		err = unstructured.SetNestedField(configMap.Object, newVal, "data", "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		objDiff, err := manager.StructuredDiff(ctx, configMap, DefaultDiffOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(ConfiguredAction, objDiff.Action); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		if diff := cmp.Diff(id, objDiff.Name); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+
+		expected := []JSONPatchOperation{{Operation: "replace", Path: "/data/key", Value: newVal}}
+		if diff := cmp.Diff(expected, objDiff.Operations); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestDiffAll(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("diffall")
+	objects, err := readManifest("testdata/test1.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetNativeKindsDefaults(objects); err != nil {
+		t.Fatal(err)
+	}
+
+	_, configMap := getFirstObject(objects, "ConfigMap", id)
+	_, role := getFirstObject(objects, "ClusterRole", id)
+
+	if _, err = manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unstructured.SetNestedField(configMap.Object, "diff-all-test", "data", "key"); err != nil {
+		t.Fatal(err)
+	}
+	newRole := role.DeepCopy()
+	newRole.SetName(generateName("diffall"))
+	invalidConfigMap := configMap.DeepCopy()
+	invalidConfigMap.SetName(generateName("diffall"))
+	invalidConfigMap.SetNamespace(generateName("missing"))
+
+	report := manager.DiffAll(ctx, append(objects, newRole, invalidConfigMap), DefaultDiffOptions())
+
+	if report.Created != 1 || report.Configured != 1 || report.Failed != 1 {
+		t.Errorf("unexpected report counts: created %d, configured %d, failed %d",
+			report.Created, report.Configured, report.Failed)
+	}
+
+	if diff := cmp.Diff(len(objects)-1, report.Unchanged); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	for _, objDiff := range report.Objects {
+		if objDiff.Name == configMap.GetName() && objDiff.GroupVersionKind.Kind == "ConfigMap" {
+			if diff := cmp.Diff(ConfiguredAction, objDiff.Action); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		}
+		if objDiff.Name == invalidConfigMap.GetName() && objDiff.Error == "" {
+			t.Errorf("expected dry-run error for %s", objDiff.Name)
+		}
+	}
+}
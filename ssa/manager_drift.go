@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ObjectDrift holds the drift of an in-cluster object from its desired state.
+type ObjectDrift struct {
+	// GroupVersionKind is the API group, version and kind of the object.
+	GroupVersionKind schema.GroupVersionKind `json:"groupVersionKind"`
+
+	// Namespace is the namespace of the object, empty for cluster scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+
+	// Missing is true if the object doesn't exist in the cluster.
+	Missing bool `json:"missing,omitempty"`
+
+	// Fields holds the JSON pointers of the drifted fields, e.g. '/spec/replicas'.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// DriftReport is the result of a drift detection run.
+type DriftReport struct {
+	// Checked is the number of objects compared with their in-cluster state.
+	Checked int `json:"checked"`
+
+	// Drifted holds the objects which are missing or differ from their desired state.
+	Drifted []ObjectDrift `json:"drifted,omitempty"`
+
+	// Failed holds the objects for which the dry-run failed.
+	Failed []ObjectDiff `json:"failed,omitempty"`
+}
+
+// HasDrifted returns true if any object has drifted from its desired state.
+func (r *DriftReport) HasDrifted() bool {
+	return len(r.Drifted) > 0
+}
+
+// DetectDrift compares the given objects with their in-cluster state using
+// server-side apply dry-runs, and returns a report of the drifted objects and fields.
+// Unlike ApplyAll, it never writes to the cluster, which makes it suitable
+// for scheduled drift audits.
+func (m *ResourceManager) DetectDrift(ctx context.Context, objects []*unstructured.Unstructured, opts DiffOptions) *DriftReport {
+	return newDriftReport(m.DiffAll(ctx, objects, opts))
+}
+
+// newDriftReport extracts the drifted objects and fields from the given diff report.
+func newDriftReport(diff *DiffReport) *DriftReport {
+	report := &DriftReport{Checked: len(diff.Objects)}
+	for _, objDiff := range diff.Objects {
+		if objDiff.Error != "" {
+			report.Failed = append(report.Failed, objDiff)
+			continue
+		}
+
+		drift := ObjectDrift{
+			GroupVersionKind: objDiff.GroupVersionKind,
+			Namespace:        objDiff.Namespace,
+			Name:             objDiff.Name,
+		}
+		switch objDiff.Action {
+		case CreatedAction:
+			drift.Missing = true
+		case ConfiguredAction:
+			for _, op := range objDiff.Operations {
+				drift.Fields = append(drift.Fields, op.Path)
+			}
+		default:
+			continue
+		}
+		report.Drifted = append(report.Drifted, drift)
+	}
+	return report
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNewDriftReport(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	diff := &DiffReport{
+		Objects: []ObjectDiff{
+			{GroupVersionKind: gvk, Namespace: "default", Name: "missing", Action: CreatedAction},
+			{GroupVersionKind: gvk, Namespace: "default", Name: "unchanged", Action: UnchangedAction},
+			{
+				GroupVersionKind: gvk,
+				Namespace:        "default",
+				Name:             "drifted",
+				Action:           ConfiguredAction,
+				Operations: []JSONPatchOperation{
+					{Operation: "replace", Path: "/spec/replicas", Value: int64(1)},
+					{Operation: "remove", Path: "/metadata/labels/app"},
+				},
+			},
+			{GroupVersionKind: gvk, Namespace: "default", Name: "failed", Action: UnknownAction, Error: "dry-run failed"},
+		},
+	}
+
+	want := &DriftReport{
+		Checked: 4,
+		Drifted: []ObjectDrift{
+			{GroupVersionKind: gvk, Namespace: "default", Name: "missing", Missing: true},
+			{
+				GroupVersionKind: gvk,
+				Namespace:        "default",
+				Name:             "drifted",
+				Fields:           []string{"/spec/replicas", "/metadata/labels/app"},
+			},
+		},
+		Failed: []ObjectDiff{diff.Objects[3]},
+	}
+
+	got := newDriftReport(diff)
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", d)
+	}
+	if !got.HasDrifted() {
+		t.Error("expected report to have drifted objects")
+	}
+	if (&DriftReport{Checked: 1}).HasDrifted() {
+		t.Error("expected empty report to have no drifted objects")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWithLogger(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}
+	kubeClient := fake.NewClientBuilder().WithObjects(configMap).Build()
+
+	var logs []string
+	log := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+	logged := rm.WithLogger(log)
+	if rm.log.GetSink() != nil {
+		t.Fatal("expected WithLogger to return a copy of the manager")
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind("ConfigMap")
+	object.SetNamespace("default")
+	object.SetName("test")
+
+	if _, err := logged.DeleteAll(context.Background(), []*unstructured.Unstructured{object}, DefaultDeleteOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`"msg"="object deleted" "object"="ConfigMap/default/test" "dryRun"=false`,
+		`"msg"="delete completed" "objects"=1`,
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("expected %d log lines, got %v", len(want), logs)
+	}
+	for i, line := range logs {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("expected log line %q to contain %q", line, want[i])
+		}
+	}
+}
+
+func TestWithLogger_SharesApplyFallback(t *testing.T) {
+	rm := NewResourceManager(fake.NewClientBuilder().Build(), nil, Owner{Field: "manager", Group: "manager.io"})
+	unsupported := apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "PATCH",
+		schema.GroupResource{Resource: "configmaps"}, "test", "", 0, false)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rm.fallbackToClientSideApply(unsupported)
+	}()
+	logged := rm.WithLogger(logr.Discard())
+	<-done
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind("ConfigMap")
+	if !logged.useClientSideApply(object) {
+		t.Error("expected the copy to share the client-side apply fallback")
+	}
+}
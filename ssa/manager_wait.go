@@ -21,10 +21,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/aggregator"
@@ -43,8 +45,24 @@ type WaitOptions struct {
 	// Timeout defines after which interval should the engine give up on waiting for resources
 	// to become ready.
 	Timeout time.Duration
+
+	// KindTimeouts overrides the Timeout for the resources of the given kinds,
+	// e.g. to wait longer for StatefulSets than for the other resources.
+	KindTimeouts map[schema.GroupKind]time.Duration
+
+	// OnStatusChange is called for every status transition of the resources while waiting,
+	// e.g. to render the progress or to emit events. A nil OnStatusChange disables the callbacks.
+	OnStatusChange StatusChangeFunc
 }
 
+// WaitTimeoutAnnotation overrides the wait timeout of an object, the value must be
+// a duration, e.g. 'ssa.fluxcd.io/wait-timeout: 10m'.
+const WaitTimeoutAnnotation = "ssa.fluxcd.io/wait-timeout"
+
+// StatusChangeFunc receives the status transitions of the resources during a wait,
+// the old status of a resource is 'Unknown' until its first transition.
+type StatusChangeFunc func(id object.ObjMetadata, oldStatus, newStatus status.Status, message string)
+
 // DefaultWaitOptions returns the default wait options where the poll interval is set to
 // five seconds and the timeout to one minute.
 func DefaultWaitOptions() WaitOptions {
@@ -55,28 +73,81 @@ func DefaultWaitOptions() WaitOptions {
 }
 
 // Wait checks if the given set of objects has been fully reconciled.
+// The timeout of an object can be overridden with the WaitTimeoutAnnotation.
 func (m *ResourceManager) Wait(objects []*unstructured.Unstructured, opts WaitOptions) error {
 	objectsMeta := object.UnstructuredSetToObjMetadataSet(objects)
 	if len(objectsMeta) == 0 {
 		return nil
 	}
 
-	return m.WaitForSet(objectsMeta, opts)
+	timeouts, err := objectTimeouts(objects)
+	if err != nil {
+		return err
+	}
+
+	return m.waitForSet(objectsMeta, opts, timeouts)
 }
 
 // WaitForSet checks if the given set of ObjMetadata has been fully reconciled.
 func (m *ResourceManager) WaitForSet(set object.ObjMetadataSet, opts WaitOptions) error {
+	return m.waitForSet(set, opts, nil)
+}
+
+// waitForSet checks if the given set of ObjMetadata has been fully reconciled, it fails
+// as soon as a resource is not ready within its own timeout, which is looked up in the
+// given object timeouts, then in the options kind timeouts.
+func (m *ResourceManager) waitForSet(set object.ObjMetadataSet, opts WaitOptions, objectTimeouts map[object.ObjMetadata]time.Duration) (err error) {
+	defer func(start time.Time) {
+		m.recordOperation(WaitOperation, start, nil, err)
+		m.logger().Info("wait completed", "objects", len(set),
+			"duration", time.Since(start), "error", err)
+	}(time.Now())
+
 	statusCollector := collector.NewResourceStatusCollector(set)
 
-	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	timeouts := make(map[object.ObjMetadata]time.Duration, len(set))
+	maxTimeout := time.Duration(0)
+	for _, id := range set {
+		timeout, ok := objectTimeouts[id]
+		if !ok {
+			timeout = opts.timeout(id.GroupKind)
+		}
+		timeouts[id] = timeout
+		if timeout > maxTimeout {
+			maxTimeout = timeout
+		}
+	}
+	if maxTimeout == 0 {
+		maxTimeout = opts.Timeout
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), maxTimeout)
 	defer cancel()
 
+	// stop waiting as soon as a resource exceeds its own timeout, the deadlines are checked
+	// on timers since kstatus emits events only when the status of a resource changes
+	var timedOut int32
+	for _, timeout := range distinctTimeouts(timeouts, maxTimeout) {
+		timer := time.AfterFunc(timeout, func() {
+			for _, rs := range statusCollector.LatestObservation().ResourceStatuses {
+				if rs.Status != status.CurrentStatus && time.Since(start) >= timeouts[rs.Identifier] {
+					atomic.StoreInt32(&timedOut, 1)
+					cancel()
+					return
+				}
+			}
+		})
+		defer timer.Stop()
+	}
+
 	pollingOpts := polling.PollOptions{
 		PollInterval: opts.Interval,
 	}
-	eventsChan := m.poller.Poll(ctx, set, pollingOpts)
+	eventsChan := m.statusPoller().Poll(ctx, set, pollingOpts)
 
 	lastStatus := make(map[object.ObjMetadata]*event.ResourceStatus)
+	reportedStatus := make(map[object.ObjMetadata]status.Status)
 
 	done := statusCollector.ListenWithObserver(eventsChan, collector.ObserverFunc(
 		func(statusCollector *collector.ResourceStatusCollector, e event.Event) {
@@ -92,6 +163,17 @@ func (m *ResourceManager) WaitForSet(set object.ObjMetadataSet, opts WaitOptions
 					lastStatus[rs.Identifier] = rs
 				}
 				rss = append(rss, rs)
+
+				if opts.OnStatusChange != nil {
+					oldStatus, ok := reportedStatus[rs.Identifier]
+					if !ok {
+						oldStatus = status.UnknownStatus
+					}
+					if rs.Status != oldStatus {
+						reportedStatus[rs.Identifier] = rs.Status
+						opts.OnStatusChange(rs.Identifier, oldStatus, rs.Status, rs.Message)
+					}
+				}
 			}
 
 			desired := status.CurrentStatus
@@ -109,9 +191,17 @@ func (m *ResourceManager) WaitForSet(set object.ObjMetadataSet, opts WaitOptions
 		return statusCollector.Error
 	}
 
-	if ctx.Err() == context.DeadlineExceeded {
+	if atomic.LoadInt32(&timedOut) == 1 || ctx.Err() == context.DeadlineExceeded {
+		elapsed := time.Since(start)
 		var errors = []string{}
 		for id, rs := range statusCollector.ResourceStatuses {
+			if elapsed < timeouts[id] {
+				continue
+			}
+			if rs != nil && lastStatus[id] != nil && lastStatus[id].Status == status.CurrentStatus {
+				continue
+			}
+			m.recordFailure(id.GroupKind, WaitOperation)
 			if rs == nil {
 				errors = append(errors, fmt.Sprintf("can't determine status for %s", FmtObjMetadata(id)))
 				continue
@@ -135,6 +225,45 @@ func (m *ResourceManager) WaitForSet(set object.ObjMetadataSet, opts WaitOptions
 	return nil
 }
 
+// distinctTimeouts returns the distinct timeouts shorter than the given max timeout.
+func distinctTimeouts(timeouts map[object.ObjMetadata]time.Duration, maxTimeout time.Duration) []time.Duration {
+	seen := make(map[time.Duration]bool)
+	var result []time.Duration
+	for _, timeout := range timeouts {
+		if timeout < maxTimeout && !seen[timeout] {
+			seen[timeout] = true
+			result = append(result, timeout)
+		}
+	}
+	return result
+}
+
+// timeout returns the timeout for the resources of the given kind.
+func (o WaitOptions) timeout(gk schema.GroupKind) time.Duration {
+	if timeout, ok := o.KindTimeouts[gk]; ok {
+		return timeout
+	}
+	return o.Timeout
+}
+
+// objectTimeouts returns the timeouts of the given objects set with the WaitTimeoutAnnotation.
+func objectTimeouts(objects []*unstructured.Unstructured) (map[object.ObjMetadata]time.Duration, error) {
+	timeouts := make(map[object.ObjMetadata]time.Duration)
+	for _, obj := range objects {
+		value, ok := obj.GetAnnotations()[WaitTimeoutAnnotation]
+		if !ok {
+			continue
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid %s annotation '%s', error: %w",
+				FmtUnstructured(obj), WaitTimeoutAnnotation, value, err)
+		}
+		timeouts[object.UnstructuredToObjMetadata(obj)] = timeout
+	}
+	return timeouts, nil
+}
+
 // WaitForTermination waits for the given objects to be deleted from the cluster.
 func (m *ResourceManager) WaitForTermination(objects []*unstructured.Unstructured, opts WaitOptions) error {
 	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
@@ -148,6 +277,100 @@ func (m *ResourceManager) WaitForTermination(objects []*unstructured.Unstructure
 	return nil
 }
 
+// WaitForSetTermination waits for the given set of objects to be deleted from the cluster,
+// including the removal of their finalizers. The objects status transitions, 'InProgress' while
+// terminating and 'NotFound' once deleted, are reported to the options OnStatusChange.
+// It fails as soon as an object is not deleted within the timeout of its kind,
+// the error lists the objects left in the cluster and their finalizers.
+func (m *ResourceManager) WaitForSetTermination(set object.ObjMetadataSet, opts WaitOptions) error {
+	maxTimeout := opts.Timeout
+	for _, id := range set {
+		if timeout := opts.timeout(id.GroupKind); timeout > maxTimeout {
+			maxTimeout = timeout
+		}
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), maxTimeout)
+	defer cancel()
+
+	reportedStatus := make(map[object.ObjMetadata]status.Status, len(set))
+	report := func(id object.ObjMetadata, newStatus status.Status, message string) {
+		oldStatus, ok := reportedStatus[id]
+		if !ok {
+			oldStatus = status.UnknownStatus
+		}
+		if opts.OnStatusChange != nil && newStatus != oldStatus {
+			opts.OnStatusChange(id, oldStatus, newStatus, message)
+		}
+		reportedStatus[id] = newStatus
+	}
+
+	remaining := make([]object.ObjMetadata, len(set))
+	copy(remaining, set)
+	finalizers := make(map[object.ObjMetadata][]string)
+	timedOut := false
+
+	err := wait.PollImmediateUntil(opts.Interval, func() (bool, error) {
+		var pending []object.ObjMetadata
+		for _, id := range remaining {
+			existing, err := m.getObject(ctx, id)
+			// the kinds of the deleted CRDs are no longer served
+			if apierrors.IsNotFound(err) || IsNoMatchError(err) {
+				report(id, status.NotFoundStatus, "Resource deleted")
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+
+			finalizers[id] = existing.GetFinalizers()
+			report(id, status.InProgressStatus, "Resource is being deleted")
+			pending = append(pending, id)
+			if time.Since(start) >= opts.timeout(id.GroupKind) {
+				timedOut = true
+			}
+		}
+		remaining = pending
+		return len(remaining) == 0 || timedOut, nil
+	}, ctx.Done())
+
+	if len(remaining) == 0 {
+		return nil
+	}
+	if err != nil && err != wait.ErrWaitTimeout {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	var errors []string
+	for _, id := range remaining {
+		if elapsed < opts.timeout(id.GroupKind) {
+			continue
+		}
+		msg := FmtObjMetadata(id)
+		if len(finalizers[id]) > 0 {
+			msg += fmt.Sprintf(" finalizers: [%s]", strings.Join(finalizers[id], ", "))
+		}
+		errors = append(errors, msg)
+	}
+	return fmt.Errorf("termination timeout waiting for: [%s]", strings.Join(errors, ", "))
+}
+
+// getObject returns the in-cluster object of the given ObjMetadata, the object
+// version is looked up with the client RESTMapper.
+func (m *ResourceManager) getObject(ctx context.Context, id object.ObjMetadata) (*unstructured.Unstructured, error) {
+	mapping, err := m.client.RESTMapper().RESTMapping(id.GroupKind)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(mapping.GroupVersionKind)
+	err = m.client.Get(ctx, client.ObjectKey{Namespace: id.Namespace, Name: id.Name}, obj)
+	return obj, err
+}
+
 func (m *ResourceManager) isDeleted(ctx context.Context, object *unstructured.Unstructured) wait.ConditionFunc {
 	return func() (bool, error) {
 		obj := object.DeepCopy()
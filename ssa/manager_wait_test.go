@@ -19,13 +19,22 @@ package ssa
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestWaitForSet(t *testing.T) {
@@ -59,7 +68,7 @@ func TestWaitForSet(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), WaitOptions{time.Second, 3 * time.Second}); err == nil {
+		if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), WaitOptions{Interval: time.Second, Timeout: 3 * time.Second}); err == nil {
 			t.Error("wanted wait error due to observedGeneration < generation")
 		}
 
@@ -93,3 +102,263 @@ func TestWaitForSet(t *testing.T) {
 		}
 	})
 }
+
+func TestWaitForSet_OnStatusChange(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("progress")
+	objects, err := readManifest("testdata/test5.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, crd := getFirstObject(objects, "CustomResourceDefinition", "clustertests.testing.fluxcd.io")
+
+	cs, err := manager.Apply(ctx, crd, DefaultApplyOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var transitions []status.Status
+	opts := DefaultWaitOptions()
+	opts.Interval = time.Second
+	opts.OnStatusChange = func(id object.ObjMetadata, oldStatus, newStatus status.Status, message string) {
+		if id != cs.ObjMetadata {
+			t.Errorf("unexpected object %s", FmtObjMetadata(id))
+		}
+		if oldStatus == newStatus {
+			t.Errorf("unexpected transition from %s to %s", oldStatus, newStatus)
+		}
+		transitions = append(transitions, newStatus)
+	}
+
+	if err := manager.WaitForSet([]object.ObjMetadata{cs.ObjMetadata}, opts); err != nil {
+		t.Fatalf("wait failed for CRD: %v", err)
+	}
+
+	if len(transitions) == 0 || transitions[len(transitions)-1] != status.CurrentStatus {
+		t.Errorf("expected the last transition to be %s, got %v", status.CurrentStatus, transitions)
+	}
+}
+
+func TestWaitForSet_KindTimeouts(t *testing.T) {
+	timeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id := generateName("wait-timeouts")
+	objects, err := readManifest("testdata/test5.yaml", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.ApplyAllStaged(ctx, objects, DefaultApplyOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cr := getFirstObject(objects, "ClusterTest", id)
+
+	t.Run("fails within the kind timeout", func(t *testing.T) {
+		opts := WaitOptions{
+			Interval: time.Second,
+			Timeout:  time.Minute,
+			KindTimeouts: map[schema.GroupKind]time.Duration{
+				{Group: "testing.fluxcd.io", Kind: "ClusterTest"}: 3 * time.Second,
+			},
+		}
+
+		start := time.Now()
+		if err := manager.Wait([]*unstructured.Unstructured{cr}, opts); err == nil {
+			t.Error("wanted wait error due to observedGeneration < generation")
+		}
+		if elapsed := time.Since(start); elapsed > timeout {
+			t.Errorf("wanted wait to fail within the kind timeout, took %s", elapsed)
+		}
+	})
+
+	t.Run("fails within the object timeout", func(t *testing.T) {
+		annotated := cr.DeepCopy()
+		annotated.SetAnnotations(map[string]string{WaitTimeoutAnnotation: "3s"})
+
+		start := time.Now()
+		if err := manager.Wait([]*unstructured.Unstructured{annotated}, WaitOptions{Interval: time.Second, Timeout: time.Minute}); err == nil {
+			t.Error("wanted wait error due to observedGeneration < generation")
+		}
+		if elapsed := time.Since(start); elapsed > timeout {
+			t.Errorf("wanted wait to fail within the object timeout, took %s", elapsed)
+		}
+	})
+}
+
+func TestWaitForSet_ShortTimeoutStuck(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+
+	// the deployments without status are reported as in progress
+	replicas := int32(1)
+	newDeployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			},
+		}
+	}
+	kubeClient := fake.NewClientBuilder().WithRESTMapper(mapper).
+		WithObjects(newDeployment("short"), newDeployment("long")).Build()
+	poller := polling.NewStatusPoller(kubeClient, mapper, polling.Options{})
+	rm := NewResourceManager(kubeClient, poller, Owner{Field: "manager", Group: "manager.io"})
+
+	newObject := func(name string, annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+		u.SetNamespace("default")
+		u.SetName(name)
+		u.SetAnnotations(annotations)
+		return u
+	}
+	objects := []*unstructured.Unstructured{
+		newObject("short", map[string]string{WaitTimeoutAnnotation: "500ms"}),
+		newObject("long", nil),
+	}
+
+	start := time.Now()
+	err := rm.Wait(objects, WaitOptions{Interval: 100 * time.Millisecond, Timeout: time.Minute})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("wanted wait to fail within the object timeout, took %s", elapsed)
+	}
+	if !strings.Contains(err.Error(), "Deployment/default/short") || strings.Contains(err.Error(), "Deployment/default/long") {
+		t.Errorf("expected only the short timeout object in the error, got %v", err)
+	}
+}
+
+func TestWaitOptions_Timeouts(t *testing.T) {
+	opts := WaitOptions{
+		Timeout: time.Minute,
+		KindTimeouts: map[schema.GroupKind]time.Duration{
+			{Group: "apps", Kind: "StatefulSet"}: 10 * time.Minute,
+		},
+	}
+
+	if got := opts.timeout(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}); got != 10*time.Minute {
+		t.Errorf("expected kind timeout, got %s", got)
+	}
+	if got := opts.timeout(schema.GroupKind{Group: "apps", Kind: "Deployment"}); got != time.Minute {
+		t.Errorf("expected default timeout, got %s", got)
+	}
+
+	newObject := func(name, timeout string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("apps/v1")
+		u.SetKind("Deployment")
+		u.SetNamespace("default")
+		u.SetName(name)
+		if timeout != "" {
+			u.SetAnnotations(map[string]string{WaitTimeoutAnnotation: timeout})
+		}
+		return u
+	}
+
+	annotated := newObject("annotated", "5m")
+	timeouts, err := objectTimeouts([]*unstructured.Unstructured{annotated, newObject("default", "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeouts) != 1 || timeouts[object.UnstructuredToObjMetadata(annotated)] != 5*time.Minute {
+		t.Errorf("expected the annotated object timeout, got %v", timeouts)
+	}
+
+	if _, err := objectTimeouts([]*unstructured.Unstructured{newObject("invalid", "5 minutes")}); err == nil {
+		t.Error("expected error for invalid timeout annotation")
+	}
+}
+
+func TestWaitForSetTermination(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	kubeClient := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "finalized", Namespace: "default", Finalizers: []string{"example.com/cleanup"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}},
+	).Build()
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+	ctx := context.Background()
+	for _, name := range []string{"finalized", "deleted"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		if err := kubeClient.Delete(ctx, cm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idFor := func(name string) object.ObjMetadata {
+		return object.ObjMetadata{GroupKind: schema.GroupKind{Kind: "ConfigMap"}, Namespace: "default", Name: name}
+	}
+
+	t.Run("waits for the deleted objects", func(t *testing.T) {
+		transitions := map[string][]status.Status{}
+		opts := WaitOptions{
+			Interval: 10 * time.Millisecond,
+			Timeout:  time.Second,
+			OnStatusChange: func(id object.ObjMetadata, _, newStatus status.Status, _ string) {
+				transitions[id.Name] = append(transitions[id.Name], newStatus)
+			},
+		}
+
+		if err := rm.WaitForSetTermination(object.ObjMetadataSet{idFor("deleted")}, opts); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string][]status.Status{"deleted": {status.NotFoundStatus}}, transitions); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("completes for the objects of deleted CRDs", func(t *testing.T) {
+		custom := object.ObjMetadata{
+			GroupKind: schema.GroupKind{Group: "example.com", Kind: "Custom"},
+			Namespace: "default",
+			Name:      "custom",
+		}
+		transitions := map[string][]status.Status{}
+		opts := WaitOptions{
+			Interval: 10 * time.Millisecond,
+			Timeout:  time.Second,
+			OnStatusChange: func(id object.ObjMetadata, _, newStatus status.Status, _ string) {
+				transitions[id.Name] = append(transitions[id.Name], newStatus)
+			},
+		}
+
+		if err := rm.WaitForSetTermination(object.ObjMetadataSet{custom, idFor("deleted")}, opts); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string][]status.Status{"custom": {status.NotFoundStatus}, "deleted": {status.NotFoundStatus}}
+		if diff := cmp.Diff(want, transitions); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("fails for the objects with finalizers", func(t *testing.T) {
+		opts := WaitOptions{
+			Interval: 10 * time.Millisecond,
+			Timeout:  time.Minute,
+			KindTimeouts: map[schema.GroupKind]time.Duration{
+				{Kind: "ConfigMap"}: 50 * time.Millisecond,
+			},
+		}
+
+		err := rm.WaitForSetTermination(object.ObjMetadataSet{idFor("deleted"), idFor("finalized")}, opts)
+		if err == nil {
+			t.Fatal("expected termination timeout error")
+		}
+		want := "termination timeout waiting for: [ConfigMap/default/finalized finalizers: [example.com/cleanup]]"
+		if diff := cmp.Diff(want, err.Error()); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+}
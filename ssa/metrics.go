@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Operation is the type of the operations measured by the MetricsRecorder.
+type Operation string
+
+const (
+	ApplyOperation  Operation = "apply"
+	DeleteOperation Operation = "delete"
+	WaitOperation   Operation = "wait"
+)
+
+// MetricsRecorder receives the measurements of the ResourceManager operations,
+// e.g. to expose them as Prometheus metrics with the PrometheusRecorder.
+type MetricsRecorder interface {
+	// RecordDuration is called at the end of each ApplyAll, DeleteAll and Wait operation.
+	RecordDuration(operation Operation, duration time.Duration, success bool)
+
+	// RecordAction is called for each object applied or deleted, with the action performed.
+	RecordAction(gk schema.GroupKind, action Action)
+
+	// RecordFailure is called for each object which failed to be applied, deleted or to become ready.
+	RecordFailure(gk schema.GroupKind, operation Operation)
+}
+
+// SetMetricsRecorder configures the ResourceManager to report the operations measurements
+// to the given recorder, a nil recorder disables the measurements.
+func (m *ResourceManager) SetMetricsRecorder(recorder MetricsRecorder) {
+	m.metricsRecorder = recorder
+}
+
+// recordOperation reports the duration of the operation started at the given time,
+// and the actions of the change set entries, the unknown actions are reported as failures.
+func (m *ResourceManager) recordOperation(operation Operation, start time.Time, changeSet *ChangeSet, err error) {
+	if m.metricsRecorder == nil {
+		return
+	}
+
+	m.metricsRecorder.RecordDuration(operation, time.Since(start), err == nil)
+	if changeSet == nil {
+		return
+	}
+	for _, entry := range changeSet.Entries {
+		if entry.Action == string(UnknownAction) {
+			m.metricsRecorder.RecordFailure(entry.ObjMetadata.GroupKind, operation)
+			continue
+		}
+		m.metricsRecorder.RecordAction(entry.ObjMetadata.GroupKind, Action(entry.Action))
+	}
+}
+
+// recordFailure reports the failure of the operation for an object of the given kind.
+func (m *ResourceManager) recordFailure(gk schema.GroupKind, operation Operation) {
+	if m.metricsRecorder != nil {
+		m.metricsRecorder.RecordFailure(gk, operation)
+	}
+}
+
+// PrometheusRecorder is a MetricsRecorder exposing the measurements as Prometheus metrics,
+// it must be registered with a Prometheus registry, e.g. the controller-runtime metrics registry.
+type PrometheusRecorder struct {
+	durations *prometheus.HistogramVec
+	actions   *prometheus.CounterVec
+	failures  *prometheus.CounterVec
+}
+
+// NewPrometheusRecorder returns a PrometheusRecorder with the following metrics:
+//
+//	ssa_operation_duration_seconds{operation, success}
+//	ssa_object_actions_total{group, kind, action}
+//	ssa_object_failures_total{group, kind, operation}
+func NewPrometheusRecorder() *PrometheusRecorder {
+	return &PrometheusRecorder{
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ssa_operation_duration_seconds",
+			Help:    "The duration in seconds of the apply, delete and wait operations.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 15),
+		}, []string{"operation", "success"}),
+		actions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ssa_object_actions_total",
+			Help: "The number of objects applied or deleted, by kind and action.",
+		}, []string{"group", "kind", "action"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ssa_object_failures_total",
+			Help: "The number of objects which failed to be applied, deleted or to become ready, by kind and operation.",
+		}, []string{"group", "kind", "operation"}),
+	}
+}
+
+// RecordDuration observes the duration of the operation.
+func (r *PrometheusRecorder) RecordDuration(operation Operation, duration time.Duration, success bool) {
+	r.durations.WithLabelValues(string(operation), strconv.FormatBool(success)).Observe(duration.Seconds())
+}
+
+// RecordAction increments the actions counter of the given kind.
+func (r *PrometheusRecorder) RecordAction(gk schema.GroupKind, action Action) {
+	r.actions.WithLabelValues(gk.Group, gk.Kind, string(action)).Inc()
+}
+
+// RecordFailure increments the failures counter of the given kind.
+func (r *PrometheusRecorder) RecordFailure(gk schema.GroupKind, operation Operation) {
+	r.failures.WithLabelValues(gk.Group, gk.Kind, string(operation)).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (r *PrometheusRecorder) Describe(ch chan<- *prometheus.Desc) {
+	r.durations.Describe(ch)
+	r.actions.Describe(ch)
+	r.failures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (r *PrometheusRecorder) Collect(ch chan<- prometheus.Metric) {
+	r.durations.Collect(ch)
+	r.actions.Collect(ch)
+	r.failures.Collect(ch)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrometheusRecorder(t *testing.T) {
+	recorder := NewPrometheusRecorder()
+	rm := &ResourceManager{owner: Owner{Field: "manager", Group: "manager.io"}}
+	rm.SetMetricsRecorder(recorder)
+
+	configMapKind := schema.GroupKind{Kind: "ConfigMap"}
+	deploymentKind := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	changeSet := NewChangeSet()
+	changeSet.Add(ChangeSetEntry{ObjMetadata: object.ObjMetadata{GroupKind: configMapKind, Name: "a"}, Action: string(CreatedAction)})
+	changeSet.Add(ChangeSetEntry{ObjMetadata: object.ObjMetadata{GroupKind: configMapKind, Name: "b"}, Action: string(CreatedAction)})
+	changeSet.Add(ChangeSetEntry{ObjMetadata: object.ObjMetadata{GroupKind: deploymentKind, Name: "c"}, Action: string(UnchangedAction)})
+	rm.recordOperation(ApplyOperation, time.Now(), changeSet, nil)
+
+	deleteSet := NewChangeSet()
+	deleteSet.Add(ChangeSetEntry{ObjMetadata: object.ObjMetadata{GroupKind: deploymentKind, Name: "c"}, Action: string(UnknownAction)})
+	rm.recordOperation(DeleteOperation, time.Now(), deleteSet, errors.New("delete failed"))
+
+	rm.recordFailure(deploymentKind, WaitOperation)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{
+			name: "created config maps",
+			got:  testutil.ToFloat64(recorder.actions.WithLabelValues("", "ConfigMap", string(CreatedAction))),
+			want: 2,
+		},
+		{
+			name: "unchanged deployments",
+			got:  testutil.ToFloat64(recorder.actions.WithLabelValues("apps", "Deployment", string(UnchangedAction))),
+			want: 1,
+		},
+		{
+			name: "deployment delete failures",
+			got:  testutil.ToFloat64(recorder.failures.WithLabelValues("apps", "Deployment", string(DeleteOperation))),
+			want: 1,
+		},
+		{
+			name: "deployment wait failures",
+			got:  testutil.ToFloat64(recorder.failures.WithLabelValues("apps", "Deployment", string(WaitOperation))),
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+
+	if n := testutil.CollectAndCount(recorder, "ssa_operation_duration_seconds"); n != 2 {
+		t.Errorf("expected 2 duration series, got %d", n)
+	}
+}
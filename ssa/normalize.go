@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// quantityFields holds the keys of the maps whose values are resource quantities,
+// e.g. the containers resources limits and requests.
+var quantityFields = map[string]bool{
+	"limits":      true,
+	"requests":    true,
+	"capacity":    true,
+	"allocatable": true,
+	"hard":        true,
+	"used":        true,
+}
+
+// normalizeObject removes the known representation differences between
+// equivalent objects, so that they are not reported as drift:
+//   - the null values, empty maps and empty lists are removed, e.g. 'creationTimestamp: null'
+//   - the resource quantities are converted to their decimal form, e.g. '1Ki' and '1024' to '1024'
+//   - the ports without a protocol are set to TCP
+//   - the Secrets stringData entries are base64 encoded into data
+func normalizeObject(object *unstructured.Unstructured) {
+	if object.GetAPIVersion() == "v1" && object.GetKind() == "Secret" {
+		normalizeSecretData(object.Object)
+	}
+	normalizeMap("", object.Object)
+}
+
+// normalizeValue returns the normalized value, and false if the value is to be removed.
+func normalizeValue(key string, value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		normalizeMap(key, v)
+		return v, len(v) > 0
+	case []interface{}:
+		for i, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				normalizeMap("", m)
+				if key == "ports" {
+					setDefaultProtocol(m)
+				}
+				v[i] = m
+			}
+		}
+		return v, len(v) > 0
+	default:
+		return v, true
+	}
+}
+
+func normalizeMap(key string, m map[string]interface{}) {
+	for k, v := range m {
+		if quantityFields[key] {
+			v = canonicalQuantity(v)
+		}
+		if nv, keep := normalizeValue(k, v); keep {
+			m[k] = nv
+		} else {
+			delete(m, k)
+		}
+	}
+}
+
+// canonicalQuantity returns the decimal form of the given quantity, which doesn't depend on its format,
+// or the value itself if it's not a valid quantity.
+func canonicalQuantity(value interface{}) interface{} {
+	switch value.(type) {
+	case string, int64, float64:
+		q, err := resource.ParseQuantity(fmt.Sprint(value))
+		if err != nil {
+			return value
+		}
+		dec := q.AsDec().String()
+		if strings.Contains(dec, ".") {
+			dec = strings.TrimRight(strings.TrimRight(dec, "0"), ".")
+		}
+		return dec
+	default:
+		return value
+	}
+}
+
+// setDefaultProtocol sets the protocol of the container and service ports to TCP if not specified.
+func setDefaultProtocol(port map[string]interface{}) {
+	if _, ok := port["protocol"]; ok {
+		return
+	}
+	_, isContainerPort := port["containerPort"]
+	_, isServicePort := port["port"]
+	if isContainerPort || isServicePort {
+		port["protocol"] = "TCP"
+	}
+}
+
+// normalizeSecretData moves the Secret stringData entries to data, the stringData
+// entries overwrite the data entries with the same key.
+func normalizeSecretData(secret map[string]interface{}) {
+	stringData, ok := secret["stringData"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	data, ok := secret["data"].(map[string]interface{})
+	if !ok {
+		data = make(map[string]interface{}, len(stringData))
+	}
+	for k, v := range stringData {
+		if s, ok := v.(string); ok {
+			data[k] = base64.StdEncoding.EncodeToString([]byte(s))
+		}
+	}
+	secret["data"] = data
+	delete(secret, "stringData")
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   string
+		expected string
+	}{
+		{
+			name: "quantities and port protocols",
+			object: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  creationTimestamp: null
+spec:
+  template:
+    metadata:
+      annotations: {}
+    spec:
+      containers:
+      - name: app
+        ports:
+        - containerPort: 8080
+        - containerPort: 9090
+          protocol: UDP
+        resources:
+          limits:
+            cpu: 1000m
+            memory: 1024Mi
+          requests:
+            cpu: 0.5
+            memory: 134217728
+        env: []
+`,
+			expected: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+        - containerPort: 9090
+          protocol: UDP
+        resources:
+          limits:
+            cpu: "1"
+            memory: "1073741824"
+          requests:
+            cpu: "0.5"
+            memory: "134217728"
+`,
+		},
+		{
+			name: "secret string data",
+			object: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+  namespace: default
+data:
+  a: b2xk
+  b: Yg==
+stringData:
+  a: new
+`,
+			expected: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+  namespace: default
+data:
+  a: bmV3
+  b: Yg==
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := ReadObject(strings.NewReader(tt.object))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := ReadObject(strings.NewReader(tt.expected))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			normalizeObject(object)
+			if diff := cmp.Diff(expected.Object, object.Object); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHasObjectDrifted_Normalized(t *testing.T) {
+	existing, err := ReadObject(strings.NewReader(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+spec:
+  selector: {}
+  ports:
+  - port: 80
+    protocol: TCP
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired, err := ReadObject(strings.NewReader(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+spec:
+  ports:
+  - port: 80
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hasObjectDrifted(existing, desired) {
+		t.Error("expected equivalent objects not to have drifted")
+	}
+
+	unstructuredPorts := desired.Object["spec"].(map[string]interface{})["ports"].([]interface{})
+	unstructuredPorts[0].(map[string]interface{})["port"] = int64(8080)
+	if !hasObjectDrifted(existing, desired) {
+		t.Error("expected changed objects to have drifted")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ManagerFactory creates a ResourceManager for the cluster of the given config.
+type ManagerFactory func(config *rest.Config) (*ResourceManager, error)
+
+// NewManagerFactory returns a ManagerFactory which creates the ResourceManagers with
+// a lazy discovery RESTMapper, the given owner and custom status readers.
+func NewManagerFactory(owner Owner, statusReaders ...engine.StatusReader) ManagerFactory {
+	return func(config *rest.Config) (*ResourceManager, error) {
+		mapper, err := apiutil.NewDynamicRESTMapper(config, apiutil.WithLazyDiscovery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create RESTMapper for '%s', error: %w", config.Host, err)
+		}
+
+		kubeClient, err := client.New(config, client.Options{Mapper: mapper})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for '%s', error: %w", config.Host, err)
+		}
+
+		poller := polling.NewStatusPoller(kubeClient, mapper, polling.Options{})
+		rm := NewResourceManager(kubeClient, poller, owner)
+		rm.SetCustomStatusReaders(statusReaders...)
+		return rm, nil
+	}
+}
+
+// ManagerPool caches the ResourceManagers of multiple clusters, so that the
+// clients, RESTMappers and pollers are reused across reconciliations.
+// The cached ResourceManagers are recreated after the TTL expires, to refresh
+// the cluster credentials and discovery information.
+type ManagerPool struct {
+	factory ManagerFactory
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	managers map[string]pooledManager
+}
+
+type pooledManager struct {
+	manager *ResourceManager
+	created time.Time
+}
+
+// NewManagerPool creates a ManagerPool which creates the ResourceManagers with the given factory.
+// A zero TTL means the ResourceManagers are cached until evicted.
+func NewManagerPool(factory ManagerFactory, ttl time.Duration) *ManagerPool {
+	return &ManagerPool{
+		factory:  factory,
+		ttl:      ttl,
+		now:      time.Now,
+		managers: make(map[string]pooledManager),
+	}
+}
+
+// Get returns the ResourceManager cached for the cluster identified by key, e.g. the namespace
+// and name of the kubeconfig secret. If no ResourceManager is cached, or if its TTL has expired,
+// a new one is created from the given config.
+func (p *ManagerPool) Get(key string, config *rest.Config) (*ResourceManager, error) {
+	p.mu.Lock()
+	pm, ok := p.managers[key]
+	p.mu.Unlock()
+
+	if ok && !p.expired(pm) {
+		return pm.manager, nil
+	}
+
+	// the factory is called without holding the lock, as the discovery requests
+	// of a cluster should not block the access to the others
+	manager, err := p.factory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.managers[key] = pooledManager{manager: manager, created: p.now()}
+	return manager, nil
+}
+
+// Evict removes the ResourceManager of the cluster identified by key from the pool,
+// e.g. when the cluster kubeconfig changes or the cluster is deleted.
+func (p *ManagerPool) Evict(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.managers, key)
+}
+
+// Len returns the number of cached ResourceManagers, including the expired ones.
+func (p *ManagerPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.managers)
+}
+
+func (p *ManagerPool) expired(pm pooledManager) bool {
+	return p.ttl > 0 && p.now().Sub(pm.created) >= p.ttl
+}
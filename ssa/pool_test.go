@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestManagerPool(t *testing.T) {
+	created := map[string]int{}
+	factory := func(config *rest.Config) (*ResourceManager, error) {
+		if config.Host == "" {
+			return nil, errors.New("host not specified")
+		}
+		created[config.Host]++
+		return NewResourceManager(fake.NewClientBuilder().Build(), nil, Owner{Field: "manager", Group: "manager.io"}), nil
+	}
+
+	now := time.Now()
+	pool := NewManagerPool(factory, time.Minute)
+	pool.now = func() time.Time { return now }
+
+	staging := &rest.Config{Host: "https://staging.example.com"}
+	production := &rest.Config{Host: "https://production.example.com"}
+
+	first, err := pool.Get("staging", staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pool.Get("staging", staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected the cached ResourceManager to be reused")
+	}
+
+	if _, err := pool.Get("production", production); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Len() != 2 {
+		t.Errorf("expected 2 cached managers, got %d", pool.Len())
+	}
+
+	now = now.Add(time.Minute)
+	refreshed, err := pool.Get("staging", staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == first {
+		t.Error("expected the expired ResourceManager to be recreated")
+	}
+	if created[staging.Host] != 2 {
+		t.Errorf("expected 2 managers created for staging, got %d", created[staging.Host])
+	}
+
+	pool.Evict("production")
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 cached manager, got %d", pool.Len())
+	}
+
+	if _, err := pool.Get("invalid", &rest.Config{}); err == nil {
+		t.Error("expected error for invalid config")
+	}
+	if pool.Len() != 1 {
+		t.Errorf("expected failed managers not to be cached, got %d", pool.Len())
+	}
+}
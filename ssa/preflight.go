@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// preflightVerbs are the verbs required to apply the objects with server-side apply.
+var preflightVerbs = []string{"create", "patch"}
+
+// PreflightResult holds the readiness of a kind in a namespace.
+type PreflightResult struct {
+	// GroupVersionKind is the API group, version and kind of the objects.
+	GroupVersionKind schema.GroupVersionKind `json:"groupVersionKind"`
+
+	// Namespace is the namespace of the objects, empty for cluster scoped kinds.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Denied holds the verbs which the manager identity is not allowed to perform.
+	Denied []string `json:"denied,omitempty"`
+
+	// Error is the discovery or access review error, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Ready returns true if the kind is served and the manager identity is allowed to apply it.
+func (r PreflightResult) Ready() bool {
+	return r.Error == "" && len(r.Denied) == 0
+}
+
+// String returns the result in the format '<Kind>/<Namespace>: <reason>'.
+func (r PreflightResult) String() string {
+	subject := r.GroupVersionKind.Kind
+	if r.Namespace != "" {
+		subject += "/" + r.Namespace
+	}
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("%s: %s", subject, r.Error)
+	case len(r.Denied) > 0:
+		return fmt.Sprintf("%s: %s forbidden", subject, strings.Join(r.Denied, ", "))
+	default:
+		return fmt.Sprintf("%s: ready", subject)
+	}
+}
+
+// PreflightReport is the result of a preflight check.
+type PreflightReport struct {
+	// Reachable is true if the API server answered the access reviews.
+	Reachable bool `json:"reachable"`
+
+	// Error is the API server connection error, if any.
+	Error string `json:"error,omitempty"`
+
+	// Results holds the readiness of each kind and namespace of the checked objects.
+	Results []PreflightResult `json:"results,omitempty"`
+}
+
+// Ready returns true if the API server is reachable and all the kinds are ready.
+func (r *PreflightReport) Ready() bool {
+	if !r.Reachable {
+		return false
+	}
+	for _, result := range r.Results {
+		if !result.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an error listing the reasons for which the report is not ready, or nil.
+func (r *PreflightReport) Err() error {
+	if !r.Reachable {
+		return fmt.Errorf("preflight failed, API server unreachable: %s", r.Error)
+	}
+	var reasons []string
+	for _, result := range r.Results {
+		if !result.Ready() {
+			reasons = append(reasons, result.String())
+		}
+	}
+	if len(reasons) > 0 {
+		return fmt.Errorf("preflight failed, errors: %s", strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Preflight verifies, without writing to the cluster, that the API server is reachable, that
+// the kinds of the given objects are served, and that the manager identity is allowed to create
+// and patch the objects of each kind in their namespaces, using SelfSubjectAccessReviews.
+func (m *ResourceManager) Preflight(ctx context.Context, objects []*unstructured.Unstructured) *PreflightReport {
+	report := &PreflightReport{}
+	if _, err := m.accessReview(ctx, authorizationv1.SelfSubjectAccessReviewSpec{
+		NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: "/readyz", Verb: "get"},
+	}); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Reachable = true
+
+	type target struct {
+		gvk       schema.GroupVersionKind
+		namespace string
+	}
+	seen := make(map[target]bool)
+	mappings := make(map[schema.GroupVersionKind]*meta.RESTMapping)
+	mappingErrors := make(map[schema.GroupVersionKind]error)
+
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+		if _, ok := mappings[gvk]; !ok && mappingErrors[gvk] == nil {
+			mapping, err := m.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				mappingErrors[gvk] = err
+			} else {
+				mappings[gvk] = mapping
+			}
+		}
+
+		t := target{gvk: gvk, namespace: object.GetNamespace()}
+		if mapping, ok := mappings[gvk]; ok && mapping.Scope.Name() == meta.RESTScopeNameRoot {
+			t.namespace = ""
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		result := PreflightResult{GroupVersionKind: gvk, Namespace: t.namespace}
+		if err := mappingErrors[gvk]; err != nil {
+			if IsNoMatchError(err) {
+				result.Error = "kind not served by the API server"
+			} else {
+				result.Error = fmt.Sprintf("discovery failed: %s", err)
+			}
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		for _, verb := range preflightVerbs {
+			allowed, err := m.accessReview(ctx, authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: t.namespace,
+					Verb:      verb,
+					Group:     gvk.Group,
+					Version:   gvk.Version,
+					Resource:  mappings[gvk].Resource.Resource,
+				},
+			})
+			if err != nil {
+				result.Error = fmt.Sprintf("access review failed: %s", err)
+				break
+			}
+			if !allowed {
+				result.Denied = append(result.Denied, verb)
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
+
+// accessReview returns true if the manager identity is allowed to perform the given request.
+func (m *ResourceManager) accessReview(ctx context.Context, spec authorizationv1.SelfSubjectAccessReviewSpec) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{Spec: spec}
+	if err := m.client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
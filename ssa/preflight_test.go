@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// accessReviewClient answers the SelfSubjectAccessReviews with the allow function.
+type accessReviewClient struct {
+	client.Client
+	allow func(attrs *authorizationv1.ResourceAttributes) bool
+	err   error
+}
+
+func (c *accessReviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	if c.err != nil {
+		return c.err
+	}
+	review.Status.Allowed = review.Spec.ResourceAttributes == nil || c.allow(review.Spec.ResourceAttributes)
+	return nil
+}
+
+func TestPreflight(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion, rbacv1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+
+	newObject := func(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetNamespace(namespace)
+		u.SetName(name)
+		return u
+	}
+	objects := []*unstructured.Unstructured{
+		newObject("v1", "ConfigMap", "default", "config1"),
+		newObject("v1", "ConfigMap", "default", "config2"),
+		newObject("v1", "ConfigMap", "apps", "config"),
+		newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "role"),
+		newObject("example.com/v1", "Widget", "default", "widget"),
+	}
+
+	t.Run("reports the denied verbs and unknown kinds", func(t *testing.T) {
+		kubeClient := &accessReviewClient{
+			Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build(),
+			allow: func(attrs *authorizationv1.ResourceAttributes) bool {
+				return attrs.Namespace != "apps" || attrs.Verb != "patch"
+			},
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+		report := rm.Preflight(context.Background(), objects)
+		want := &PreflightReport{
+			Reachable: true,
+			Results: []PreflightResult{
+				{GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ConfigMap"), Namespace: "default"},
+				{GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ConfigMap"), Namespace: "apps", Denied: []string{"patch"}},
+				{GroupVersionKind: rbacv1.SchemeGroupVersion.WithKind("ClusterRole")},
+				{
+					GroupVersionKind: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+					Namespace:        "default",
+					Error:            "kind not served by the API server",
+				},
+			},
+		}
+		if diff := cmp.Diff(want, report); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+		if report.Ready() {
+			t.Error("expected report not to be ready")
+		}
+		if err := report.Err(); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("reports the unreachable API server", func(t *testing.T) {
+		kubeClient := &accessReviewClient{
+			Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build(),
+			err:    errors.New("connection refused"),
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+		report := rm.Preflight(context.Background(), objects)
+		if report.Reachable || report.Ready() {
+			t.Error("expected report not to be reachable")
+		}
+		if len(report.Results) != 0 {
+			t.Errorf("expected no results, got %v", report.Results)
+		}
+	})
+
+	t.Run("is ready when all verbs are allowed", func(t *testing.T) {
+		kubeClient := &accessReviewClient{
+			Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build(),
+			allow:  func(*authorizationv1.ResourceAttributes) bool { return true },
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+
+		report := rm.Preflight(context.Background(), objects[:4])
+		if err := report.Err(); err != nil {
+			t.Error(err)
+		}
+	})
+}
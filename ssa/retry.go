@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// DefaultRetryBackoff is the backoff of the apply and delete requests which fail
+// due to conflicts, API throttling or etcd leader changes.
+var DefaultRetryBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Cap:      5 * time.Second,
+}
+
+// SetRetryBackoff sets the backoff of the apply and delete requests which fail due to
+// transient API server errors, see DefaultRetryBackoff.
+// A backoff with less than two steps disables the retries.
+func (m *ResourceManager) SetRetryBackoff(backoff wait.Backoff) {
+	m.retryBackoff = backoff
+}
+
+// withRetry runs fn until it succeeds, it returns a non-retriable error,
+// the backoff steps are exhausted or the context is cancelled.
+func (m *ResourceManager) withRetry(ctx context.Context, fn func() error) error {
+	backoff := m.retryBackoff
+	if backoff.Steps < 1 {
+		backoff.Steps = 1
+	}
+
+	attempt := 0
+	return retry.OnError(backoff, func(err error) bool {
+		if ctx.Err() != nil || !isRetriableError(err) {
+			return false
+		}
+		attempt++
+		m.logger().Info("retrying request", "attempt", attempt, "error", err.Error())
+		return true
+	}, fn)
+}
+
+// isRetriableError returns true if the given error is caused by a transient
+// API server condition, e.g. a conflict, API throttling or an etcd leader change.
+// The server-side apply field manager conflicts are not retriable.
+func isRetriableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apierrors.IsConflict(err) {
+		_, fieldConflict := apierrors.StatusCause(err, metav1.CauseTypeFieldManagerConflict)
+		return !fieldConflict
+	}
+
+	if apierrors.IsTooManyRequests(err) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "etcdserver: leader changed")
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIsRetriableError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	fieldConflict := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   409,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{{Type: metav1.CauseTypeFieldManagerConflict}},
+		},
+	}}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "conflict", err: apierrors.NewConflict(gr, "test", errors.New("the object has been modified")), want: true},
+		{name: "field manager conflict", err: fieldConflict, want: false},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("throttled", 1), want: true},
+		{name: "etcd leader changed", err: apierrors.NewInternalError(errors.New("etcdserver: leader changed")), want: true},
+		{name: "wrapped", err: fmt.Errorf("apply failed, error: %w", apierrors.NewTooManyRequests("throttled", 1)), want: true},
+		{name: "not found", err: apierrors.NewNotFound(gr, "test"), want: false},
+		{name: "invalid", err: apierrors.NewBadRequest("invalid"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetriableError(tt.err); got != tt.want {
+				t.Errorf("isRetriableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// throttledClient fails the first delete requests with TooManyRequests.
+type throttledClient struct {
+	client.Client
+	failures int
+	calls    int
+}
+
+func (c *throttledClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.calls++
+	if c.calls <= c.failures {
+		return apierrors.NewTooManyRequests("throttled", 1)
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestDelete_Retry(t *testing.T) {
+	newObject := func() *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName("config")
+		return u
+	}
+
+	backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 2}
+
+	t.Run("retries throttled requests", func(t *testing.T) {
+		kubeClient := &throttledClient{
+			Client: fake.NewClientBuilder().WithObjects(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}},
+			).Build(),
+			failures: 2,
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetRetryBackoff(backoff)
+
+		entry, err := rm.Delete(context.Background(), newObject(), DefaultDeleteOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Action != string(DeletedAction) {
+			t.Errorf("expected action %s, got %s", DeletedAction, entry.Action)
+		}
+		if kubeClient.calls != 3 {
+			t.Errorf("expected 3 delete requests, got %d", kubeClient.calls)
+		}
+	})
+
+	t.Run("fails when the backoff is exhausted", func(t *testing.T) {
+		kubeClient := &throttledClient{
+			Client: fake.NewClientBuilder().WithObjects(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}},
+			).Build(),
+			failures: 3,
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetRetryBackoff(backoff)
+
+		_, err := rm.Delete(context.Background(), newObject(), DefaultDeleteOptions())
+		if !apierrors.IsTooManyRequests(err) {
+			t.Fatalf("expected TooManyRequests error, got %v", err)
+		}
+		if kubeClient.calls != 3 {
+			t.Errorf("expected 3 delete requests, got %d", kubeClient.calls)
+		}
+	})
+
+	t.Run("disables retries", func(t *testing.T) {
+		kubeClient := &throttledClient{
+			Client: fake.NewClientBuilder().WithObjects(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}},
+			).Build(),
+			failures: 1,
+		}
+		rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+		rm.SetRetryBackoff(wait.Backoff{})
+
+		if _, err := rm.Delete(context.Background(), newObject(), DefaultDeleteOptions()); err == nil {
+			t.Fatal("expected error")
+		}
+		if kubeClient.calls != 1 {
+			t.Errorf("expected 1 delete request, got %d", kubeClient.calls)
+		}
+	})
+}
@@ -25,9 +25,68 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
+// KindOrder holds the kinds reconciled before and after all the other kinds.
 type KindOrder struct {
+	// First holds the kinds reconciled first, in order.
 	First []string
-	Last  []string
+
+	// Last holds the kinds reconciled last, in order.
+	Last []string
+}
+
+// LessFunc reports whether the object i should be reconciled before the object j.
+type LessFunc func(i, j *unstructured.Unstructured) bool
+
+// Less reports whether the kind i is reconciled before the kind j. The kinds
+// not listed in the order are reconciled between the First and Last kinds,
+// sorted by group and kind.
+func (o KindOrder) Less(i, j schema.GroupKind) bool {
+	indexI := o.index(i.Kind)
+	indexJ := o.index(j.Kind)
+	if indexI != indexJ {
+		return indexI < indexJ
+	}
+	if i.Group != j.Group {
+		return i.Group < j.Group
+	}
+	return i.Kind < j.Kind
+}
+
+// LessFunc returns a LessFunc which sorts the objects by kind according to the order,
+// and by namespace and name for the objects of the same kind.
+func (o KindOrder) LessFunc() LessFunc {
+	return func(i, j *unstructured.Unstructured) bool {
+		first := object.UnstructuredToObjMetadata(i)
+		second := object.UnstructuredToObjMetadata(j)
+		if !Equals(first.GroupKind, second.GroupKind) {
+			return o.Less(first.GroupKind, second.GroupKind)
+		}
+		return lessNamespacedName(first, second)
+	}
+}
+
+// index returns the position of the kind relative to the unlisted kinds,
+// negative for the First kinds and positive for the Last kinds.
+func (o KindOrder) index(kind string) int {
+	for i, n := range o.First {
+		if n == kind {
+			return -len(o.First) + i
+		}
+	}
+	for i, n := range o.Last {
+		if n == kind {
+			return 1 + i
+		}
+	}
+	return 0
+}
+
+// SortObjects sorts the given objects in place using the less function.
+// The objects for which less reports no order keep their relative position.
+func SortObjects(objects []*unstructured.Unstructured, less LessFunc) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return less(objects[i], objects[j])
+	})
 }
 
 // ReconcileOrder holds the list of the Kubernetes native kinds that
@@ -90,6 +149,10 @@ func less(i, j object.ObjMetadata) bool {
 	if !Equals(i.GroupKind, j.GroupKind) {
 		return IsLessThan(i.GroupKind, j.GroupKind)
 	}
+	return lessNamespacedName(i, j)
+}
+
+func lessNamespacedName(i, j object.ObjMetadata) bool {
 	// In case of tie, compare the namespace and name combination so that the output
 	// order is consistent irrespective of input order
 	if i.Namespace != j.Namespace {
@@ -98,39 +161,11 @@ func less(i, j object.ObjMetadata) bool {
 	return i.Name < j.Name
 }
 
-func computeKind2index() map[string]int {
-	// An attempt to order things to help k8s, e.g.
-	// a Service should come before things that refer to it.
-	// Namespace should be first.
-	// In some cases order just specified to provide determinism.
-
-	kind2indexResult := make(map[string]int, len(ReconcileOrder.First)+len(ReconcileOrder.Last))
-	for i, n := range ReconcileOrder.First {
-		kind2indexResult[n] = -len(ReconcileOrder.First) + i
-	}
-	for i, n := range ReconcileOrder.Last {
-		kind2indexResult[n] = 1 + i
-	}
-	return kind2indexResult
-}
-
-// getIndexByKind returns the index of the kind respecting the order
-func getIndexByKind(kind string) int {
-	return computeKind2index()[kind]
-}
-
 func Equals(i, j schema.GroupKind) bool {
 	return i.Group == j.Group && i.Kind == j.Kind
 }
 
+// IsLessThan reports whether the kind i is reconciled before the kind j according to the ReconcileOrder.
 func IsLessThan(i, j schema.GroupKind) bool {
-	indexI := getIndexByKind(i.Kind)
-	indexJ := getIndexByKind(j.Kind)
-	if indexI != indexJ {
-		return indexI < indexJ
-	}
-	if i.Group != j.Group {
-		return i.Group < j.Group
-	}
-	return i.Kind < j.Kind
+	return ReconcileOrder.Less(i, j)
 }
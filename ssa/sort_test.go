@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSortObjects(t *testing.T) {
+	objects, err := ReadObjects(strings.NewReader(`
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: webhook
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+---
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: high
+value: 1000
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  namespace: default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subjects := func(objects []*unstructured.Unstructured) []string {
+		var result []string
+		for _, object := range objects {
+			result = append(result, object.GetKind()+"/"+object.GetName())
+		}
+		return result
+	}
+
+	tests := []struct {
+		name string
+		less LessFunc
+		want []string
+	}{
+		{
+			name: "default order",
+			less: ReconcileOrder.LessFunc(),
+			want: []string{
+				"Namespace/default",
+				"PriorityClass/high",
+				"ConfigMap/a",
+				"ConfigMap/b",
+				"Deployment/app",
+				"ValidatingWebhookConfiguration/webhook",
+			},
+		},
+		{
+			name: "custom order",
+			less: KindOrder{
+				First: []string{"PriorityClass", "ValidatingWebhookConfiguration", "Namespace"},
+				Last:  []string{"ConfigMap"},
+			}.LessFunc(),
+			want: []string{
+				"PriorityClass/high",
+				"ValidatingWebhookConfiguration/webhook",
+				"Namespace/default",
+				"Deployment/app",
+				"ConfigMap/a",
+				"ConfigMap/b",
+			},
+		},
+		{
+			name: "custom comparator",
+			less: func(i, j *unstructured.Unstructured) bool {
+				return i.GetName() < j.GetName()
+			},
+			want: []string{
+				"ConfigMap/a",
+				"Deployment/app",
+				"ConfigMap/b",
+				"Namespace/default",
+				"PriorityClass/high",
+				"ValidatingWebhookConfiguration/webhook",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := make([]*unstructured.Unstructured, len(objects))
+			copy(sorted, objects)
+			SortObjects(sorted, tt.less)
+
+			if diff := cmp.Diff(tt.want, subjects(sorted)); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResourceManager_SortObjects(t *testing.T) {
+	objects, err := ReadObjects(strings.NewReader(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &ResourceManager{}
+	m.sortObjects(objects, false)
+	if diff := cmp.Diff("Namespace", objects[0].GetKind()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	m.sortObjects(objects, true)
+	if diff := cmp.Diff("ConfigMap", objects[0].GetKind()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+
+	m.SetSortFunc(KindOrder{First: []string{"ConfigMap"}}.LessFunc())
+	m.sortObjects(objects, false)
+	if diff := cmp.Diff("ConfigMap", objects[0].GetKind()); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
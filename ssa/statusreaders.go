@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/statusreaders"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// SetCustomStatusReaders adds the given readers to the status readers of the ResourceManager,
+// which compute the status of the resources before falling back to the kstatus readers.
+// This allows health checking custom resources which don't follow the kstatus conventions.
+// When custom readers are set, the status poller is built for each wait from the ResourceManager
+// client and the options set with SetStatusPollerOptions, instead of the poller given to NewResourceManager.
+func (m *ResourceManager) SetCustomStatusReaders(readers ...engine.StatusReader) {
+	m.statusReaders = append(m.statusReaders, readers...)
+}
+
+// SetStatusPollerOptions sets the options of the status poller built when custom status readers
+// are set, e.g. a cluster reader factory. The options readers take precedence over the custom readers.
+func (m *ResourceManager) SetStatusPollerOptions(opts polling.Options) {
+	m.pollerOptions = opts
+}
+
+// statusPoller returns the poller of the ResourceManager, or a poller with the custom status readers.
+func (m *ResourceManager) statusPoller() *polling.StatusPoller {
+	if len(m.statusReaders) == 0 {
+		return m.poller
+	}
+	opts := m.pollerOptions
+	opts.CustomStatusReaders = append(append([]engine.StatusReader{}, opts.CustomStatusReaders...), m.statusReaders...)
+	return polling.NewStatusPoller(m.client, m.client.RESTMapper(), opts)
+}
+
+// NewConditionStatusReader returns a status reader for the given kinds which computes
+// the status of the resources from the condition of the given type, e.g. 'Ready'.
+// The resources are Current when the condition is True and the observed generation,
+// if any, is up-to-date, and InProgress otherwise.
+func NewConditionStatusReader(mapper meta.RESTMapper, conditionType string, groupKinds ...schema.GroupKind) engine.StatusReader {
+	return NewGroupKindStatusReader(mapper, conditionStatus(conditionType), groupKinds...)
+}
+
+// NewGroupKindStatusReader returns a status reader which computes the status of the
+// resources of the given kinds with the given function.
+func NewGroupKindStatusReader(mapper meta.RESTMapper, statusFunc statusreaders.StatusFunc, groupKinds ...schema.GroupKind) engine.StatusReader {
+	return &groupKindStatusReader{
+		reader:     statusreaders.NewGenericStatusReader(mapper, statusFunc),
+		groupKinds: groupKinds,
+	}
+}
+
+// groupKindStatusReader restricts a status reader to the given kinds.
+type groupKindStatusReader struct {
+	reader     engine.StatusReader
+	groupKinds []schema.GroupKind
+}
+
+// Supports returns true if the given kind is one of the reader kinds.
+func (r *groupKindStatusReader) Supports(gk schema.GroupKind) bool {
+	for _, groupKind := range r.groupKinds {
+		if Equals(groupKind, gk) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadStatus fetches the resource and computes its status.
+func (r *groupKindStatusReader) ReadStatus(ctx context.Context, reader engine.ClusterReader, id object.ObjMetadata) (*event.ResourceStatus, error) {
+	return r.reader.ReadStatus(ctx, reader, id)
+}
+
+// ReadStatusForObject computes the status of the given resource.
+func (r *groupKindStatusReader) ReadStatusForObject(ctx context.Context, reader engine.ClusterReader, resource *unstructured.Unstructured) (*event.ResourceStatus, error) {
+	return r.reader.ReadStatusForObject(ctx, reader, resource)
+}
+
+// conditionStatus returns a function which computes the status of a resource
+// from the condition of the given type.
+func conditionStatus(conditionType string) statusreaders.StatusFunc {
+	return func(u *unstructured.Unstructured) (*status.Result, error) {
+		obj, err := status.GetObjectWithConditions(u.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		observedGeneration, found, err := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+		if err != nil {
+			return nil, err
+		}
+		if found && observedGeneration < u.GetGeneration() {
+			return &status.Result{
+				Status:  status.InProgressStatus,
+				Message: fmt.Sprintf("%s generation is %d, but latest observed generation is %d", u.GetKind(), u.GetGeneration(), observedGeneration),
+			}, nil
+		}
+
+		for _, c := range obj.Status.Conditions {
+			if c.Type != conditionType {
+				continue
+			}
+			result := &status.Result{
+				Status:  status.InProgressStatus,
+				Message: c.Message,
+			}
+			if c.Status == corev1.ConditionTrue {
+				result.Status = status.CurrentStatus
+			}
+			return result, nil
+		}
+
+		return &status.Result{
+			Status:  status.InProgressStatus,
+			Message: fmt.Sprintf("%s condition not found", conditionType),
+		}, nil
+	}
+}
+
+// HealthCheckExpressions holds the CEL expressions evaluated against a resource to compute
+// its status, e.g. "status.phase == 'Ready'". The expressions can refer to the resource
+// top level fields: apiVersion, kind, metadata, spec and status.
+type HealthCheckExpressions struct {
+	// Current is the expression which evaluates to true when the resource is healthy.
+	Current string
+
+	// Failed is the optional expression which evaluates to true when the resource
+	// reconciliation failed.
+	Failed string
+}
+
+// celObjectFields holds the resource fields declared as CEL variables.
+var celObjectFields = []string{"apiVersion", "kind", "metadata", "spec", "status"}
+
+// NewCELStatusReader returns a status reader for the given kind which computes the status
+// of the resources by evaluating the given CEL expressions. The resources are Failed when
+// the Failed expression is true, Current when the Current expression is true, and InProgress
+// otherwise, including when the expressions refer to fields that are not set yet.
+func NewCELStatusReader(mapper meta.RESTMapper, gk schema.GroupKind, exprs HealthCheckExpressions) (engine.StatusReader, error) {
+	if exprs.Current == "" {
+		return nil, fmt.Errorf("%s health check: the Current expression is required", gk.String())
+	}
+
+	var opts []cel.EnvOption
+	for _, field := range celObjectFields {
+		opts = append(opts, cel.Variable(field, cel.DynType))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := compileHealthCheck(env, exprs.Current)
+	if err != nil {
+		return nil, fmt.Errorf("%s health check: invalid Current expression, error: %w", gk.String(), err)
+	}
+
+	var failed cel.Program
+	if exprs.Failed != "" {
+		failed, err = compileHealthCheck(env, exprs.Failed)
+		if err != nil {
+			return nil, fmt.Errorf("%s health check: invalid Failed expression, error: %w", gk.String(), err)
+		}
+	}
+
+	return NewGroupKindStatusReader(mapper, celStatus(current, failed), gk), nil
+}
+
+// compileHealthCheck compiles the given CEL expression, which must evaluate to a bool.
+func compileHealthCheck(env *cel.Env, expr string) (cel.Program, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression '%s' must evaluate to a bool, got %s", expr, ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// celStatus returns a function which computes the status of a resource by evaluating the
+// given programs, the failed program is optional.
+func celStatus(current, failed cel.Program) statusreaders.StatusFunc {
+	return func(u *unstructured.Unstructured) (*status.Result, error) {
+		vars := make(map[string]interface{}, len(celObjectFields))
+		for _, field := range celObjectFields {
+			if v, ok := u.Object[field]; ok {
+				vars[field] = v
+			} else {
+				vars[field] = map[string]interface{}{}
+			}
+		}
+
+		if failed != nil {
+			if ok, _ := evalHealthCheck(failed, vars); ok {
+				return &status.Result{
+					Status:  status.FailedStatus,
+					Message: "health check failed",
+				}, nil
+			}
+		}
+
+		ok, err := evalHealthCheck(current, vars)
+		if err != nil {
+			return &status.Result{
+				Status:  status.InProgressStatus,
+				Message: fmt.Sprintf("health check can't be evaluated: %s", err),
+			}, nil
+		}
+		if !ok {
+			return &status.Result{
+				Status:  status.InProgressStatus,
+				Message: "health check in progress",
+			}, nil
+		}
+		return &status.Result{
+			Status:  status.CurrentStatus,
+			Message: "health check passed",
+		}, nil
+	}
+}
+
+// evalHealthCheck evaluates the given program with the given variables.
+func evalHealthCheck(prg cel.Program, vars map[string]interface{}) (bool, error) {
+	val, _, err := prg.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	ok, isBool := val.Value().(bool)
+	if !isBool {
+		return false, fmt.Errorf("expression evaluated to %v, not a bool", val.Value())
+	}
+	return ok, nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConditionStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   map[string]interface{}
+		expected status.Status
+	}{
+		{
+			name: "ready",
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True", "message": "certificate is up to date"},
+				},
+			},
+			expected: status.CurrentStatus,
+		},
+		{
+			name: "not ready",
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "message": "issuing certificate"},
+				},
+			},
+			expected: status.InProgressStatus,
+		},
+		{
+			name: "stale observed generation",
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+				},
+			},
+			expected: status.InProgressStatus,
+		},
+		{
+			name: "missing condition",
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Issuing", "status": "True"},
+				},
+			},
+			expected: status.InProgressStatus,
+		},
+		{
+			name:     "missing status",
+			expected: status.InProgressStatus,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"})
+			u.SetName("test")
+			u.SetGeneration(2)
+			if tc.status != nil {
+				u.Object["status"] = tc.status
+			}
+
+			result, err := conditionStatus("Ready")(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != tc.expected {
+				t.Errorf("expected status %s, got %s: %s", tc.expected, result.Status, result.Message)
+			}
+		})
+	}
+}
+
+func TestGroupKindStatusReader_Supports(t *testing.T) {
+	reader := NewConditionStatusReader(nil, "Ready",
+		schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"})
+
+	if !reader.Supports(schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"}) {
+		t.Error("expected Certificate to be supported")
+	}
+	if reader.Supports(schema.GroupKind{Group: "cert-manager.io", Kind: "Issuer"}) {
+		t.Error("expected Issuer to be unsupported")
+	}
+}
+
+func TestSetCustomStatusReaders(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("StatefulSet"), meta.RESTScopeNamespace)
+
+	// the workloads without status are in progress for kstatus
+	replicas := int32(1)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+	kubeClient := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, Selector: selector},
+		},
+	).Build()
+
+	current := func(u *unstructured.Unstructured) (*status.Result, error) {
+		return &status.Result{Status: status.CurrentStatus, Message: "always ready"}, nil
+	}
+	rm := NewResourceManager(kubeClient, nil, Owner{Field: "manager", Group: "manager.io"})
+	rm.SetCustomStatusReaders(NewGroupKindStatusReader(mapper, current, schema.GroupKind{Group: "apps", Kind: "Deployment"}))
+	rm.SetCustomStatusReaders(NewGroupKindStatusReader(mapper, current, schema.GroupKind{Group: "apps", Kind: "StatefulSet"}))
+
+	set := object.ObjMetadataSet{
+		{GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"}, Namespace: "default", Name: "test"},
+		{GroupKind: schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, Namespace: "default", Name: "test"},
+	}
+	if err := rm.WaitForSet(set, WaitOptions{Interval: 100 * time.Millisecond, Timeout: 2 * time.Second}); err != nil {
+		t.Errorf("expected the readers of both registrations to be used, got %v", err)
+	}
+}
+
+func TestCELStatusReader(t *testing.T) {
+	gk := schema.GroupKind{Group: "testing.fluxcd.io", Kind: "ClusterTest"}
+	exprs := HealthCheckExpressions{
+		Current: "status.phase == 'Ready'",
+		Failed:  "status.phase == 'Failed'",
+	}
+
+	testCases := []struct {
+		name     string
+		status   map[string]interface{}
+		expected status.Status
+	}{
+		{
+			name:     "current",
+			status:   map[string]interface{}{"phase": "Ready"},
+			expected: status.CurrentStatus,
+		},
+		{
+			name:     "failed",
+			status:   map[string]interface{}{"phase": "Failed"},
+			expected: status.FailedStatus,
+		},
+		{
+			name:     "in progress",
+			status:   map[string]interface{}{"phase": "Pending"},
+			expected: status.InProgressStatus,
+		},
+		{
+			name:     "missing field",
+			expected: status.InProgressStatus,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := NewCELStatusReader(nil, gk, exprs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reader.Supports(gk) {
+				t.Fatalf("expected %s to be supported", gk)
+			}
+
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gk.WithVersion("v1"))
+			u.SetName("test")
+			if tc.status != nil {
+				u.Object["status"] = tc.status
+			}
+
+			rs, err := reader.ReadStatusForObject(context.Background(), nil, u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs.Status != tc.expected {
+				t.Errorf("expected status %s, got %s: %s", tc.expected, rs.Status, rs.Message)
+			}
+		})
+	}
+}
+
+func TestCELStatusReader_InvalidExpressions(t *testing.T) {
+	gk := schema.GroupKind{Group: "testing.fluxcd.io", Kind: "ClusterTest"}
+
+	for name, exprs := range map[string]HealthCheckExpressions{
+		"missing current": {Failed: "status.phase == 'Failed'"},
+		"syntax error":    {Current: "status.phase == "},
+		"not a bool":      {Current: "'Ready'"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewCELStatusReader(nil, gk, exprs); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// ResourceStatus is the last status of a resource observed while waiting.
+type ResourceStatus struct {
+	// Status is the kstatus of the resource, e.g. 'Current' or 'Failed'.
+	Status status.Status `json:"status"`
+
+	// Message is the status message of the resource.
+	Message string `json:"message,omitempty"`
+
+	// Duration is the time from the start of the wait to the last status transition.
+	Duration time.Duration `json:"duration"`
+}
+
+// StatusRecorder records the status transitions of the resources reported by the wait
+// functions, its OnStatusChange method can be set as the WaitOptions OnStatusChange.
+type StatusRecorder struct {
+	start    time.Time
+	mu       sync.Mutex
+	statuses map[object.ObjMetadata]ResourceStatus
+}
+
+// NewStatusRecorder returns a StatusRecorder which measures the durations from now.
+func NewStatusRecorder() *StatusRecorder {
+	return &StatusRecorder{
+		start:    time.Now(),
+		statuses: make(map[object.ObjMetadata]ResourceStatus),
+	}
+}
+
+// OnStatusChange records the new status of the given resource.
+func (r *StatusRecorder) OnStatusChange(id object.ObjMetadata, _, newStatus status.Status, message string) {
+	r.record(id, ResourceStatus{Status: newStatus, Message: message, Duration: time.Since(r.start)})
+}
+
+func (r *StatusRecorder) record(id object.ObjMetadata, rs ResourceStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[id] = rs
+}
+
+// Statuses returns a copy of the last recorded status of each resource.
+func (r *StatusRecorder) Statuses() map[object.ObjMetadata]ResourceStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make(map[object.ObjMetadata]ResourceStatus, len(r.statuses))
+	for id, rs := range r.statuses {
+		res[id] = rs
+	}
+	return res
+}
+
+// ResourceSummary holds the outcome of the reconciliation of a resource.
+type ResourceSummary struct {
+	// Subject is the resource ID in the format 'kind/namespace/name'.
+	Subject string `json:"subject"`
+
+	// Action is the action performed on the resource, empty if the resource is not in the ChangeSet.
+	Action Action `json:"action,omitempty"`
+
+	// Status is the last status of the resource, empty if no status was recorded.
+	Status status.Status `json:"status,omitempty"`
+
+	// Message is the last status message of the resource.
+	Message string `json:"message,omitempty"`
+
+	// Duration is the time it took for the resource to reach its last status.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Summary is the aggregated result of the reconciliation of an object set,
+// suitable for a custom resource status or a CLI output.
+type Summary struct {
+	// Total is the number of resources in the ChangeSet.
+	Total int `json:"total"`
+
+	// Actions holds the number of resources per action.
+	Actions map[Action]int `json:"actions,omitempty"`
+
+	// Statuses holds the number of resources per status.
+	Statuses map[status.Status]int `json:"statuses,omitempty"`
+
+	// Slowest holds the resources which took the longest to become ready, slowest first.
+	Slowest []ResourceSummary `json:"slowest,omitempty"`
+
+	// Failed holds the resources which failed or are not ready, and the ones
+	// with an unknown action, sorted by subject.
+	Failed []ResourceSummary `json:"failed,omitempty"`
+}
+
+// Summarize aggregates the given ChangeSet and recorded statuses into a Summary,
+// with at most maxSlowest entries in the slowest resources list.
+// The statuses of the resources missing from the ChangeSet are counted as well.
+func Summarize(changeSet *ChangeSet, statuses map[object.ObjMetadata]ResourceStatus, maxSlowest int) *Summary {
+	summary := &Summary{
+		Actions:  make(map[Action]int),
+		Statuses: make(map[status.Status]int),
+	}
+
+	resources := make(map[object.ObjMetadata]*ResourceSummary)
+	if changeSet != nil {
+		summary.Total = len(changeSet.Entries)
+		for _, entry := range changeSet.Entries {
+			summary.Actions[Action(entry.Action)]++
+			resources[entry.ObjMetadata] = &ResourceSummary{
+				Subject: entry.Subject,
+				Action:  Action(entry.Action),
+			}
+		}
+	}
+
+	for id, rs := range statuses {
+		summary.Statuses[rs.Status]++
+		res, ok := resources[id]
+		if !ok {
+			res = &ResourceSummary{Subject: FmtObjMetadata(id)}
+			resources[id] = res
+		}
+		res.Status = rs.Status
+		res.Message = rs.Message
+		res.Duration = rs.Duration
+	}
+
+	for _, res := range resources {
+		switch {
+		case res.Action == UnknownAction:
+			summary.Failed = append(summary.Failed, *res)
+		case res.Status == status.CurrentStatus:
+			summary.Slowest = append(summary.Slowest, *res)
+		case res.Status != "" && res.Status != status.NotFoundStatus:
+			summary.Failed = append(summary.Failed, *res)
+		}
+	}
+
+	sort.Slice(summary.Failed, func(i, j int) bool {
+		return summary.Failed[i].Subject < summary.Failed[j].Subject
+	})
+	sort.Slice(summary.Slowest, func(i, j int) bool {
+		if summary.Slowest[i].Duration != summary.Slowest[j].Duration {
+			return summary.Slowest[i].Duration > summary.Slowest[j].Duration
+		}
+		return summary.Slowest[i].Subject < summary.Slowest[j].Subject
+	})
+	if len(summary.Slowest) > maxSlowest {
+		summary.Slowest = summary.Slowest[:maxSlowest]
+	}
+	if len(summary.Slowest) == 0 {
+		summary.Slowest = nil
+	}
+
+	return summary
+}
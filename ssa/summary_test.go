@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestSummarize(t *testing.T) {
+	newID := func(kind, name string) object.ObjMetadata {
+		return object.ObjMetadata{GroupKind: schema.GroupKind{Group: "apps", Kind: kind}, Namespace: "default", Name: name}
+	}
+	newEntry := func(id object.ObjMetadata, action Action) ChangeSetEntry {
+		return ChangeSetEntry{ObjMetadata: id, GroupVersion: "v1", Subject: FmtObjMetadata(id), Action: string(action)}
+	}
+
+	frontend := newID("Deployment", "frontend")
+	backend := newID("Deployment", "backend")
+	db := newID("StatefulSet", "db")
+	cache := newID("StatefulSet", "cache")
+	worker := newID("Deployment", "worker")
+
+	changeSet := NewChangeSet()
+	changeSet.Add(newEntry(frontend, CreatedAction))
+	changeSet.Add(newEntry(backend, ConfiguredAction))
+	changeSet.Add(newEntry(db, UnchangedAction))
+	changeSet.Add(newEntry(cache, ConfiguredAction))
+	changeSet.Add(newEntry(worker, UnknownAction))
+
+	recorder := NewStatusRecorder()
+	recorder.record(frontend, ResourceStatus{Status: status.CurrentStatus, Message: "Deployment is available", Duration: 10 * time.Second})
+	recorder.record(backend, ResourceStatus{Status: status.CurrentStatus, Message: "Deployment is available", Duration: 30 * time.Second})
+	recorder.record(db, ResourceStatus{Status: status.CurrentStatus, Message: "Partition rollout complete", Duration: 20 * time.Second})
+	recorder.record(cache, ResourceStatus{Status: status.InProgressStatus, Message: "Ready: 1/3", Duration: time.Minute})
+
+	got := Summarize(changeSet, recorder.Statuses(), 2)
+	want := &Summary{
+		Total: 5,
+		Actions: map[Action]int{
+			CreatedAction:    1,
+			ConfiguredAction: 2,
+			UnchangedAction:  1,
+			UnknownAction:    1,
+		},
+		Statuses: map[status.Status]int{
+			status.CurrentStatus:    3,
+			status.InProgressStatus: 1,
+		},
+		Slowest: []ResourceSummary{
+			{Subject: "Deployment/default/backend", Action: ConfiguredAction, Status: status.CurrentStatus, Message: "Deployment is available", Duration: 30 * time.Second},
+			{Subject: "StatefulSet/default/db", Action: UnchangedAction, Status: status.CurrentStatus, Message: "Partition rollout complete", Duration: 20 * time.Second},
+		},
+		Failed: []ResourceSummary{
+			{Subject: "Deployment/default/worker", Action: UnknownAction},
+			{Subject: "StatefulSet/default/cache", Action: ConfiguredAction, Status: status.InProgressStatus, Message: "Ready: 1/3", Duration: time.Minute},
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
+
+func TestStatusRecorder(t *testing.T) {
+	id := object.ObjMetadata{GroupKind: schema.GroupKind{Kind: "ConfigMap"}, Namespace: "default", Name: "config"}
+
+	recorder := NewStatusRecorder()
+	opts := DefaultWaitOptions()
+	opts.OnStatusChange = recorder.OnStatusChange
+	opts.OnStatusChange(id, status.UnknownStatus, status.InProgressStatus, "creating")
+	opts.OnStatusChange(id, status.InProgressStatus, status.CurrentStatus, "ready")
+
+	statuses := recorder.Statuses()
+	if diff := cmp.Diff(status.CurrentStatus, statuses[id].Status); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("ready", statuses[id].Message); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
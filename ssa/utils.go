@@ -18,10 +18,15 @@ limitations under the License.
 package ssa
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/google/go-cmp/cmp"
 	appsv1 "k8s.io/api/apps/v1"
@@ -30,6 +35,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
@@ -112,6 +118,103 @@ func cmpMaskData(currentData, futureData map[string]interface{}) (map[string]int
 	return currentData, futureData
 }
 
+// maskPaths replaces the values of the fields matching the given JSON pointer patterns
+// with the default mask, and with the diff mask in the future object when the values differ.
+func maskPaths(current, future map[string]interface{}, patterns []string) {
+	for _, pattern := range patterns {
+		segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		paths := matchPaths(current, nil, segments)
+		paths = append(paths, matchPaths(future, nil, segments)...)
+		for _, path := range paths {
+			currentVal, inCurrent := getPath(current, path)
+			futureVal, inFuture := getPath(future, path)
+			if inCurrent {
+				setPath(current, path, defaultMask)
+			}
+			if inFuture {
+				if inCurrent && cmp.Diff(currentVal, futureVal) != "" {
+					setPath(future, path, diffMask)
+				} else {
+					setPath(future, path, defaultMask)
+				}
+			}
+		}
+	}
+}
+
+// matchPaths returns the paths of the values matching the given JSON pointer segments.
+func matchPaths(value interface{}, prefix []string, segments []string) [][]string {
+	if len(segments) == 0 {
+		return [][]string{prefix}
+	}
+
+	var keys []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case []interface{}:
+		for i := range v {
+			keys = append(keys, fmt.Sprint(i))
+		}
+	default:
+		return nil
+	}
+
+	segment := strings.ReplaceAll(strings.ReplaceAll(segments[0], "~1", "/"), "~0", "~")
+	var res [][]string
+	for _, k := range keys {
+		if segment != "*" && segment != k {
+			continue
+		}
+		child, _ := getPath(value, []string{k})
+		path := append(append([]string{}, prefix...), k)
+		res = append(res, matchPaths(child, path, segments[1:])...)
+	}
+	return res
+}
+
+// getPath returns the value at the given path.
+func getPath(value interface{}, path []string) (interface{}, bool) {
+	for _, k := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[k]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setPath replaces the existing value at the given path.
+func setPath(value interface{}, path []string, newValue interface{}) {
+	parent, ok := getPath(value, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	k := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[k] = newValue
+	case []interface{}:
+		if i, err := strconv.Atoi(k); err == nil && i >= 0 && i < len(v) {
+			v[i] = newValue
+		}
+	}
+}
+
 // maskSecret replaces the data key values with the given mask.
 func maskSecret(data map[string]interface{}, object *unstructured.Unstructured, mask string) (*unstructured.Unstructured, error) {
 
@@ -142,38 +245,100 @@ func ReadObject(r io.Reader) (*unstructured.Unstructured, error) {
 // ReadObjects decodes the YAML or JSON documents from the given reader into unstructured Kubernetes API objects.
 // The documents which do not subscribe to the Kubernetes Object interface, are silently dropped from the result.
 func ReadObjects(r io.Reader) ([]*unstructured.Unstructured, error) {
-	reader := yamlutil.NewYAMLOrJSONDecoder(r, 2048)
 	objects := make([]*unstructured.Unstructured, 0)
+	err := ReadObjectsFunc(r, func(obj *unstructured.Unstructured) error {
+		objects = append(objects, obj)
+		return nil
+	})
+	return objects, err
+}
+
+// ReadObjectsFunc decodes the YAML or JSON documents from the given reader one at a time, and calls fn for each
+// unstructured Kubernetes API object, so that large manifests can be processed without holding all the objects
+// in memory. The documents which do not subscribe to the Kubernetes Object interface, are silently dropped.
+// The decoding stops at the first error returned by fn, and the error is returned as is.
+//
+// The YAML and JSON documents can be mixed in the same stream, separated by '---'. A document can hold
+// a stream of JSON objects, a JSON array of objects, e.g. jsonnet output, or a List of objects,
+// e.g. 'kubectl get -o json' output, the arrays and lists are flattened.
+func ReadObjectsFunc(r io.Reader, fn func(obj *unstructured.Unstructured) error) error {
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
 
 	for {
-		obj := &unstructured.Unstructured{}
-		err := reader.Decode(obj)
+		doc, err := reader.Read()
 		if err != nil {
 			if err == io.EOF {
-				err = nil
-				break
+				return nil
 			}
-			return objects, err
+			return err
 		}
 
-		if obj.IsList() {
-			err = obj.EachListItem(func(item runtime.Object) error {
-				obj := item.(*unstructured.Unstructured)
-				objects = append(objects, obj)
-				return nil
-			})
+		doc = bytes.TrimSpace(doc)
+		if len(doc) == 0 {
+			continue
+		}
+
+		if doc[0] != '{' && doc[0] != '[' {
+			doc, err = yaml.YAMLToJSON(doc)
 			if err != nil {
-				return objects, err
+				return err
 			}
-			continue
 		}
 
-		if IsKubernetesObject(obj) && !IsKustomization(obj) {
-			objects = append(objects, obj)
+		decoder := json.NewDecoder(bytes.NewReader(doc))
+		for {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			if err := decodeJSONObjects(value, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeJSONObjects decodes the given JSON object or array of objects, and calls fn for each object.
+func decodeJSONObjects(data []byte, fn func(obj *unstructured.Unstructured) error) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return nil
+	case data[0] == '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := decodeJSONObjects(item, fn); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
-	return objects, nil
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	return yieldObjects(obj, fn)
+}
+
+// yieldObjects calls fn for the given object, or for each item of the given List.
+func yieldObjects(obj *unstructured.Unstructured, fn func(obj *unstructured.Unstructured) error) error {
+	if obj.IsList() {
+		return obj.EachListItem(func(item runtime.Object) error {
+			return yieldObjects(item.(*unstructured.Unstructured), fn)
+		})
+	}
+
+	if IsKubernetesObject(obj) && !IsKustomization(obj) {
+		return fn(obj)
+	}
+	return nil
 }
 
 // ObjectToYAML encodes the given Kubernetes API object to YAML.
@@ -235,6 +400,14 @@ func IsClusterDefinition(object *unstructured.Unstructured) bool {
 	return false
 }
 
+// IsNoMatchError checks if the given error, or any error it wraps,
+// is a RESTMapper no kind or no resource match error.
+func IsNoMatchError(err error) bool {
+	var kindErr *meta.NoKindMatchError
+	var resourceErr *meta.NoResourceMatchError
+	return goerrors.As(err, &kindErr) || goerrors.As(err, &resourceErr)
+}
+
 // IsKubernetesObject checks if the given object has the minimum required fields to be a Kubernetes object.
 func IsKubernetesObject(object *unstructured.Unstructured) bool {
 	if object.GetName() == "" || object.GetKind() == "" || object.GetAPIVersion() == "" {
@@ -261,6 +434,34 @@ func IsImmutableError(err error) bool {
 	return false
 }
 
+// immutableFieldMessages holds the messages of the API server validation errors
+// caused by changes to immutable fields.
+var immutableFieldMessages = []string{
+	// Deployment and Job selectors, Secrets and ConfigMaps marked as immutable.
+	"field is immutable",
+	// Service clusterIP and clusterIPs.
+	"may not change once set",
+	// PersistentVolumeClaim spec.
+	"is immutable after creation",
+	// StatefulSet volumeClaimTemplates, selector and serviceName.
+	"updates to statefulset spec for fields other than",
+}
+
+// IsImmutableFieldError checks if the given error is a validation error caused by
+// changes to immutable fields, e.g. a Service clusterIP or a Job selector.
+func IsImmutableFieldError(err error) bool {
+	if !errors.IsInvalid(err) {
+		return false
+	}
+	msg := err.Error()
+	for _, m := range immutableFieldMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
 // AnyInMetadata searches for the specified key-value pairs in labels and annotations,
 // returns true if at least one key-value pair matches.
 func AnyInMetadata(object *unstructured.Unstructured, metadata map[string]string) bool {
@@ -502,3 +703,25 @@ func convertStringDataToData(secret *corev1.Secret) {
 		secret.StringData = nil
 	}
 }
+
+// runConcurrently calls fn for each index in [0, n), with at most concurrency calls in parallel.
+func runConcurrently(concurrency, n int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -19,9 +19,15 @@ package ssa
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 func TestCmpMaskData(t *testing.T) {
@@ -161,3 +167,307 @@ stringData:
 		})
 	}
 }
+
+func TestMaskPaths(t *testing.T) {
+	current := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"values": map[string]interface{}{
+				"password": "old",
+				"user":     "admin",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": "TOKEN", "value": "same"},
+				map[string]interface{}{"name": "KEY", "value": "old"},
+			},
+		},
+	}
+	future := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"values": map[string]interface{}{
+				"password": "new",
+				"user":     "admin",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": "TOKEN", "value": "same"},
+				map[string]interface{}{"name": "KEY", "value": "new"},
+				map[string]interface{}{"name": "ADDED", "value": "new"},
+			},
+		},
+	}
+
+	maskPaths(current, future, []string{"/spec/values/password", "/spec/env/*/value", "/spec/missing"})
+
+	expectedCurrent := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"values": map[string]interface{}{
+				"password": defaultMask,
+				"user":     "admin",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": "TOKEN", "value": defaultMask},
+				map[string]interface{}{"name": "KEY", "value": defaultMask},
+			},
+		},
+	}
+	expectedFuture := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"values": map[string]interface{}{
+				"password": diffMask,
+				"user":     "admin",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": "TOKEN", "value": defaultMask},
+				map[string]interface{}{"name": "KEY", "value": diffMask},
+				map[string]interface{}{"name": "ADDED", "value": defaultMask},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expectedCurrent, current); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(expectedFuture, future); diff != "" {
+		t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsImmutableFieldError(t *testing.T) {
+	gk := schema.GroupKind{Kind: "Service"}
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name: "Service clusterIP",
+			err: apierrors.NewInvalid(gk, "app", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "clusterIPs").Index(0), "10.0.0.1", "may not change once set"),
+			}),
+			expected: true,
+		},
+		{
+			name: "Job selector",
+			err: apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "job", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "selector"), "", "field is immutable"),
+			}),
+			expected: true,
+		},
+		{
+			name: "invalid value",
+			err: apierrors.NewInvalid(gk, "app", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "ports").Index(0).Child("port"), 0, "must be between 1 and 65535"),
+			}),
+			expected: false,
+		},
+		{
+			name:     "conflict",
+			err:      apierrors.NewConflict(schema.GroupResource{Resource: "services"}, "app", fmt.Errorf("field is immutable")),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsImmutableFieldError(tc.err); got != tc.expected {
+				t.Errorf("expected %v, got %v for %v", tc.expected, got, tc.err)
+			}
+		})
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var running, maxRunning int64
+			calls := make([]int64, 20)
+			runConcurrently(concurrency, len(calls), func(i int) {
+				n := atomic.AddInt64(&running, 1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+						break
+					}
+				}
+				atomic.AddInt64(&calls[i], 1)
+				atomic.AddInt64(&running, -1)
+			})
+
+			for i, n := range calls {
+				if n != 1 {
+					t.Errorf("expected one call for index %d, got %d", i, n)
+				}
+			}
+			limit := int64(concurrency)
+			if limit < 1 {
+				limit = 1
+			}
+			if maxRunning > limit {
+				t.Errorf("expected at most %d concurrent calls, got %d", limit, maxRunning)
+			}
+		})
+	}
+}
+
+func TestIsNoMatchError(t *testing.T) {
+	noKindMatch := &meta.NoKindMatchError{
+		GroupKind:        schema.GroupKind{Group: "testing.fluxcd.io", Kind: "ClusterTest"},
+		SearchedVersions: []string{"v1"},
+	}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "no kind match", err: noKindMatch, expected: true},
+		{name: "no resource match", err: &meta.NoResourceMatchError{}, expected: true},
+		{name: "wrapped no kind match", err: fmt.Errorf("ClusterTest/test dry-run failed, error: %w", noKindMatch), expected: true},
+		{name: "not found", err: apierrors.NewNotFound(schema.GroupResource{Resource: "clustertests"}, "test"), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsNoMatchError(tc.err); got != tc.expected {
+				t.Errorf("expected %v, got %v for %v", tc.expected, got, tc.err)
+			}
+		})
+	}
+}
+
+func TestReadObjectsFunc(t *testing.T) {
+	var builder strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&builder, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-%d\n  namespace: default\n", i)
+	}
+	builder.WriteString("---\napiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: test\n")
+	manifest := builder.String()
+
+	t.Run("yields all objects", func(t *testing.T) {
+		var names []string
+		err := ReadObjectsFunc(strings.NewReader(manifest), func(obj *unstructured.Unstructured) error {
+			names = append(names, obj.GetKind()+"/"+obj.GetName())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 101 {
+			t.Fatalf("expected 101 objects, got %d", len(names))
+		}
+		if diff := cmp.Diff([]string{"ConfigMap/test-0", "Secret/test"}, []string{names[0], names[100]}); diff != "" {
+			t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		stop := fmt.Errorf("stop")
+		count := 0
+		err := ReadObjectsFunc(strings.NewReader(manifest), func(obj *unstructured.Unstructured) error {
+			count++
+			if count == 10 {
+				return stop
+			}
+			return nil
+		})
+		if err != stop {
+			t.Fatalf("expected callback error, got %v", err)
+		}
+		if count != 10 {
+			t.Errorf("expected decoding to stop after 10 objects, got %d", count)
+		}
+	})
+}
+
+func TestReadObjects_JSONAndLists(t *testing.T) {
+	testCases := []struct {
+		name      string
+		resources string
+		expected  []string
+	}{
+		{
+			name:      "JSON object",
+			resources: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}}`,
+			expected:  []string{"ConfigMap/a"},
+		},
+		{
+			name: "JSON stream",
+			resources: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}}
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b", "namespace": "default"}}`,
+			expected: []string{"ConfigMap/a", "ConfigMap/b"},
+		},
+		{
+			name: "JSON array",
+			resources: `[
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}},
+  {"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "b", "namespace": "default"}}
+]`,
+			expected: []string{"ConfigMap/a", "Secret/b"},
+		},
+		{
+			name: "JSON List",
+			resources: `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}},
+    {"apiVersion": "v1", "kind": "ConfigMapList", "items": [
+      {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b", "namespace": "default"}}
+    ]}
+  ]
+}`,
+			expected: []string{"ConfigMap/a", "ConfigMap/b"},
+		},
+		{
+			name: "YAML List",
+			resources: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+    namespace: default
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    namespace: default
+`,
+			expected: []string{"ConfigMap/a"},
+		},
+		{
+			name: "mixed YAML and JSON documents",
+			resources: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  namespace: default
+---
+# comment only
+---
+[{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "c", "namespace": "default"}}]
+`,
+			expected: []string{"ConfigMap/a", "ConfigMap/b", "ConfigMap/c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := ReadObjects(strings.NewReader(tc.resources))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, obj := range objects {
+				names = append(names, obj.GetKind()+"/"+obj.GetName())
+			}
+			if diff := cmp.Diff(tc.expected, names); diff != "" {
+				t.Errorf("Mismatch from expected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+	"k8s.io/kubectl/pkg/util/openapi"
+)
+
+// SchemaValidator validates objects against the OpenAPI schemas published by the
+// Kubernetes API server, including the structural schemas of the installed CRDs.
+type SchemaValidator struct {
+	resources openapi.Resources
+}
+
+// NewSchemaValidator fetches the OpenAPI v2 document from the API server
+// and returns a SchemaValidator for the kinds it contains.
+func NewSchemaValidator(client discovery.OpenAPISchemaInterface) (*SchemaValidator, error) {
+	doc, err := client.OpenAPISchema()
+	if err != nil {
+		return nil, fmt.Errorf("fetching OpenAPI schema failed, error: %w", err)
+	}
+
+	resources, err := openapi.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI schema failed, error: %w", err)
+	}
+
+	return &SchemaValidator{resources: resources}, nil
+}
+
+// Validate checks the objects against their schemas without writing to the cluster,
+// reporting unknown fields, type mismatches and missing required fields.
+// Objects with kinds not published by the API server, e.g. custom resources
+// whose CRDs are part of the same set, are skipped.
+// The returned error aggregates the validation errors of all objects.
+func (v *SchemaValidator) Validate(objects []*unstructured.Unstructured) error {
+	var errs []error
+	for _, object := range objects {
+		if err := v.validate(object); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (v *SchemaValidator) validate(object *unstructured.Unstructured) error {
+	gvk := object.GroupVersionKind()
+	schema := v.resources.LookupResource(gvk)
+	if schema == nil {
+		return nil
+	}
+
+	// round trip through JSON to normalise the field values to the types
+	// expected by the validator
+	data, err := json.Marshal(object.Object)
+	if err != nil {
+		return fmt.Errorf("%s validation failed, error: %w", FmtUnstructured(object), err)
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("%s validation failed, error: %w", FmtUnstructured(object), err)
+	}
+
+	if errs := validation.ValidateModel(obj, schema, gvk.Kind); len(errs) > 0 {
+		return fmt.Errorf("%s validation failed, error: %w", FmtUnstructured(object), utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
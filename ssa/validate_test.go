@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssa
+
+import (
+	"strings"
+	"testing"
+
+	openapi_v2 "github.com/google/gnostic/openapiv2"
+)
+
+const testOpenAPISchema = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.25.0"},
+  "paths": {},
+  "definitions": {
+    "io.k8s.api.core.v1.ConfigMap": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "data": {"type": "object", "additionalProperties": {"type": "string"}},
+        "immutable": {"type": "boolean"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "namespace": {"type": "string"},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+type fakeOpenAPISchema struct{}
+
+func (fakeOpenAPISchema) OpenAPISchema() (*openapi_v2.Document, error) {
+	return openapi_v2.ParseDocument([]byte(testOpenAPISchema))
+}
+
+func TestSchemaValidator_Validate(t *testing.T) {
+	validator, err := NewSchemaValidator(fakeOpenAPISchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  []string
+	}{
+		{
+			name: "valid object",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: valid
+  namespace: default
+data:
+  key: value
+immutable: true
+`,
+		},
+		{
+			name: "unknown field",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unknown
+  namespace: default
+dataz:
+  key: value
+`,
+			wantErr: []string{"ConfigMap/default/unknown validation failed", "dataz"},
+		},
+		{
+			name: "invalid type",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: invalid
+  namespace: default
+immutable: "yes"
+`,
+			wantErr: []string{"ConfigMap/default/invalid validation failed", "immutable"},
+		},
+		{
+			name: "kind without schema",
+			manifest: `
+apiVersion: example.com/v1
+kind: Custom
+metadata:
+  name: custom
+  namespace: default
+spec:
+  any: field
+`,
+		},
+		{
+			name: "multiple objects",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+  namespace: default
+dataz: {}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+  namespace: default
+  labelz: {}
+`,
+			wantErr: []string{"ConfigMap/default/first", "ConfigMap/default/second", "labelz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := ReadObjects(strings.NewReader(tt.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = validator.Validate(objects)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}